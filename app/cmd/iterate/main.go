@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/NetScout-Go/NetTool/app/plugins"
@@ -31,6 +32,18 @@ func (s *SimplePluginWrapper) Execute(params map[string]interface{}) (interface{
 	return s.executeFunc(params)
 }
 
+// paramArgs collects repeated -param name=value flags
+type paramArgs []string
+
+func (a *paramArgs) String() string {
+	return strings.Join(*a, " ")
+}
+
+func (a *paramArgs) Set(value string) error {
+	*a = append(*a, value)
+	return nil
+}
+
 func main() {
 	// Define command-line flags
 	pluginID := flag.String("plugin", "", "ID of the plugin to run")
@@ -40,6 +53,8 @@ func main() {
 	delay := flag.Int("delay", 5, "Delay between iterations in seconds")
 	outputFile := flag.String("output", "", "Path to save results (optional)")
 	continueToIterate := flag.Bool("iterate", false, "Whether to run with iteration")
+	var paramFlags paramArgs
+	flag.Var(&paramFlags, "param", "Parameter as name=value, @path for files (repeatable)")
 	flag.Parse()
 
 	// Check if a plugin ID was provided
@@ -63,6 +78,23 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Parameters given as flags take precedence over the JSON ones, and all
+	// are checked against the plugin's definition as the server does
+	definition := pluginInstance.GetDefinition()
+	args, err := plugins.ParseParamArgs(definition, paramFlags)
+	if err != nil {
+		fmt.Printf("Error parsing parameters: %v\n", err)
+		os.Exit(1)
+	}
+	for name, value := range args {
+		params[name] = value
+	}
+	params, err = plugins.ValidateParams(definition, params)
+	if err != nil {
+		fmt.Printf("Error in parameters: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Add iteration parameter if requested
 	if *continueToIterate {
 		params["continueToIterate"] = true
@@ -127,6 +159,10 @@ func loadPlugin(pluginID string) (types.Plugin, error) {
 	// Find plugin directory - need to adjust path since we're in cmd/iterate
 	pluginDir := filepath.Join("..", "..", "app", "plugins", "plugins", pluginID)
 	if _, err := os.Stat(pluginDir); os.IsNotExist(err) {
+		// Builtins such as ping describe themselves and need no directory
+		if builtin, ok := plugins.IterableBuiltin(pluginID); ok {
+			return builtin, nil
+		}
 		return nil, fmt.Errorf("plugin not found: %s", pluginID)
 	}
 
@@ -200,11 +236,13 @@ func printUsage() {
 	fmt.Println("  -plugin string     ID of the plugin to run")
 	fmt.Println("  -params string     Path to JSON file containing parameters")
 	fmt.Println("  -paramsJson string JSON string of parameters")
+	fmt.Println("  -param name=value  Parameter, repeatable; use name=@path for file parameters")
 	fmt.Println("  -iterate           Run with iteration support")
 	fmt.Println("  -max int           Maximum number of iterations (0 = unlimited)")
 	fmt.Println("  -delay int         Delay between iterations in seconds")
 	fmt.Println("  -output string     Path to save results (optional)")
 	fmt.Println("")
 	fmt.Println("Example:")
-	fmt.Println("  iterate -plugin iterative_ping -paramsJson '{\"host\":\"8.8.8.8\",\"count\":3}' -iterate -max 10 -delay 2")
+	fmt.Println("  iterate -plugin ping -param host=8.8.8.8 -param count=3 -iterate -max 10 -delay 2")
+	fmt.Println("  iterate -plugin port_scanner -param host=192.168.1.1 -param ports=22,80,8000-8100")
 }
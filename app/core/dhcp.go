@@ -0,0 +1,559 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Lease file locations of the supported DHCP clients
+const (
+	dhcpcdLeaseDir         = "/var/lib/dhcpcd"
+	dhcpcd5LeaseDir        = "/var/lib/dhcpcd5"
+	networkdLeaseDir       = "/run/systemd/netif/leases"
+	networkManagerLeaseDir = "/var/lib/NetworkManager"
+	networkManagerStateDir = "/run/NetworkManager/devices"
+)
+
+var dhclientLeaseGlobs = []string{
+	"/var/lib/dhcp/dhclient*.leases",
+	"/var/lib/dhclient/dhclient*.lease*",
+}
+
+// getDHCPInfo reports how the IPv4 address of an interface was configured and,
+// when it came from DHCP, the details of the current lease. permanent maps the
+// interface addresses to whether they lack a valid lifetime and is nil when
+// that is unknown.
+func getDHCPInfo(ifaceName string, ifaceIndex int, ipv4 string, permanent map[string]bool) DHCPInfo {
+	if isStaticallyConfigured(ifaceName) {
+		return DHCPInfo{Static: true}
+	}
+
+	lease := findDHCPLease(ifaceName, ifaceIndex, ipv4)
+	if lease != nil {
+		// A lease for a different address than a permanently assigned one is stale
+		if ipv4 != "" && lease.LeasedAddress != "" && lease.LeasedAddress != ipv4 && permanent[ipv4] {
+			return DHCPInfo{Static: true}
+		}
+		return *lease
+	}
+
+	if ipv4 == "" {
+		return DHCPInfo{}
+	}
+
+	if isPermanent, known := permanent[ipv4]; known {
+		// DHCP clients install addresses with the lease lifetime
+		return DHCPInfo{Enabled: !isPermanent, Static: isPermanent}
+	}
+
+	return DHCPInfo{}
+}
+
+// findDHCPLease looks for the lease of an interface in the state of every
+// supported client, preferring a lease for the currently assigned address and
+// otherwise the most recently obtained one. Expired leases are left out, as
+// clients keep them on disk after losing the lease.
+func findDHCPLease(ifaceName string, ifaceIndex int, ipv4 string) *DHCPInfo {
+	now := time.Now()
+	var leases []*DHCPInfo
+
+	if lease := readNetworkManagerDeviceLease(filepath.Join(networkManagerStateDir, strconv.Itoa(ifaceIndex))); lease != nil {
+		leases = append(leases, lease)
+	}
+	if lease := readKeyValueLease(filepath.Join(networkdLeaseDir, strconv.Itoa(ifaceIndex)), "systemd-networkd"); lease != nil {
+		leases = append(leases, lease)
+	}
+	if lease := readDhcpcdLease(ifaceName); lease != nil {
+		leases = append(leases, lease)
+	}
+
+	nmInternal, _ := filepath.Glob(filepath.Join(networkManagerLeaseDir, "internal-*-"+ifaceName+".lease"))
+	for _, path := range nmInternal {
+		if lease := readKeyValueLease(path, "NetworkManager"); lease != nil {
+			leases = append(leases, lease)
+		}
+	}
+
+	nmDhclient, _ := filepath.Glob(filepath.Join(networkManagerLeaseDir, "dhclient-*-"+ifaceName+".lease"))
+	for _, path := range nmDhclient {
+		if lease := readDhclientLease(path, ifaceName, now); lease != nil {
+			lease.Client = "NetworkManager"
+			leases = append(leases, lease)
+		}
+	}
+
+	for _, pattern := range dhclientLeaseGlobs {
+		paths, _ := filepath.Glob(pattern)
+		for _, path := range paths {
+			if lease := readDhclientLease(path, ifaceName, now); lease != nil {
+				leases = append(leases, lease)
+			}
+		}
+	}
+
+	leases = slices.DeleteFunc(leases, func(lease *DHCPInfo) bool {
+		return leaseExpired(lease, now)
+	})
+	if len(leases) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(leases, func(i, j int) bool {
+		iMatch := ipv4 != "" && leases[i].LeasedAddress == ipv4
+		jMatch := ipv4 != "" && leases[j].LeasedAddress == ipv4
+		if iMatch != jMatch {
+			return iMatch
+		}
+		return leases[i].LeaseObtained.After(leases[j].LeaseObtained)
+	})
+
+	return leases[0]
+}
+
+// completeLeaseTimes derives missing lease timestamps from the lease time,
+// using the RFC 2131 defaults for T1 (50%) and T2 (87.5%)
+func completeLeaseTimes(lease *DHCPInfo, renewSecs, rebindSecs int64) {
+	leaseTime := time.Duration(lease.LeaseTime) * time.Second
+
+	if lease.LeaseObtained.IsZero() && !lease.LeaseExpires.IsZero() && leaseTime > 0 {
+		lease.LeaseObtained = lease.LeaseExpires.Add(-leaseTime)
+	}
+	if lease.LeaseObtained.IsZero() {
+		return
+	}
+
+	if lease.LeaseExpires.IsZero() && leaseTime > 0 {
+		lease.LeaseExpires = lease.LeaseObtained.Add(leaseTime)
+	}
+
+	if lease.RenewAt.IsZero() {
+		if renewSecs > 0 {
+			lease.RenewAt = lease.LeaseObtained.Add(time.Duration(renewSecs) * time.Second)
+		} else if leaseTime > 0 {
+			lease.RenewAt = lease.LeaseObtained.Add(leaseTime / 2)
+		}
+	}
+
+	if lease.RebindAt.IsZero() {
+		if rebindSecs > 0 {
+			lease.RebindAt = lease.LeaseObtained.Add(time.Duration(rebindSecs) * time.Second)
+		} else if leaseTime > 0 {
+			lease.RebindAt = lease.LeaseObtained.Add(leaseTime * 7 / 8)
+		}
+	}
+}
+
+// leaseExpired reports whether a lease with a known expiry has run out
+func leaseExpired(lease *DHCPInfo, now time.Time) bool {
+	return !lease.LeaseExpires.IsZero() && !lease.LeaseExpires.After(now)
+}
+
+// readDhclientLease returns the last lease recorded for an interface in an
+// ISC dhclient lease database that has not expired at now. dhclient appends
+// every lease it obtains, so the older blocks are superseded or stale.
+func readDhclientLease(path, ifaceName string, now time.Time) *DHCPInfo {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	var (
+		result             *DHCPInfo
+		current            *DHCPInfo
+		currentIface       string
+		renewSecs, rebSecs int64
+	)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if i := strings.Index(line, "#"); i != -1 {
+			line = strings.TrimSpace(line[:i])
+		}
+		line = strings.TrimSuffix(line, ";")
+
+		switch {
+		case strings.HasPrefix(line, "lease") && strings.HasSuffix(line, "{"):
+			current = &DHCPInfo{Enabled: true, Client: "dhclient"}
+			currentIface, renewSecs, rebSecs = "", 0, 0
+			continue
+		case line == "}":
+			if current != nil && currentIface == ifaceName {
+				completeLeaseTimes(current, renewSecs, rebSecs)
+				if !leaseExpired(current, now) {
+					result = current
+				}
+			}
+			current = nil
+			continue
+		case current == nil:
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+
+		switch fields[0] {
+		case "interface":
+			currentIface = strings.Trim(fields[1], "\"")
+		case "fixed-address":
+			current.LeasedAddress = fields[1]
+		case "renew":
+			current.RenewAt = parseDhclientTime(fields[1:])
+		case "rebind":
+			current.RebindAt = parseDhclientTime(fields[1:])
+		case "expire":
+			current.LeaseExpires = parseDhclientTime(fields[1:])
+		case "option":
+			if len(fields) < 3 {
+				continue
+			}
+			value := strings.Join(fields[2:], " ")
+			switch fields[1] {
+			case "routers":
+				current.Routers = splitList(value)
+			case "domain-name-servers":
+				current.DNSServers = splitList(value)
+			case "ntp-servers":
+				current.NTPServers = splitList(value)
+			case "domain-name":
+				current.DomainName = strings.Trim(value, "\"")
+			case "dhcp-server-identifier":
+				current.DHCPServer = value
+			case "dhcp-lease-time":
+				current.LeaseTime, _ = strconv.ParseInt(value, 10, 64)
+			case "dhcp-renewal-time":
+				renewSecs, _ = strconv.ParseInt(value, 10, 64)
+			case "dhcp-rebinding-time":
+				rebSecs, _ = strconv.ParseInt(value, 10, 64)
+			}
+		}
+	}
+
+	return result
+}
+
+// parseDhclientTime parses "W YYYY/MM/DD HH:MM:SS" (UTC) or "epoch N" timestamps
+func parseDhclientTime(fields []string) time.Time {
+	if len(fields) >= 2 && fields[0] == "epoch" {
+		secs, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return time.Time{}
+		}
+		return time.Unix(secs, 0)
+	}
+
+	if len(fields) < 3 {
+		return time.Time{}
+	}
+
+	t, err := time.Parse("2006/01/02 15:04:05", fields[1]+" "+fields[2])
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// readDhcpcdLease parses the raw BOOTP reply dhcpcd stores for an interface.
+// Wireless leases are stored per SSID as <iface>-<ssid>.lease, so the most
+// recently written candidate wins.
+func readDhcpcdLease(ifaceName string) *DHCPInfo {
+	var candidates []string
+	for _, dir := range []string{dhcpcdLeaseDir, dhcpcd5LeaseDir} {
+		candidates = append(candidates,
+			filepath.Join(dir, ifaceName+".lease"),
+			filepath.Join(dir, "dhcpcd-"+ifaceName+".lease"))
+		ssidLeases, _ := filepath.Glob(filepath.Join(dir, ifaceName+"-*.lease"))
+		candidates = append(candidates, ssidLeases...)
+	}
+
+	var (
+		newest     string
+		newestTime time.Time
+	)
+	for _, path := range candidates {
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		if info.ModTime().After(newestTime) {
+			newest, newestTime = path, info.ModTime()
+		}
+	}
+
+	if newest == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(newest)
+	if err != nil {
+		return nil
+	}
+
+	lease, renewSecs, rebindSecs := parseBOOTPLease(data)
+	if lease == nil {
+		return nil
+	}
+
+	lease.Client = "dhcpcd"
+	lease.LeaseObtained = newestTime
+	completeLeaseTimes(lease, renewSecs, rebindSecs)
+	return lease
+}
+
+// DHCP option codes reported in the lease panel
+const (
+	dhcpOptPad        = 0
+	dhcpOptRouter     = 3
+	dhcpOptDNS        = 6
+	dhcpOptDomainName = 15
+	dhcpOptNTP        = 42
+	dhcpOptLeaseTime  = 51
+	dhcpOptServerID   = 54
+	dhcpOptRenewal    = 58
+	dhcpOptRebinding  = 59
+	dhcpOptEnd        = 255
+)
+
+// parseBOOTPLease decodes the address and options of a DHCP message, returning
+// the T1 and T2 options separately. Absolute lease times are filled in by the
+// caller, which knows when the lease was written.
+func parseBOOTPLease(data []byte) (*DHCPInfo, int64, int64) {
+	// Fixed BOOTP header followed by the DHCP magic cookie
+	if len(data) < 240 || !bytes.Equal(data[236:240], []byte{99, 130, 83, 99}) {
+		return nil, 0, 0
+	}
+
+	lease := &DHCPInfo{
+		Enabled:       true,
+		LeasedAddress: net.IP(data[16:20]).String(),
+	}
+
+	var renewSecs, rebindSecs int64
+	options := data[240:]
+	for len(options) > 0 {
+		code := options[0]
+		if code == dhcpOptPad {
+			options = options[1:]
+			continue
+		}
+		if code == dhcpOptEnd || len(options) < 2 {
+			break
+		}
+
+		length := int(options[1])
+		if len(options) < 2+length {
+			break
+		}
+		value := options[2 : 2+length]
+		options = options[2+length:]
+
+		switch code {
+		case dhcpOptRouter:
+			lease.Routers = ipList(value)
+		case dhcpOptDNS:
+			lease.DNSServers = ipList(value)
+		case dhcpOptNTP:
+			lease.NTPServers = ipList(value)
+		case dhcpOptDomainName:
+			lease.DomainName = strings.TrimRight(string(value), "\x00")
+		case dhcpOptServerID:
+			if length == 4 {
+				lease.DHCPServer = net.IP(value).String()
+			}
+		case dhcpOptLeaseTime:
+			if length == 4 {
+				lease.LeaseTime = int64(binary.BigEndian.Uint32(value))
+			}
+		case dhcpOptRenewal:
+			if length == 4 {
+				renewSecs = int64(binary.BigEndian.Uint32(value))
+			}
+		case dhcpOptRebinding:
+			if length == 4 {
+				rebindSecs = int64(binary.BigEndian.Uint32(value))
+			}
+		}
+	}
+
+	return lease, renewSecs, rebindSecs
+}
+
+// readKeyValueLease parses the KEY=value lease files written by systemd-networkd
+// and the internal DHCP client of NetworkManager. Both store relative lease
+// times, so the file modification time is taken as the time it was obtained.
+func readKeyValueLease(path, client string) *DHCPInfo {
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return nil
+	}
+
+	values := readKeyValueFile(path, "")
+	if values == nil || values["ADDRESS"] == "" {
+		return nil
+	}
+
+	lease := &DHCPInfo{
+		Enabled:       true,
+		Client:        client,
+		LeasedAddress: values["ADDRESS"],
+		LeaseObtained: info.ModTime(),
+		DHCPServer:    values["SERVER_ADDRESS"],
+		Routers:       splitList(values["ROUTER"]),
+		DNSServers:    splitList(values["DNS"]),
+		NTPServers:    splitList(values["NTP"]),
+		DomainName:    values["DOMAINNAME"],
+	}
+	lease.LeaseTime, _ = strconv.ParseInt(values["LIFETIME"], 10, 64)
+
+	renewSecs, _ := strconv.ParseInt(values["T1"], 10, 64)
+	rebindSecs, _ := strconv.ParseInt(values["T2"], 10, 64)
+	completeLeaseTimes(lease, renewSecs, rebindSecs)
+
+	return lease
+}
+
+// readNetworkManagerDeviceLease reads the [dhcp4] section of the state file
+// NetworkManager keeps for an active device, which holds absolute expiry times
+func readNetworkManagerDeviceLease(path string) *DHCPInfo {
+	values := readKeyValueFile(path, "dhcp4")
+	if values == nil || values["ip_address"] == "" {
+		return nil
+	}
+
+	lease := &DHCPInfo{
+		Enabled:       true,
+		Client:        "NetworkManager",
+		LeasedAddress: values["ip_address"],
+		DHCPServer:    values["dhcp_server_identifier"],
+		Routers:       splitList(values["routers"]),
+		DNSServers:    splitList(values["domain_name_servers"]),
+		NTPServers:    splitList(values["ntp_servers"]),
+		DomainName:    values["domain_name"],
+	}
+	lease.LeaseTime, _ = strconv.ParseInt(values["dhcp_lease_time"], 10, 64)
+
+	if expiry, err := strconv.ParseInt(values["expiry"], 10, 64); err == nil && expiry > 0 {
+		lease.LeaseExpires = time.Unix(expiry, 0)
+	}
+
+	renewSecs, _ := strconv.ParseInt(values["dhcp_renewal_time"], 10, 64)
+	rebindSecs, _ := strconv.ParseInt(values["dhcp_rebinding_time"], 10, 64)
+	completeLeaseTimes(lease, renewSecs, rebindSecs)
+
+	return lease
+}
+
+// readKeyValueFile reads KEY=value lines, optionally restricted to one [section]
+// of an ini-style file. It returns nil when the file cannot be read.
+func readKeyValueFile(path, section string) map[string]string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	values := make(map[string]string)
+	inSection := section == ""
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			inSection = section == "" || line[1:len(line)-1] == section
+			continue
+		}
+		if !inSection {
+			continue
+		}
+
+		if key, value, ok := strings.Cut(line, "="); ok {
+			values[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+
+	return values
+}
+
+// isStaticallyConfigured checks the dhcpcd and ifupdown configuration for a
+// static IPv4 address on the interface
+func isStaticallyConfigured(ifaceName string) bool {
+	if data, err := os.ReadFile("/etc/dhcpcd.conf"); err == nil && dhcpcdConfHasStatic(string(data), ifaceName) {
+		return true
+	}
+
+	paths := []string{"/etc/network/interfaces"}
+	if extra, err := filepath.Glob("/etc/network/interfaces.d/*"); err == nil {
+		paths = append(paths, extra...)
+	}
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			// iface <name> inet static
+			fields := strings.Fields(line)
+			if len(fields) >= 4 && fields[0] == "iface" && fields[1] == ifaceName && fields[2] == "inet" && fields[3] == "static" {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func dhcpcdConfHasStatic(conf, ifaceName string) bool {
+	inBlock := false
+	for _, line := range strings.Split(conf, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		switch fields[0] {
+		case "interface":
+			inBlock = len(fields) >= 2 && fields[1] == ifaceName
+		case "ssid", "profile":
+			// Per-SSID and fallback profiles are conditional, not the interface default
+			if inBlock {
+				inBlock = false
+			}
+		case "static":
+			if inBlock && len(fields) >= 2 && strings.HasPrefix(fields[1], "ip_address=") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// splitList splits comma or space separated address lists
+func splitList(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+}
+
+func ipList(value []byte) []string {
+	var ips []string
+	for i := 0; i+4 <= len(value); i += 4 {
+		ips = append(ips, net.IP(value[i:i+4]).String())
+	}
+	return ips
+}
@@ -0,0 +1,292 @@
+package core
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func utc(s string) time.Time {
+	t, err := time.Parse(time.DateTime, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestReadDhclientLease(t *testing.T) {
+	tests := []struct {
+		name    string
+		iface   string
+		now     time.Time
+		address string // empty when no lease is expected
+		want    DHCPInfo
+	}{
+		{
+			name:    "latest lease",
+			iface:   "eth0",
+			now:     utc("2026-10-15 12:00:00"),
+			address: "192.168.1.60",
+			// Without renew and rebind lines the times follow from the lease time
+			want: DHCPInfo{
+				LeaseObtained: utc("2026-10-15 00:00:00"),
+				LeaseExpires:  utc("2026-10-16 00:00:00"),
+				RenewAt:       utc("2026-10-15 12:00:00"),
+				RebindAt:      utc("2026-10-15 21:00:00"),
+				LeaseTime:     86400,
+				DHCPServer:    "192.168.1.1",
+				Routers:       []string{"192.168.1.1"},
+				DNSServers:    []string{"192.168.1.1", "8.8.8.8"},
+				NTPServers:    []string{"192.168.1.1"},
+				DomainName:    "lan",
+			},
+		},
+		{
+			name:  "latest lease expired",
+			iface: "eth0",
+			now:   utc("2026-10-16 00:00:00"),
+		},
+		{
+			name:    "epoch times",
+			iface:   "wlan0",
+			now:     utc("2026-10-15 08:40:00"),
+			address: "10.10.0.12",
+			want: DHCPInfo{
+				LeaseObtained: utc("2026-10-15 08:00:00"),
+				LeaseExpires:  utc("2026-10-15 09:00:00"),
+				RenewAt:       utc("2026-10-15 08:30:00"),
+				RebindAt:      utc("2026-10-15 08:47:30"),
+				LeaseTime:     3600,
+				DHCPServer:    "10.10.0.1",
+				Routers:       []string{"10.10.0.1"},
+				DNSServers:    []string{"10.10.0.1"},
+			},
+		},
+		{
+			name:  "other interface",
+			iface: "eth1",
+			now:   utc("2026-10-15 12:00:00"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lease := readDhclientLease(filepath.Join("testdata", "dhclient.leases"), tt.iface, tt.now)
+			if tt.address == "" {
+				if lease != nil {
+					t.Fatalf("got lease for %s, want none", lease.LeasedAddress)
+				}
+				return
+			}
+			if lease == nil {
+				t.Fatal("no lease found")
+			}
+
+			tt.want.Enabled = true
+			tt.want.Client = "dhclient"
+			tt.want.LeasedAddress = tt.address
+			checkLease(t, lease, &tt.want)
+		})
+	}
+}
+
+func TestParseBOOTPLease(t *testing.T) {
+	message := make([]byte, 240)
+	copy(message[16:20], []byte{192, 168, 4, 20})
+	copy(message[236:240], []byte{99, 130, 83, 99})
+
+	option := func(code byte, value ...byte) []byte { return append([]byte{code, byte(len(value))}, value...) }
+	seconds := func(n uint32) []byte { return binary.BigEndian.AppendUint32(nil, n) }
+	options := slices.Concat(
+		[]byte{dhcpOptPad},
+		option(dhcpOptRouter, 192, 168, 4, 1),
+		option(dhcpOptDNS, 192, 168, 4, 1, 9, 9, 9, 9),
+		option(dhcpOptDomainName, []byte("home.arpa\x00")...),
+		option(dhcpOptServerID, 192, 168, 4, 1),
+		option(dhcpOptLeaseTime, seconds(3600)...),
+		option(dhcpOptRenewal, seconds(1200)...),
+		option(dhcpOptRebinding, seconds(3000)...),
+		[]byte{dhcpOptEnd},
+		option(dhcpOptNTP, 10, 0, 0, 1), // past the end option
+	)
+
+	lease, renewSecs, rebindSecs := parseBOOTPLease(append(message, options...))
+	if lease == nil {
+		t.Fatal("lease not parsed")
+	}
+	checkLease(t, lease, &DHCPInfo{
+		Enabled:       true,
+		LeasedAddress: "192.168.4.20",
+		LeaseTime:     3600,
+		DHCPServer:    "192.168.4.1",
+		Routers:       []string{"192.168.4.1"},
+		DNSServers:    []string{"192.168.4.1", "9.9.9.9"},
+		DomainName:    "home.arpa",
+	})
+	if renewSecs != 1200 || rebindSecs != 3000 {
+		t.Errorf("T1 %d s, T2 %d s; want 1200 s, 3000 s", renewSecs, rebindSecs)
+	}
+
+	// Options running past the end of the message are ignored
+	truncated := append(append([]byte(nil), message...), option(dhcpOptRouter, 192, 168, 4, 1)[:4]...)
+	if lease, _, _ := parseBOOTPLease(truncated); lease == nil || lease.Routers != nil {
+		t.Errorf("truncated options parsed as %+v", lease)
+	}
+
+	if lease, _, _ := parseBOOTPLease(message[:239]); lease != nil {
+		t.Error("short message parsed as a lease")
+	}
+	message[239] = 0
+	if lease, _, _ := parseBOOTPLease(message); lease != nil {
+		t.Error("message without the magic cookie parsed as a lease")
+	}
+}
+
+func TestReadKeyValueLease(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "networkd.lease"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Lease times are relative to when the file was written
+	path := filepath.Join(t.TempDir(), "2")
+	obtained := utc("2026-10-15 09:00:00")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, obtained, obtained); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, client := range []string{"systemd-networkd", "NetworkManager"} {
+		lease := readKeyValueLease(path, client)
+		if lease == nil {
+			t.Fatalf("%s: no lease found", client)
+		}
+		checkLease(t, lease, &DHCPInfo{
+			Enabled:       true,
+			Client:        client,
+			LeasedAddress: "10.0.0.23",
+			LeaseObtained: obtained,
+			LeaseExpires:  utc("2026-10-15 10:00:00"),
+			RenewAt:       utc("2026-10-15 09:30:00"),
+			RebindAt:      utc("2026-10-15 09:52:30"),
+			LeaseTime:     3600,
+			DHCPServer:    "10.0.0.1",
+			Routers:       []string{"10.0.0.1"},
+			DNSServers:    []string{"10.0.0.1", "1.1.1.1"},
+			NTPServers:    []string{"10.0.0.5"},
+			DomainName:    "example.internal",
+		})
+	}
+
+	if lease := readKeyValueLease(filepath.Join("testdata", "missing.lease"), "systemd-networkd"); lease != nil {
+		t.Error("lease read from a missing file")
+	}
+	if lease := readKeyValueLease(filepath.Join("testdata", "nm-device"), "systemd-networkd"); lease != nil {
+		t.Error("lease read from a file without ADDRESS")
+	}
+}
+
+func TestReadNetworkManagerDeviceLease(t *testing.T) {
+	lease := readNetworkManagerDeviceLease(filepath.Join("testdata", "nm-device"))
+	if lease == nil {
+		t.Fatal("no lease found")
+	}
+	expires := time.Unix(1792000000, 0)
+	checkLease(t, lease, &DHCPInfo{
+		Enabled:       true,
+		Client:        "NetworkManager",
+		LeasedAddress: "192.168.50.77",
+		LeaseObtained: expires.Add(-2 * time.Hour),
+		LeaseExpires:  expires,
+		RenewAt:       expires.Add(-time.Hour),
+		RebindAt:      expires.Add(-15 * time.Minute),
+		LeaseTime:     7200,
+		DHCPServer:    "192.168.50.1",
+		Routers:       []string{"192.168.50.1"},
+		DNSServers:    []string{"192.168.50.1", "192.168.50.2"},
+		DomainName:    "office.example",
+	})
+
+	if lease := readNetworkManagerDeviceLease(filepath.Join("testdata", "networkd.lease")); lease != nil {
+		t.Error("lease read from a file without a [dhcp4] section")
+	}
+}
+
+func TestDhcpcdConfHasStatic(t *testing.T) {
+	const conf = `# A sample configuration for dhcpcd.
+hostname
+clientid
+persistent
+option rapid_commit
+
+interface eth0
+static ip_address=192.168.1.10/24
+static routers=192.168.1.1
+
+interface wlan0
+# static ip_address=192.168.2.10/24
+static routers=192.168.2.1
+
+interface usb0
+ssid HomeNetwork
+static ip_address=192.168.3.10/24
+
+profile static_eth1
+static ip_address=192.168.4.10/24
+interface eth1
+fallback static_eth1
+`
+	tests := map[string]bool{
+		"eth0":  true,  // static address in its block
+		"wlan0": false, // commented out, and routers alone are not an address
+		"usb0":  false, // only for one SSID
+		"eth1":  false, // only as a fallback profile
+		"eth2":  false, // not configured
+	}
+	for iface, want := range tests {
+		if got := dhcpcdConfHasStatic(conf, iface); got != want {
+			t.Errorf("dhcpcdConfHasStatic(%s) = %v, want %v", iface, got, want)
+		}
+	}
+}
+
+func checkLease(t *testing.T, got, want *DHCPInfo) {
+	t.Helper()
+	if got.Enabled != want.Enabled || got.Static != want.Static || got.Client != want.Client || got.LeasedAddress != want.LeasedAddress {
+		t.Errorf("lease %v/%v from %q for %s, want %v/%v from %q for %s",
+			got.Enabled, got.Static, got.Client, got.LeasedAddress, want.Enabled, want.Static, want.Client, want.LeasedAddress)
+	}
+	times := []struct {
+		name      string
+		got, want time.Time
+	}{
+		{"obtained", got.LeaseObtained, want.LeaseObtained},
+		{"expires", got.LeaseExpires, want.LeaseExpires},
+		{"renew", got.RenewAt, want.RenewAt},
+		{"rebind", got.RebindAt, want.RebindAt},
+	}
+	for _, tt := range times {
+		if !tt.got.Equal(tt.want) {
+			t.Errorf("%s at %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+	if got.LeaseTime != want.LeaseTime || got.DHCPServer != want.DHCPServer || got.DomainName != want.DomainName {
+		t.Errorf("lease time %d from %s in %q, want %d from %s in %q",
+			got.LeaseTime, got.DHCPServer, got.DomainName, want.LeaseTime, want.DHCPServer, want.DomainName)
+	}
+	for _, list := range []struct {
+		name      string
+		got, want []string
+	}{
+		{"routers", got.Routers, want.Routers},
+		{"DNS servers", got.DNSServers, want.DNSServers},
+		{"NTP servers", got.NTPServers, want.NTPServers},
+	} {
+		if !slices.Equal(list.got, list.want) {
+			t.Errorf("%s %q, want %q", list.name, list.got, list.want)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package core
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ethtoolSettings holds the link settings reported by ETHTOOL_GSET
+type ethtoolSettings struct {
+	SpeedMbps   int // 0 when unknown
+	Duplex      string
+	AutoNeg     bool
+	Supported   uint32
+	Advertising uint32
+}
+
+// ethtoolDriverInfo holds the driver details reported by ETHTOOL_GDRVINFO
+type ethtoolDriverInfo struct {
+	Driver          string
+	Version         string
+	FirmwareVersion string
+	BusInfo         string
+}
+
+// ethtoolLinkModes names the link mode bits of the legacy ethtool masks.
+// Port types and pause bits share the masks and are left out.
+var ethtoolLinkModes = []struct {
+	bit  uint
+	name string
+}{
+	{0, "10baseT/Half"},
+	{1, "10baseT/Full"},
+	{2, "100baseT/Half"},
+	{3, "100baseT/Full"},
+	{4, "1000baseT/Half"},
+	{5, "1000baseT/Full"},
+	{12, "10000baseT/Full"},
+	{15, "2500baseX/Full"},
+	{17, "1000baseKX/Full"},
+	{18, "10000baseKX4/Full"},
+	{19, "10000baseKR/Full"},
+	{21, "20000baseMLD2/Full"},
+	{22, "20000baseKR2/Full"},
+	{23, "40000baseKR4/Full"},
+	{24, "40000baseCR4/Full"},
+	{25, "40000baseSR4/Full"},
+	{26, "40000baseLR4/Full"},
+	{27, "56000baseKR4/Full"},
+	{28, "56000baseCR4/Full"},
+	{29, "56000baseSR4/Full"},
+	{30, "56000baseLR4/Full"},
+}
+
+func linkModeNames(mask uint32) []string {
+	var modes []string
+	for _, mode := range ethtoolLinkModes {
+		if mask&(1<<mode.bit) != 0 {
+			modes = append(modes, mode.name)
+		}
+	}
+	return modes
+}
+
+// getEthernetInfo reads the negotiated link settings, carrier state and driver
+// details of an interface. The ethtool ioctl is preferred, sysfs fills in what
+// it cannot report. Values that are unavailable, e.g. the speed of a Wi-Fi
+// interface or of a link without carrier, are reported as "Unknown".
+func getEthernetInfo(iface net.Interface) EthernetInfo {
+	info := EthernetInfo{
+		InterfaceName: iface.Name,
+		MACAddress:    iface.HardwareAddr.String(),
+		Speed:         "Unknown",
+		Duplex:        "Unknown",
+	}
+
+	sysfs := filepath.Join("/sys/class/net", iface.Name)
+
+	if settings, err := getEthtoolSettings(iface.Name); err == nil {
+		info.SpeedMbps = settings.SpeedMbps
+		info.Duplex = settings.Duplex
+		info.AutoNegotiation = "off"
+		if settings.AutoNeg {
+			info.AutoNegotiation = "on"
+		}
+		info.SupportedModes = linkModeNames(settings.Supported)
+		info.AdvertisedModes = linkModeNames(settings.Advertising)
+	}
+
+	if info.SpeedMbps == 0 {
+		// sysfs reports -1 or fails with EINVAL when the speed is unknown
+		if speed, err := readSysfsInt(filepath.Join(sysfs, "speed")); err == nil && speed > 0 {
+			info.SpeedMbps = speed
+		}
+	}
+	if info.SpeedMbps > 0 {
+		info.Speed = formatLinkSpeed(info.SpeedMbps)
+	}
+
+	if info.Duplex == "Unknown" {
+		if duplex, err := readSysfsString(filepath.Join(sysfs, "duplex")); err == nil {
+			info.Duplex = formatDuplex(duplex)
+		}
+	}
+
+	if carrier, err := readSysfsInt(filepath.Join(sysfs, "carrier")); err == nil {
+		info.Carrier = carrier == 1
+	}
+	if changes, err := readSysfsInt(filepath.Join(sysfs, "carrier_changes")); err == nil {
+		info.CarrierChanges = changes
+	}
+
+	if drv, err := getEthtoolDriverInfo(iface.Name); err == nil {
+		info.Driver = drv.Driver
+		info.DriverVersion = drv.Version
+		info.FirmwareVersion = drv.FirmwareVersion
+		info.BusInfo = drv.BusInfo
+	} else if target, err := os.Readlink(filepath.Join(sysfs, "device", "driver")); err == nil {
+		info.Driver = filepath.Base(target)
+	}
+
+	return info
+}
+
+// formatLinkSpeed renders a speed in Mbps the way the dashboard shows it, e.g. "2.5 Gbps"
+func formatLinkSpeed(mbps int) string {
+	if mbps >= 1000 {
+		return strconv.FormatFloat(float64(mbps)/1000, 'f', -1, 64) + " Gbps"
+	}
+	return strconv.Itoa(mbps) + " Mbps"
+}
+
+func formatDuplex(duplex string) string {
+	switch strings.ToLower(duplex) {
+	case "full":
+		return "Full"
+	case "half":
+		return "Half"
+	default:
+		return "Unknown"
+	}
+}
+
+func readSysfsString(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func readSysfsInt(path string) (int, error) {
+	value, err := readSysfsString(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(value)
+}
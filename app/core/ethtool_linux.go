@@ -0,0 +1,102 @@
+package core
+
+import (
+	"encoding/binary"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+// ethtool ioctl constants from linux/ethtool.h and linux/sockios.h
+const (
+	siocEthtool       = 0x8946
+	ethtoolGSet       = 0x1
+	ethtoolGDrvInfo   = 0x3
+	ethtoolCmdLen     = 44
+	ethtoolDrvInfoLen = 196
+
+	ethtoolSpeedUnknown  = 0xffffffff
+	ethtoolDuplexHalf    = 0x00
+	ethtoolDuplexFull    = 0x01
+	ethtoolAutoNegEnable = 0x01
+)
+
+// ifreqData mirrors struct ifreq with ifr_data set, padded to the size of the union
+type ifreqData struct {
+	name [syscall.IFNAMSIZ]byte
+	data uintptr
+	_    [24 - unsafe.Sizeof(uintptr(0))]byte
+}
+
+// ethtoolIoctl issues an SIOCETHTOOL request with buf as the ethtool command
+// structure, whose first word must hold the command
+func ethtoolIoctl(ifaceName string, buf []byte) error {
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+	defer syscall.Close(fd)
+
+	var ifr ifreqData
+	copy(ifr.name[:syscall.IFNAMSIZ-1], ifaceName)
+	ifr.data = uintptr(unsafe.Pointer(&buf[0]))
+
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), siocEthtool, uintptr(unsafe.Pointer(&ifr)))
+	runtime.KeepAlive(buf)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+func getEthtoolSettings(ifaceName string) (ethtoolSettings, error) {
+	buf := make([]byte, ethtoolCmdLen)
+	binary.NativeEndian.PutUint32(buf[0:4], ethtoolGSet)
+
+	if err := ethtoolIoctl(ifaceName, buf); err != nil {
+		return ethtoolSettings{}, err
+	}
+
+	settings := ethtoolSettings{
+		Supported:   binary.NativeEndian.Uint32(buf[4:8]),
+		Advertising: binary.NativeEndian.Uint32(buf[8:12]),
+		AutoNeg:     buf[18] == ethtoolAutoNegEnable,
+		Duplex:      "Unknown",
+	}
+
+	speed := uint32(binary.NativeEndian.Uint16(buf[12:14])) | uint32(binary.NativeEndian.Uint16(buf[28:30]))<<16
+	if speed != ethtoolSpeedUnknown && speed != 0xffff && speed != 0 {
+		settings.SpeedMbps = int(speed)
+	}
+
+	switch buf[14] {
+	case ethtoolDuplexHalf:
+		settings.Duplex = "Half"
+	case ethtoolDuplexFull:
+		settings.Duplex = "Full"
+	}
+
+	// Drivers without a PHY (virtual, Wi-Fi) report no speed; their duplex is meaningless
+	if settings.SpeedMbps == 0 {
+		settings.Duplex = "Unknown"
+	}
+
+	return settings, nil
+}
+
+func getEthtoolDriverInfo(ifaceName string) (ethtoolDriverInfo, error) {
+	buf := make([]byte, ethtoolDrvInfoLen)
+	binary.NativeEndian.PutUint32(buf[0:4], ethtoolGDrvInfo)
+
+	if err := ethtoolIoctl(ifaceName, buf); err != nil {
+		return ethtoolDriverInfo{}, err
+	}
+
+	// struct ethtool_drvinfo: cmd, then 32-byte driver, version, fw_version and bus_info strings
+	return ethtoolDriverInfo{
+		Driver:          netlinkString(buf[4:36]),
+		Version:         netlinkString(buf[36:68]),
+		FirmwareVersion: netlinkString(buf[68:100]),
+		BusInfo:         netlinkString(buf[100:132]),
+	}, nil
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+
+package core
+
+import "errors"
+
+var errEthtoolUnsupported = errors.New("ethtool is not supported on this platform")
+
+func getEthtoolSettings(_ string) (ethtoolSettings, error) {
+	return ethtoolSettings{}, errEthtoolUnsupported
+}
+
+func getEthtoolDriverInfo(_ string) (ethtoolDriverInfo, error) {
+	return ethtoolDriverInfo{}, errEthtoolUnsupported
+}
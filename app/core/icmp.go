@@ -0,0 +1,276 @@
+package core
+
+import (
+	"errors"
+	"math"
+	"net"
+	"net/netip"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+
+	"github.com/NetScout-Go/NetTool/app/internal/icmpsock"
+)
+
+// defaultICMPWindow is the number of echo requests a prober keeps statistics for
+const defaultICMPWindow = 20
+
+// ErrICMPUnavailable is returned when neither an unprivileged ICMP datagram
+// socket nor a raw socket can be opened
+var ErrICMPUnavailable = icmpsock.ErrUnavailable
+
+// icmpSequence hands out echo sequence numbers process-wide, so that probers
+// sharing the identifier of a raw socket never claim each other's replies
+var icmpSequence uint32
+
+// ICMPStats summarises the echo requests in a prober's rolling window
+type ICMPStats struct {
+	Target      string  `json:"target"`
+	Sent        int     `json:"sent"`
+	Received    int     `json:"received"`
+	PacketLoss  float64 `json:"packetLoss"` // percentage
+	MinRTTMS    float64 `json:"minRttMs"`
+	AvgRTTMS    float64 `json:"avgRttMs"`
+	MaxRTTMS    float64 `json:"maxRttMs"`
+	JitterMS    float64 `json:"jitterMs"`              // mean difference between consecutive RTTs
+	Privileged  bool    `json:"privileged"`            // raw socket instead of an ICMP datagram socket
+	LastError   string  `json:"lastError,omitempty"`   // error of the most recent probe, if any
+	WindowSize  int     `json:"windowSize"`            // capacity of the rolling window
+	LastProbeAt int64   `json:"lastProbeAt,omitempty"` // unix seconds
+}
+
+// icmpSample records the outcome of a single echo request
+type icmpSample struct {
+	rtt      time.Duration
+	received bool
+}
+
+// ICMPProber measures round-trip times to a single target with ICMP echo
+// requests and keeps statistics over the most recent requests
+type ICMPProber struct {
+	target  string
+	window  int
+	timeout time.Duration
+
+	mu         sync.Mutex
+	samples    []icmpSample
+	privileged bool
+	lastErr    error
+	lastProbe  time.Time
+}
+
+// NewICMPProber creates a prober for target, an IP address or host name,
+// keeping statistics over the last window echo requests. A window of zero
+// selects the default size.
+func NewICMPProber(target string, window int) *ICMPProber {
+	if window <= 0 {
+		window = defaultICMPWindow
+	}
+	return &ICMPProber{
+		target:  target,
+		window:  window,
+		timeout: time.Second,
+	}
+}
+
+// SetTimeout sets how long each echo request waits for its reply
+func (p *ICMPProber) SetTimeout(timeout time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.timeout = timeout
+}
+
+// Probe sends count echo requests one after another, interval apart, and adds
+// their outcome to the rolling window. Lost replies are not an error; an error
+// is only returned when no request could be sent at all.
+func (p *ICMPProber) Probe(count int, interval time.Duration) error {
+	p.mu.Lock()
+	timeout := p.timeout
+	p.mu.Unlock()
+
+	ip, err := resolveProbeTarget(p.target)
+	if err != nil {
+		p.recordError(err)
+		return err
+	}
+
+	conn, err := icmpsock.Listen(ip, icmpsock.Options{})
+	if err != nil {
+		p.recordError(err)
+		return err
+	}
+	defer conn.Close()
+
+	for i := 0; i < count; i++ {
+		if i > 0 && interval > 0 {
+			time.Sleep(interval)
+		}
+
+		rtt, err := sendEcho(conn, ip, timeout)
+		if err != nil && !isTimeout(err) {
+			p.recordError(err)
+			return err
+		}
+		p.record(icmpSample{rtt: rtt, received: err == nil}, conn.Privileged())
+	}
+
+	return nil
+}
+
+func (p *ICMPProber) record(sample icmpSample, privileged bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.samples = append(p.samples, sample)
+	if len(p.samples) > p.window {
+		p.samples = p.samples[len(p.samples)-p.window:]
+	}
+	p.privileged = privileged
+	p.lastErr = nil
+	p.lastProbe = time.Now()
+}
+
+func (p *ICMPProber) recordError(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.lastErr = err
+	p.lastProbe = time.Now()
+}
+
+// Stats returns the statistics of the rolling window
+func (p *ICMPProber) Stats() ICMPStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := ICMPStats{
+		Target:     p.target,
+		Sent:       len(p.samples),
+		Privileged: p.privileged,
+		WindowSize: p.window,
+	}
+	if p.lastErr != nil {
+		stats.LastError = p.lastErr.Error()
+	}
+	if !p.lastProbe.IsZero() {
+		stats.LastProbeAt = p.lastProbe.Unix()
+	}
+	if stats.Sent == 0 {
+		return stats
+	}
+
+	var total, jitter float64
+	var prev float64
+	jitterSamples := 0
+	stats.MinRTTMS = math.MaxFloat64
+	for _, sample := range p.samples {
+		if !sample.received {
+			continue
+		}
+
+		rtt := float64(sample.rtt.Microseconds()) / 1000
+		total += rtt
+		stats.MinRTTMS = math.Min(stats.MinRTTMS, rtt)
+		stats.MaxRTTMS = math.Max(stats.MaxRTTMS, rtt)
+		if stats.Received > 0 {
+			jitter += math.Abs(rtt - prev)
+			jitterSamples++
+		}
+		prev = rtt
+		stats.Received++
+	}
+
+	stats.PacketLoss = 100 * float64(stats.Sent-stats.Received) / float64(stats.Sent)
+	if stats.Received == 0 {
+		stats.MinRTTMS = 0
+		return stats
+	}
+
+	stats.AvgRTTMS = total / float64(stats.Received)
+	if jitterSamples > 0 {
+		stats.JitterMS = jitter / float64(jitterSamples)
+	}
+
+	return stats
+}
+
+func resolveProbeTarget(target string) (netip.Addr, error) {
+	if ip, err := netip.ParseAddr(target); err == nil {
+		return ip.Unmap(), nil
+	}
+
+	addr, err := net.ResolveIPAddr("ip", target)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	ip, _ := netip.AddrFromSlice(addr.IP)
+	return ip.Unmap().WithZone(addr.Zone), nil
+}
+
+// sendEcho sends a single echo request and waits for the matching reply
+func sendEcho(conn *icmpsock.Conn, ip netip.Addr, timeout time.Duration) (time.Duration, error) {
+	// Datagram sockets get their identifier assigned by the kernel
+	id := os.Getpid() & 0xffff
+	seq := int(atomic.AddUint32(&icmpSequence, 1) & 0xffff)
+
+	replyType := icmp.Type(ipv4.ICMPTypeEchoReply)
+	if ip.Is6() {
+		replyType = ipv6.ICMPTypeEchoReply
+	}
+
+	if err := conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return 0, err
+	}
+
+	start := time.Now()
+	if err := conn.WriteEcho(ip, id, seq, []byte("NetTool-ICMP-probe")); err != nil {
+		return 0, err
+	}
+
+	buf := make([]byte, 1500)
+	for {
+		packet, err := conn.Read(buf)
+		if isTimeout(err) || errors.Is(err, net.ErrClosed) {
+			return 0, err
+		}
+		if err != nil || packet.From.WithZone("") != ip.WithZone("") || packet.Message.Type != replyType {
+			continue
+		}
+
+		echo, ok := packet.Message.Body.(*icmp.Echo)
+		if !ok || echo.Seq != seq || !conn.OwnsEcho(echo, id) {
+			continue
+		}
+
+		return packet.Received.Sub(start), nil
+	}
+}
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// The connection probers of the dashboard, one per target, so that the
+// statistics cover a rolling window across collection passes
+var (
+	connectionProbersMu sync.Mutex
+	connectionProbers   = make(map[string]*ICMPProber)
+)
+
+func connectionProber(target string) *ICMPProber {
+	connectionProbersMu.Lock()
+	defer connectionProbersMu.Unlock()
+
+	prober, ok := connectionProbers[target]
+	if !ok {
+		prober = NewICMPProber(target, defaultICMPWindow)
+		prober.SetTimeout(750 * time.Millisecond)
+		connectionProbers[target] = prober
+	}
+	return prober
+}
@@ -0,0 +1,145 @@
+package core
+
+import (
+	"errors"
+	"math"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestICMPProberLoopback(t *testing.T) {
+	for _, target := range []string{"127.0.0.1", "::1"} {
+		t.Run(target, func(t *testing.T) {
+			if target == "::1" {
+				ln, err := net.Listen("tcp6", "[::1]:0")
+				if err != nil {
+					t.Skipf("no IPv6 loopback: %v", err)
+				}
+				ln.Close()
+			}
+
+			prober := NewICMPProber(target, 0)
+			err := prober.Probe(3, 10*time.Millisecond)
+			if errors.Is(err, ErrICMPUnavailable) {
+				t.Skip(err)
+			}
+			if err != nil {
+				t.Fatalf("Probe: %v", err)
+			}
+
+			stats := prober.Stats()
+			if stats.Target != target || stats.WindowSize != defaultICMPWindow {
+				t.Errorf("target %q with window %d", stats.Target, stats.WindowSize)
+			}
+			if stats.Sent != 3 || stats.Received != 3 || stats.PacketLoss != 0 {
+				t.Errorf("sent %d, received %d, loss %v%%; want 3 replies", stats.Sent, stats.Received, stats.PacketLoss)
+			}
+			if stats.MinRTTMS > stats.AvgRTTMS || stats.AvgRTTMS > stats.MaxRTTMS || stats.MaxRTTMS <= 0 {
+				t.Errorf("rtt min/avg/max = %v/%v/%v ms", stats.MinRTTMS, stats.AvgRTTMS, stats.MaxRTTMS)
+			}
+			if stats.JitterMS < 0 || stats.JitterMS > stats.MaxRTTMS-stats.MinRTTMS {
+				t.Errorf("jitter %v ms outside the rtt range", stats.JitterMS)
+			}
+			if stats.LastError != "" || stats.LastProbeAt == 0 {
+				t.Errorf("last error %q, last probe at %d", stats.LastError, stats.LastProbeAt)
+			}
+		})
+	}
+}
+
+func TestICMPProberStats(t *testing.T) {
+	ms := func(n int) icmpSample { return icmpSample{rtt: time.Duration(n) * time.Millisecond, received: true} }
+	lost := icmpSample{}
+
+	// The rolling window of 20 holds 10, 20, 30 ms and a lost reply, five
+	// times over, after five slow replies that fall out of it
+	var samples []icmpSample
+	for i := 0; i < 5; i++ {
+		samples = append(samples, ms(500))
+	}
+	for i := 0; i < 5; i++ {
+		samples = append(samples, ms(10), ms(20), lost, ms(30))
+	}
+
+	tests := []struct {
+		name          string
+		window        int
+		samples       []icmpSample
+		sent          int
+		received      int
+		loss          float64
+		min, avg, max float64
+		jitter        float64
+	}{
+		{
+			name:    "default window",
+			samples: samples,
+			sent:    defaultICMPWindow, received: 15, loss: 25,
+			min: 10, avg: 20, max: 30,
+			// 10, 20, 30 repeated: differences of 10, 10 and 20 between
+			// consecutive replies, skipping the lost ones
+			jitter: (10 + 10 + 4*(20+10+10)) / 14.0,
+		},
+		{
+			name:    "small window",
+			window:  3,
+			samples: samples,
+			sent:    3, received: 2, loss: 100.0 / 3,
+			min: 20, avg: 25, max: 30, jitter: 10,
+		},
+		{
+			name:    "all lost",
+			samples: []icmpSample{lost, lost},
+			sent:    2, loss: 100,
+		},
+		{
+			name: "no samples",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prober := NewICMPProber("192.0.2.1", tt.window)
+			for _, sample := range tt.samples {
+				prober.record(sample, true)
+			}
+
+			stats := prober.Stats()
+			want := tt.window
+			if want == 0 {
+				want = defaultICMPWindow
+			}
+			if stats.WindowSize != want {
+				t.Errorf("window %d, want %d", stats.WindowSize, want)
+			}
+			if stats.Sent != tt.sent || stats.Received != tt.received || !near(stats.PacketLoss, tt.loss) {
+				t.Errorf("sent %d, received %d, loss %v%%; want %d, %d, %v%%", stats.Sent, stats.Received, stats.PacketLoss, tt.sent, tt.received, tt.loss)
+			}
+			if !near(stats.MinRTTMS, tt.min) || !near(stats.AvgRTTMS, tt.avg) || !near(stats.MaxRTTMS, tt.max) {
+				t.Errorf("rtt min/avg/max = %v/%v/%v ms, want %v/%v/%v", stats.MinRTTMS, stats.AvgRTTMS, stats.MaxRTTMS, tt.min, tt.avg, tt.max)
+			}
+			if !near(stats.JitterMS, tt.jitter) {
+				t.Errorf("jitter %v ms, want %v", stats.JitterMS, tt.jitter)
+			}
+			if stats.Privileged != (tt.sent > 0) {
+				t.Errorf("privileged = %v", stats.Privileged)
+			}
+		})
+	}
+}
+
+func TestICMPProberRecordsErrors(t *testing.T) {
+	prober := NewICMPProber("host.invalid", 0)
+	if err := prober.Probe(1, 0); err == nil {
+		t.Fatal("probing an unresolvable host succeeded")
+	}
+
+	stats := prober.Stats()
+	if stats.LastError == "" || stats.LastProbeAt == 0 || stats.Sent != 0 {
+		t.Errorf("last error %q at %d after %d requests", stats.LastError, stats.LastProbeAt, stats.Sent)
+	}
+}
+
+func near(got, want float64) bool {
+	return math.Abs(got-want) < 1e-9
+}
@@ -0,0 +1,92 @@
+package core
+
+import (
+	"errors"
+	"net"
+)
+
+// errNetlinkUnsupported is returned on platforms without rtnetlink/nl80211
+var errNetlinkUnsupported = errors.New("netlink is not supported on this platform")
+
+// netlinkLink describes a link as reported by RTM_GETLINK
+type netlinkLink struct {
+	Index        int
+	Name         string
+	MTU          int
+	HardwareAddr net.HardwareAddr
+	Flags        net.Flags
+	OperState    string
+	Kind         string // link kind from IFLA_INFO_KIND, e.g. "vlan"
+	VLANID       int
+	ParentIndex  int
+	Stats        *linkStats
+}
+
+// linkStats holds the 64-bit link counters from IFLA_STATS64
+type linkStats struct {
+	RxPackets uint64
+	TxPackets uint64
+	RxBytes   uint64
+	TxBytes   uint64
+	RxErrors  uint64
+	TxErrors  uint64
+	RxDropped uint64
+	TxDropped uint64
+}
+
+// netlinkAddr describes an interface address as reported by RTM_GETADDR
+type netlinkAddr struct {
+	Index     int
+	IPNet     *net.IPNet
+	Permanent bool // no valid lifetime, i.e. not handed out by DHCP or SLAAC
+}
+
+// netlinkRoute describes a main-table unicast route as reported by RTM_GETROUTE
+type netlinkRoute struct {
+	DstLen   int
+	Gateway  net.IP
+	OutIndex int
+	Priority int
+}
+
+// netlinkNeighbor describes a neighbour cache entry as reported by RTM_GETNEIGH
+type netlinkNeighbor struct {
+	Index        int
+	IP           net.IP
+	HardwareAddr net.HardwareAddr
+	State        uint16
+}
+
+// wirelessStatus holds the association details reported by nl80211
+type wirelessStatus struct {
+	SSID      string
+	Signal    int // in dBm
+	HasSignal bool
+}
+
+// nudNoARP marks neighbour entries that need no resolution, e.g. multicast
+const nudNoARP = 0x40
+
+// neighborStateNames maps NUD_* state bits to the names used by 'ip neigh'
+var neighborStateNames = []struct {
+	bit  uint16
+	name string
+}{
+	{0x80, "PERMANENT"},
+	{0x02, "REACHABLE"},
+	{0x04, "STALE"},
+	{0x08, "DELAY"},
+	{0x10, "PROBE"},
+	{0x20, "FAILED"},
+	{nudNoARP, "NOARP"},
+	{0x01, "INCOMPLETE"},
+}
+
+func neighborStateName(state uint16) string {
+	for _, s := range neighborStateNames {
+		if state&s.bit != 0 {
+			return s.name
+		}
+	}
+	return ""
+}
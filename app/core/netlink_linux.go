@@ -0,0 +1,411 @@
+package core
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"syscall"
+	"time"
+)
+
+// Netlink constants not exported by the syscall package
+const (
+	nlaTypeMask = 0x3fff // strips NLA_F_NESTED and NLA_F_NET_BYTEORDER
+
+	iflaOperState = 16
+	iflaLinkInfo  = 18
+	iflaStats64   = 23
+
+	iflaInfoKind = 1
+	iflaInfoData = 2
+	iflaVLANID   = 1
+
+	ifaFlags      = 8
+	ifaFPermanent = 0x80
+
+	ndaDst    = 1
+	ndaLLAddr = 2
+
+	rtTableMain = 254
+)
+
+var operStateNames = map[uint8]string{
+	0: "unknown",
+	1: "notpresent",
+	2: "down",
+	3: "lowerlayerdown",
+	4: "testing",
+	5: "dormant",
+	6: "up",
+}
+
+// netlinkConn is a minimal synchronous netlink socket
+type netlinkConn struct {
+	fd  int
+	seq uint32
+}
+
+func dialNetlink(protocol int) (*netlinkConn, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW|syscall.SOCK_CLOEXEC, protocol)
+	if err != nil {
+		return nil, fmt.Errorf("netlink socket: %w", err)
+	}
+
+	if err := syscall.Bind(fd, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("netlink bind: %w", err)
+	}
+
+	// Never block a sampling cycle on an unresponsive kernel family
+	tv := syscall.NsecToTimeval(int64(2 * time.Second))
+	if err := syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &tv); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("netlink timeout: %w", err)
+	}
+
+	return &netlinkConn{fd: fd, seq: uint32(time.Now().Unix())}, nil
+}
+
+func (c *netlinkConn) Close() error {
+	return syscall.Close(c.fd)
+}
+
+// request sends a single netlink message and collects every reply belonging to
+// it, following multipart dumps until NLMSG_DONE.
+func (c *netlinkConn) request(msgType, flags uint16, body []byte) ([]syscall.NetlinkMessage, error) {
+	c.seq++
+	seq := c.seq
+
+	msg := make([]byte, syscall.NLMSG_HDRLEN, syscall.NLMSG_HDRLEN+len(body))
+	binary.NativeEndian.PutUint32(msg[0:4], uint32(syscall.NLMSG_HDRLEN+len(body)))
+	binary.NativeEndian.PutUint16(msg[4:6], msgType)
+	binary.NativeEndian.PutUint16(msg[6:8], syscall.NLM_F_REQUEST|flags)
+	binary.NativeEndian.PutUint32(msg[8:12], seq)
+	msg = append(msg, body...)
+
+	if err := syscall.Sendto(c.fd, msg, 0, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}); err != nil {
+		return nil, fmt.Errorf("netlink send: %w", err)
+	}
+
+	var replies []syscall.NetlinkMessage
+	for {
+		// Parsed messages alias the buffer, so every read needs its own
+		buf := make([]byte, 64*1024)
+		n, _, err := syscall.Recvfrom(c.fd, buf, 0)
+		if err != nil {
+			return nil, fmt.Errorf("netlink receive: %w", err)
+		}
+
+		msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			return nil, fmt.Errorf("netlink parse: %w", err)
+		}
+
+		for _, m := range msgs {
+			if m.Header.Seq != seq {
+				continue
+			}
+
+			switch m.Header.Type {
+			case syscall.NLMSG_DONE:
+				if errno := netlinkErrno(m.Data); errno != 0 {
+					return nil, errno
+				}
+				return replies, nil
+			case syscall.NLMSG_ERROR:
+				if errno := netlinkErrno(m.Data); errno != 0 {
+					return nil, errno
+				}
+				return replies, nil
+			}
+
+			replies = append(replies, m)
+			if m.Header.Flags&syscall.NLM_F_MULTI == 0 {
+				return replies, nil
+			}
+		}
+	}
+}
+
+func netlinkErrno(data []byte) syscall.Errno {
+	if len(data) < 4 {
+		return 0
+	}
+	code := int32(binary.NativeEndian.Uint32(data[0:4]))
+	if code >= 0 {
+		return 0
+	}
+	return syscall.Errno(-code)
+}
+
+// parseNetlinkAttrs decodes a run of netlink attributes keyed by type
+func parseNetlinkAttrs(b []byte) map[uint16][]byte {
+	attrs := make(map[uint16][]byte)
+	for len(b) >= syscall.SizeofRtAttr {
+		length := int(binary.NativeEndian.Uint16(b[0:2]))
+		typ := binary.NativeEndian.Uint16(b[2:4]) & nlaTypeMask
+		if length < syscall.SizeofRtAttr || length > len(b) {
+			break
+		}
+		attrs[typ] = b[syscall.SizeofRtAttr:length]
+
+		aligned := (length + syscall.RTA_ALIGNTO - 1) &^ (syscall.RTA_ALIGNTO - 1)
+		if aligned > len(b) {
+			break
+		}
+		b = b[aligned:]
+	}
+	return attrs
+}
+
+// appendNetlinkAttr encodes a single netlink attribute onto b
+func appendNetlinkAttr(b []byte, typ uint16, data []byte) []byte {
+	length := syscall.SizeofRtAttr + len(data)
+	hdr := make([]byte, syscall.SizeofRtAttr)
+	binary.NativeEndian.PutUint16(hdr[0:2], uint16(length))
+	binary.NativeEndian.PutUint16(hdr[2:4], typ)
+	b = append(b, hdr...)
+	b = append(b, data...)
+	for len(b)%syscall.RTA_ALIGNTO != 0 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+func netlinkString(b []byte) string {
+	for i, c := range b {
+		if c == 0 {
+			return string(b[:i])
+		}
+	}
+	return string(b)
+}
+
+func rtnetlinkDump(msgType uint16, body []byte) ([]syscall.NetlinkMessage, error) {
+	conn, err := dialNetlink(syscall.NETLINK_ROUTE)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	return conn.request(msgType, syscall.NLM_F_DUMP, body)
+}
+
+// listLinks returns every link with its counters and VLAN details
+func listLinks() ([]netlinkLink, error) {
+	msgs, err := rtnetlinkDump(syscall.RTM_GETLINK, make([]byte, syscall.SizeofIfInfomsg))
+	if err != nil {
+		return nil, err
+	}
+
+	links := make([]netlinkLink, 0, len(msgs))
+	for _, m := range msgs {
+		if m.Header.Type != syscall.RTM_NEWLINK || len(m.Data) < syscall.SizeofIfInfomsg {
+			continue
+		}
+
+		link := netlinkLink{
+			Index: int(int32(binary.NativeEndian.Uint32(m.Data[4:8]))),
+			Flags: linkFlags(binary.NativeEndian.Uint32(m.Data[8:12])),
+		}
+
+		attrs := parseNetlinkAttrs(m.Data[syscall.SizeofIfInfomsg:])
+		link.Name = netlinkString(attrs[syscall.IFLA_IFNAME])
+		if v, ok := attrs[syscall.IFLA_MTU]; ok && len(v) >= 4 {
+			link.MTU = int(binary.NativeEndian.Uint32(v))
+		}
+		if v, ok := attrs[syscall.IFLA_ADDRESS]; ok && len(v) > 0 {
+			link.HardwareAddr = net.HardwareAddr(append([]byte(nil), v...))
+		}
+		if v, ok := attrs[syscall.IFLA_LINK]; ok && len(v) >= 4 {
+			link.ParentIndex = int(int32(binary.NativeEndian.Uint32(v)))
+		}
+		if v, ok := attrs[iflaOperState]; ok && len(v) >= 1 {
+			link.OperState = operStateNames[v[0]]
+		}
+		if v, ok := attrs[iflaStats64]; ok && len(v) >= 64 {
+			link.Stats = &linkStats{
+				RxPackets: binary.NativeEndian.Uint64(v[0:8]),
+				TxPackets: binary.NativeEndian.Uint64(v[8:16]),
+				RxBytes:   binary.NativeEndian.Uint64(v[16:24]),
+				TxBytes:   binary.NativeEndian.Uint64(v[24:32]),
+				RxErrors:  binary.NativeEndian.Uint64(v[32:40]),
+				TxErrors:  binary.NativeEndian.Uint64(v[40:48]),
+				RxDropped: binary.NativeEndian.Uint64(v[48:56]),
+				TxDropped: binary.NativeEndian.Uint64(v[56:64]),
+			}
+		}
+		if v, ok := attrs[iflaLinkInfo]; ok {
+			info := parseNetlinkAttrs(v)
+			link.Kind = netlinkString(info[iflaInfoKind])
+			if link.Kind == "vlan" {
+				data := parseNetlinkAttrs(info[iflaInfoData])
+				if id, ok := data[iflaVLANID]; ok && len(id) >= 2 {
+					link.VLANID = int(binary.NativeEndian.Uint16(id))
+				}
+			}
+		}
+
+		links = append(links, link)
+	}
+
+	return links, nil
+}
+
+// linkFlags converts IFF_* flags to net.Flags the same way the net package does
+func linkFlags(rawFlags uint32) net.Flags {
+	var f net.Flags
+	if rawFlags&syscall.IFF_UP != 0 {
+		f |= net.FlagUp
+	}
+	if rawFlags&syscall.IFF_RUNNING != 0 {
+		f |= net.FlagRunning
+	}
+	if rawFlags&syscall.IFF_BROADCAST != 0 {
+		f |= net.FlagBroadcast
+	}
+	if rawFlags&syscall.IFF_LOOPBACK != 0 {
+		f |= net.FlagLoopback
+	}
+	if rawFlags&syscall.IFF_POINTOPOINT != 0 {
+		f |= net.FlagPointToPoint
+	}
+	if rawFlags&syscall.IFF_MULTICAST != 0 {
+		f |= net.FlagMulticast
+	}
+	return f
+}
+
+// listAddrs returns every IPv4 and IPv6 interface address
+func listAddrs() ([]netlinkAddr, error) {
+	msgs, err := rtnetlinkDump(syscall.RTM_GETADDR, make([]byte, syscall.SizeofIfAddrmsg))
+	if err != nil {
+		return nil, err
+	}
+
+	addrs := make([]netlinkAddr, 0, len(msgs))
+	for _, m := range msgs {
+		if m.Header.Type != syscall.RTM_NEWADDR || len(m.Data) < syscall.SizeofIfAddrmsg {
+			continue
+		}
+
+		family := m.Data[0]
+		prefixLen := int(m.Data[1])
+		attrs := parseNetlinkAttrs(m.Data[syscall.SizeofIfAddrmsg:])
+
+		// IFA_LOCAL is the local address on point-to-point links
+		raw, ok := attrs[syscall.IFA_LOCAL]
+		if !ok {
+			raw, ok = attrs[syscall.IFA_ADDRESS]
+		}
+		if !ok {
+			continue
+		}
+
+		bits := 8 * net.IPv4len
+		if family == syscall.AF_INET6 {
+			bits = 8 * net.IPv6len
+		}
+		if len(raw)*8 != bits {
+			continue
+		}
+
+		// IFA_FLAGS carries the full 32-bit flags, the header only the low byte
+		flags := uint32(m.Data[2])
+		if v, ok := attrs[ifaFlags]; ok && len(v) >= 4 {
+			flags = binary.NativeEndian.Uint32(v)
+		}
+
+		addrs = append(addrs, netlinkAddr{
+			Index: int(binary.NativeEndian.Uint32(m.Data[4:8])),
+			IPNet: &net.IPNet{
+				IP:   net.IP(append([]byte(nil), raw...)),
+				Mask: net.CIDRMask(prefixLen, bits),
+			},
+			Permanent: flags&ifaFPermanent != 0,
+		})
+	}
+
+	return addrs, nil
+}
+
+// listRoutes returns the unicast routes of the main table for the given family
+func listRoutes(family int) ([]netlinkRoute, error) {
+	body := make([]byte, syscall.SizeofRtMsg)
+	body[0] = byte(family)
+
+	msgs, err := rtnetlinkDump(syscall.RTM_GETROUTE, body)
+	if err != nil {
+		return nil, err
+	}
+
+	var routes []netlinkRoute
+	for _, m := range msgs {
+		if m.Header.Type != syscall.RTM_NEWROUTE || len(m.Data) < syscall.SizeofRtMsg {
+			continue
+		}
+		if int(m.Data[0]) != family || m.Data[7] != syscall.RTN_UNICAST {
+			continue
+		}
+
+		attrs := parseNetlinkAttrs(m.Data[syscall.SizeofRtMsg:])
+
+		table := uint32(m.Data[4])
+		if v, ok := attrs[syscall.RTA_TABLE]; ok && len(v) >= 4 {
+			table = binary.NativeEndian.Uint32(v)
+		}
+		if table != rtTableMain {
+			continue
+		}
+
+		route := netlinkRoute{DstLen: int(m.Data[1])}
+		if v, ok := attrs[syscall.RTA_GATEWAY]; ok {
+			route.Gateway = net.IP(append([]byte(nil), v...))
+		}
+		if v, ok := attrs[syscall.RTA_OIF]; ok && len(v) >= 4 {
+			route.OutIndex = int(binary.NativeEndian.Uint32(v))
+		}
+		if v, ok := attrs[syscall.RTA_PRIORITY]; ok && len(v) >= 4 {
+			route.Priority = int(binary.NativeEndian.Uint32(v))
+		}
+
+		routes = append(routes, route)
+	}
+
+	return routes, nil
+}
+
+// listNeighbors returns the IPv4 and IPv6 neighbour cache
+func listNeighbors() ([]netlinkNeighbor, error) {
+	// struct ndmsg: family, pad1, pad2, ifindex, state, flags, type
+	msgs, err := rtnetlinkDump(syscall.RTM_GETNEIGH, make([]byte, 12))
+	if err != nil {
+		return nil, err
+	}
+
+	var neighbors []netlinkNeighbor
+	for _, m := range msgs {
+		if m.Header.Type != syscall.RTM_NEWNEIGH || len(m.Data) < 12 {
+			continue
+		}
+
+		attrs := parseNetlinkAttrs(m.Data[12:])
+		dst, ok := attrs[ndaDst]
+		if !ok || (len(dst) != net.IPv4len && len(dst) != net.IPv6len) {
+			continue
+		}
+
+		neighbor := netlinkNeighbor{
+			Index: int(int32(binary.NativeEndian.Uint32(m.Data[4:8]))),
+			IP:    net.IP(append([]byte(nil), dst...)),
+			State: binary.NativeEndian.Uint16(m.Data[8:10]),
+		}
+		if lladdr, ok := attrs[ndaLLAddr]; ok {
+			neighbor.HardwareAddr = net.HardwareAddr(append([]byte(nil), lladdr...))
+		}
+
+		neighbors = append(neighbors, neighbor)
+	}
+
+	return neighbors, nil
+}
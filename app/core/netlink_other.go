@@ -0,0 +1,23 @@
+//go:build !linux
+
+package core
+
+func listLinks() ([]netlinkLink, error) {
+	return nil, errNetlinkUnsupported
+}
+
+func listAddrs() ([]netlinkAddr, error) {
+	return nil, errNetlinkUnsupported
+}
+
+func listRoutes(_ int) ([]netlinkRoute, error) {
+	return nil, errNetlinkUnsupported
+}
+
+func listNeighbors() ([]netlinkNeighbor, error) {
+	return nil, errNetlinkUnsupported
+}
+
+func getWirelessStatus(_ int) (wirelessStatus, bool, error) {
+	return wirelessStatus{}, false, errNetlinkUnsupported
+}
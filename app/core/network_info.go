@@ -5,14 +5,15 @@ import (
 	"bufio"
 	"bytes"
 	"encoding/binary"
-	"io"
+	"errors"
+	"fmt"
 	"net"
-	"net/http"
 	"os"
 	"os/exec"
+	"sort"
 	"strconv"
 	"strings"
-	"sync"
+	"syscall"
 	"time"
 
 	psnet "github.com/shirou/gopsutil/v3/net"
@@ -20,36 +21,82 @@ import (
 
 // NetworkInfo represents the network information for the device
 type NetworkInfo struct {
-	IPv4Address    string         `json:"ipv4Address"`
-	IPv6Address    string         `json:"ipv6Address"`
-	SubnetMask     string         `json:"subnetMask"`
-	Gateway        string         `json:"gateway"`
-	SSID           string         `json:"ssid,omitempty"`
-	EthernetInfo   EthernetInfo   `json:"ethernetInfo,omitempty"`
-	DNSServers     []string       `json:"dnsServers"`
-	DHCPInfo       DHCPInfo       `json:"dhcpInfo"`
-	VLANInfo       VLANInfo       `json:"vlanInfo,omitempty"`
-	Connection     Connection     `json:"connection"`
-	Traffic        Traffic        `json:"traffic"`
-	ARPEntries     []ARPEntry     `json:"arpEntries"`
-	ServiceLatency ServiceLatency `json:"serviceLatency"`
-	Timestamp      time.Time      `json:"timestamp"`
+	IPv4Address    string           `json:"ipv4Address"`
+	IPv6Address    string           `json:"ipv6Address"`
+	SubnetMask     string           `json:"subnetMask"`
+	Gateway        string           `json:"gateway"`
+	SSID           string           `json:"ssid,omitempty"`
+	EthernetInfo   EthernetInfo     `json:"ethernetInfo,omitempty"`
+	DNSServers     []string         `json:"dnsServers"`
+	DHCPInfo       DHCPInfo         `json:"dhcpInfo"`
+	VLANInfo       VLANInfo         `json:"vlanInfo,omitempty"`
+	Connection     Connection       `json:"connection"`
+	Traffic        Traffic          `json:"traffic"`
+	ARPEntries     []ARPEntry       `json:"arpEntries"`
+	ServiceLatency []ServiceLatency `json:"serviceLatency"`
+	Interfaces     []InterfaceInfo  `json:"interfaces"`
+	Timestamp      time.Time        `json:"timestamp"`
 }
 
+// InterfaceInfo represents the state of a single network interface
+type InterfaceInfo struct {
+	Name           string       `json:"name"`
+	Index          int          `json:"index"`
+	MACAddress     string       `json:"macAddress"`
+	MTU            int          `json:"mtu"`
+	Up             bool         `json:"up"`
+	Primary        bool         `json:"primary"`
+	IPv4Address    string       `json:"ipv4Address"`
+	IPv6Address    string       `json:"ipv6Address"`
+	SubnetMask     string       `json:"subnetMask"`
+	Addresses      []string     `json:"addresses"` // all addresses in CIDR notation
+	Gateway        string       `json:"gateway,omitempty"`
+	Wireless       bool         `json:"wireless"`
+	SSID           string       `json:"ssid,omitempty"`
+	SignalStrength int          `json:"signalStrength,omitempty"` // in dBm
+	EthernetInfo   EthernetInfo `json:"ethernetInfo"`
+	DHCPInfo       DHCPInfo     `json:"dhcpInfo"`
+	VLANInfo       VLANInfo     `json:"vlanInfo"`
+	Traffic        Traffic      `json:"traffic"`
+}
+
+// ErrInterfaceNotFound is returned when a requested interface does not exist
+var ErrInterfaceNotFound = errors.New("interface not found")
+
 // EthernetInfo represents ethernet connection details
 type EthernetInfo struct {
-	InterfaceName string `json:"interfaceName"`
-	MACAddress    string `json:"macAddress"`
-	Speed         string `json:"speed"`
-	Duplex        string `json:"duplex"`
+	InterfaceName   string   `json:"interfaceName"`
+	MACAddress      string   `json:"macAddress"`
+	Speed           string   `json:"speed"`                     // e.g. "1 Gbps", "Unknown"
+	SpeedMbps       int      `json:"speedMbps,omitempty"`       // 0 when unknown
+	Duplex          string   `json:"duplex"`                    // "Full", "Half" or "Unknown"
+	AutoNegotiation string   `json:"autoNegotiation,omitempty"` // "on" or "off"
+	SupportedModes  []string `json:"supportedModes,omitempty"`
+	AdvertisedModes []string `json:"advertisedModes,omitempty"`
+	Carrier         bool     `json:"carrier"`
+	CarrierChanges  int      `json:"carrierChanges"`
+	Driver          string   `json:"driver,omitempty"`
+	DriverVersion   string   `json:"driverVersion,omitempty"`
+	FirmwareVersion string   `json:"firmwareVersion,omitempty"`
+	BusInfo         string   `json:"busInfo,omitempty"`
 }
 
 // DHCPInfo represents DHCP configuration
 type DHCPInfo struct {
 	Enabled       bool      `json:"enabled"`
-	LeaseObtained time.Time `json:"leaseObtained,omitempty"`
-	LeaseExpires  time.Time `json:"leaseExpires,omitempty"`
-	DHCPServer    string    `json:"dhcpServer,omitempty"`
+	Static        bool      `json:"static"`           // address is statically configured
+	Client        string    `json:"client,omitempty"` // DHCP client holding the lease
+	LeasedAddress string    `json:"leasedAddress,omitempty"`
+	LeaseObtained time.Time `json:"leaseObtained,omitzero"`
+	LeaseExpires  time.Time `json:"leaseExpires,omitzero"`
+	RenewAt       time.Time `json:"renewAt,omitzero"`     // T1
+	RebindAt      time.Time `json:"rebindAt,omitzero"`    // T2
+	LeaseTime     int64     `json:"leaseTime,omitempty"`  // in seconds
+	DHCPServer    string    `json:"dhcpServer,omitempty"` // server identifier (option 54)
+	Routers       []string  `json:"routers,omitempty"`
+	DNSServers    []string  `json:"dnsServers,omitempty"`
+	DomainName    string    `json:"domainName,omitempty"`
+	NTPServers    []string  `json:"ntpServers,omitempty"`
 }
 
 // VLANInfo represents VLAN configuration if applicable
@@ -62,10 +109,15 @@ type VLANInfo struct {
 
 // Connection represents connection status and metrics
 type Connection struct {
-	Status         string  `json:"status"` // "connected", "disconnected", "limited"
-	Uptime         int64   `json:"uptime"` // in seconds
-	LatencyMS      float64 `json:"latencyMs"`
+	Status         string  `json:"status"`    // "connected", "disconnected", "limited"
+	Uptime         int64   `json:"uptime"`    // in seconds
+	LatencyMS      float64 `json:"latencyMs"` // average RTT over the probe window
+	LatencyMinMS   float64 `json:"latencyMinMs"`
+	LatencyMaxMS   float64 `json:"latencyMaxMs"`
+	JitterMS       float64 `json:"jitterMs"`
 	PacketLoss     float64 `json:"packetLoss"`               // percentage
+	ProbeTarget    string  `json:"probeTarget,omitempty"`    // host the metrics were measured against
+	ProbeMethod    string  `json:"probeMethod,omitempty"`    // "icmp" or "tcp"
 	SignalStrength int     `json:"signalStrength,omitempty"` // for wireless, in dBm
 }
 
@@ -75,7 +127,12 @@ type Traffic struct {
 	BytesSent        int64   `json:"bytesSent"`
 	PacketsReceived  int64   `json:"packetsReceived"`
 	PacketsSent      int64   `json:"packetsSent"`
-	CurrentBandwidth float64 `json:"currentBandwidth"` // in Mbps
+	ErrorsReceived   int64   `json:"errorsReceived"`
+	ErrorsSent       int64   `json:"errorsSent"`
+	DropsReceived    int64   `json:"dropsReceived"`
+	DropsSent        int64   `json:"dropsSent"`
+	CurrentBandwidth float64 `json:"currentBandwidth"` // RX plus TX, in Mbps (10^6 bits/s)
+	TrafficRates
 }
 
 // ARPEntry represents a single entry in the ARP table (IP to MAC mapping)
@@ -86,25 +143,138 @@ type ARPEntry struct {
 	State      string `json:"state"`
 }
 
-// ServiceLatency represents latency measurements to various major services
-type ServiceLatency struct {
-	Google     float64 `json:"google"`     // Google latency in ms
-	Amazon     float64 `json:"amazon"`     // Amazon latency in ms
-	Cloudflare float64 `json:"cloudflare"` // Cloudflare latency in ms
-	Microsoft  float64 `json:"microsoft"`  // Microsoft latency in ms
-	DNS        float64 `json:"dns"`        // DNS latency in ms
-	HTTP       float64 `json:"http"`       // HTTP latency in ms
+// defaultRoute describes a default route entry from the kernel routing table
+type defaultRoute struct {
+	Iface   string
+	Gateway string
+	Metric  int
+}
+
+// linkState bundles what a single collection pass knows about an interface
+type linkState struct {
+	iface   net.Interface
+	addrs   []*net.IPNet
+	counter *psnet.IOCountersStat
+	kind    string // link kind reported by rtnetlink, e.g. "vlan"
+	vlanID  int
+	// permanent reports per address whether it lacks a valid lifetime,
+	// nil when the address flags are unknown
+	permanent map[string]bool
+}
+
+// GetAllInterfaces returns every non-loopback interface with its addresses,
+// counters, bandwidth and wireless/VLAN details. The interface carrying the
+// preferred default route is marked as primary.
+func GetAllInterfaces() ([]InterfaceInfo, error) {
+	states, err := gatherLinks()
+	if err != nil {
+		return nil, err
+	}
+
+	ifaces := make([]net.Interface, 0, len(states))
+	names := make(map[string]bool, len(states))
+	for _, state := range states {
+		ifaces = append(ifaces, state.iface)
+		names[state.iface.Name] = true
+	}
+	defaultRateTracker.Forget(names)
+
+	routes := getDefaultRoutes()
+	primary := selectPrimaryInterface(ifaces, routes)
+
+	infos := make([]InterfaceInfo, 0, len(states))
+	for _, state := range states {
+		if state.iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+
+		info := collectInterfaceInfo(state, routes)
+		info.Primary = state.iface.Name == primary
+		infos = append(infos, info)
+	}
+
+	return infos, nil
+}
+
+// gatherLinks reads links, addresses and counters over rtnetlink, falling back
+// to the net package and gopsutil when netlink is unavailable
+func gatherLinks() ([]linkState, error) {
+	links, err := listLinks()
+	if err == nil {
+		var addrs []netlinkAddr
+		addrs, err = listAddrs()
+		if err == nil {
+			return linkStatesFromNetlink(links, addrs), nil
+		}
+	}
+
+	if err != errNetlinkUnsupported {
+		fmt.Printf("Warning: netlink link query failed, falling back: %v\n", err)
+	}
+
+	return gatherLinksFallback()
 }
 
-func findPrimaryInterface() (*net.Interface, *psnet.IOCountersStat, error) {
+func linkStatesFromNetlink(links []netlinkLink, addrs []netlinkAddr) []linkState {
+	addrsByIndex := make(map[int][]*net.IPNet)
+	permanentByIndex := make(map[int]map[string]bool)
+	for _, addr := range addrs {
+		addrsByIndex[addr.Index] = append(addrsByIndex[addr.Index], addr.IPNet)
+		if permanentByIndex[addr.Index] == nil {
+			permanentByIndex[addr.Index] = make(map[string]bool)
+		}
+		permanentByIndex[addr.Index][addr.IPNet.IP.String()] = addr.Permanent
+	}
+
+	sort.Slice(links, func(i, j int) bool {
+		return links[i].Index < links[j].Index
+	})
+
+	states := make([]linkState, 0, len(links))
+	for _, link := range links {
+		state := linkState{
+			iface: net.Interface{
+				Index:        link.Index,
+				MTU:          link.MTU,
+				Name:         link.Name,
+				HardwareAddr: link.HardwareAddr,
+				Flags:        link.Flags,
+			},
+			addrs:     addrsByIndex[link.Index],
+			kind:      link.Kind,
+			vlanID:    link.VLANID,
+			permanent: permanentByIndex[link.Index],
+		}
+
+		if link.Stats != nil {
+			state.counter = &psnet.IOCountersStat{
+				Name:        link.Name,
+				BytesSent:   link.Stats.TxBytes,
+				BytesRecv:   link.Stats.RxBytes,
+				PacketsSent: link.Stats.TxPackets,
+				PacketsRecv: link.Stats.RxPackets,
+				Errin:       link.Stats.RxErrors,
+				Errout:      link.Stats.TxErrors,
+				Dropin:      link.Stats.RxDropped,
+				Dropout:     link.Stats.TxDropped,
+			}
+		}
+
+		states = append(states, state)
+	}
+
+	return states
+}
+
+func gatherLinksFallback() ([]linkState, error) {
 	ifaces, err := net.Interfaces()
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
 	counters, err := psnet.IOCounters(true)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
 	counterMap := make(map[string]psnet.IOCountersStat, len(counters))
@@ -112,67 +282,172 @@ func findPrimaryInterface() (*net.Interface, *psnet.IOCountersStat, error) {
 		counterMap[c.Name] = c
 	}
 
+	states := make([]linkState, 0, len(ifaces))
 	for _, iface := range ifaces {
-		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
-			continue
+		state := linkState{iface: iface}
+
+		if addrs, err := iface.Addrs(); err == nil {
+			for _, addr := range addrs {
+				if ipNet, ok := addr.(*net.IPNet); ok {
+					state.addrs = append(state.addrs, ipNet)
+				}
+			}
 		}
 
 		if c, ok := counterMap[iface.Name]; ok {
-			counter := c
-			ifaceCopy := iface
-			return &ifaceCopy, &counter, nil
+			state.counter = &c
 		}
 
-		ifaceCopy := iface
-		return &ifaceCopy, nil, nil
+		states = append(states, state)
 	}
 
-	return nil, nil, nil
+	return states, nil
 }
 
-func extractIPInfo(iface *net.Interface) (string, string, string) {
-	if iface == nil {
-		return "", "", ""
+// selectPrimaryInterface picks the interface holding the lowest-metric default
+// route, falling back to the first interface that is up and not a loopback.
+func selectPrimaryInterface(ifaces []net.Interface, routes []defaultRoute) string {
+	up := make(map[string]bool, len(ifaces))
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp != 0 && iface.Flags&net.FlagLoopback == 0 {
+			up[iface.Name] = true
+		}
 	}
 
-	addrs, err := iface.Addrs()
-	if err != nil {
-		return "", "", ""
+	for _, route := range routes {
+		if up[route.Iface] {
+			return route.Iface
+		}
+	}
+
+	for _, iface := range ifaces {
+		if up[iface.Name] {
+			return iface.Name
+		}
 	}
 
+	return ""
+}
+
+func collectInterfaceInfo(state linkState, routes []defaultRoute) InterfaceInfo {
+	iface := state.iface
+	ipv4, ipv6, subnet, addresses := extractIPInfo(state.addrs)
+
+	info := InterfaceInfo{
+		Name:         iface.Name,
+		Index:        iface.Index,
+		MACAddress:   iface.HardwareAddr.String(),
+		MTU:          iface.MTU,
+		Up:           iface.Flags&net.FlagUp != 0,
+		IPv4Address:  ipv4,
+		IPv6Address:  ipv6,
+		SubnetMask:   subnet,
+		Addresses:    addresses,
+		EthernetInfo: getEthernetInfo(iface),
+		VLANInfo:     VLANInfo{Enabled: false},
+	}
+
+	for _, route := range routes {
+		if route.Iface == iface.Name && route.Gateway != "" {
+			info.Gateway = route.Gateway
+			break
+		}
+	}
+
+	if counter := state.counter; counter != nil {
+		info.Traffic = trafficFromCounter(defaultRateTracker, iface.Name, *counter, time.Now())
+	}
+
+	info.Wireless, info.SSID, info.SignalStrength = getWirelessInfo(iface)
+	info.DHCPInfo = getDHCPInfo(iface.Name, iface.Index, ipv4, state.permanent)
+
+	if state.kind == "vlan" {
+		info.VLANInfo = VLANInfo{
+			Enabled: true,
+			VLANID:  state.vlanID,
+			Name:    fmt.Sprintf("VLAN %d", state.vlanID),
+		}
+	} else if state.kind == "" && strings.Contains(iface.Name, ".") {
+		// Link kind is unknown without netlink, so rely on the naming convention
+		vlanComponent := iface.Name[strings.LastIndex(iface.Name, ".")+1:]
+		info.VLANInfo = VLANInfo{
+			Enabled: true,
+			VLANID:  getVLANID(iface.Name),
+			Name:    "VLAN " + vlanComponent,
+		}
+	}
+
+	return info
+}
+
+func extractIPInfo(addrs []*net.IPNet) (string, string, string, []string) {
 	var ipv4, ipv6, subnet string
-	for _, addr := range addrs {
-		if ipNet, ok := addr.(*net.IPNet); ok {
-			if ip := ipNet.IP.To4(); ip != nil {
+	addresses := make([]string, 0, len(addrs))
+	for _, ipNet := range addrs {
+		addresses = append(addresses, ipNet.String())
+		if ip := ipNet.IP.To4(); ip != nil {
+			if ipv4 == "" {
 				ipv4 = ip.String()
 				ones, _ := ipNet.Mask.Size()
 				subnet = cidrToSubnet(ones)
-			} else if ipv6 == "" {
-				ipv6 = ipNet.IP.String()
 			}
+		} else if ipv6 == "" {
+			ipv6 = ipNet.IP.String()
 		}
 	}
 
-	return ipv4, ipv6, subnet
+	return ipv4, ipv6, subnet, addresses
 }
 
-func getConnectionMetrics(gateway string) (float64, float64) {
+// getConnectionMetrics measures the connection health with ICMP echo requests
+// to the gateway, falling back to a public resolver when the gateway does not
+// answer. TCP connects are only used when no ICMP socket can be opened.
+func getConnectionMetrics(gateway string) Connection {
 	targets := []string{}
 	if gateway != "" && gateway != "N/A" {
 		targets = append(targets, gateway)
 	}
 	targets = append(targets, "8.8.8.8")
 
+	for _, target := range targets {
+		prober := connectionProber(target)
+		err := prober.Probe(3, 100*time.Millisecond)
+		if errors.Is(err, ErrICMPUnavailable) {
+			break
+		}
+
+		stats := prober.Stats()
+		if stats.Received > 0 {
+			return Connection{
+				LatencyMS:    stats.AvgRTTMS,
+				LatencyMinMS: stats.MinRTTMS,
+				LatencyMaxMS: stats.MaxRTTMS,
+				JitterMS:     stats.JitterMS,
+				PacketLoss:   stats.PacketLoss,
+				ProbeTarget:  target,
+				ProbeMethod:  "icmp",
+			}
+		}
+	}
+
 	for _, target := range targets {
 		latency, loss := probeConnection(target)
 		if latency > 0 || loss < 100 {
-			return latency, loss
+			return Connection{
+				LatencyMS:    latency,
+				LatencyMinMS: latency,
+				LatencyMaxMS: latency,
+				PacketLoss:   loss,
+				ProbeTarget:  target,
+				ProbeMethod:  "tcp",
+			}
 		}
 	}
 
-	return 0, 100
+	return Connection{PacketLoss: 100}
 }
 
+// probeConnection estimates latency by dialing the DNS port of target
 func probeConnection(target string) (float64, float64) {
 	const attempts = 3
 	const timeout = 750 * time.Millisecond
@@ -201,187 +476,149 @@ func probeConnection(target string) (float64, float64) {
 	return total / float64(successes), loss
 }
 
-func measureServiceLatencies() ServiceLatency {
-	services := map[string]string{
-		"google":     "google.com",
-		"amazon":     "amazon.com",
-		"cloudflare": "cloudflare.com",
-		"microsoft":  "microsoft.com",
-	}
+// GetNetworkInfo retrieves the current network information for the primary interface
+func GetNetworkInfo() (*NetworkInfo, error) {
+	return GetNetworkInfoForInterface("")
+}
 
-	type result struct {
-		name    string
-		latency float64
+// GetNetworkInfoForInterface retrieves the current network information with the
+// summary fields describing the named interface. An empty name selects the
+// primary interface and reports every interface in Interfaces; a non-empty
+// name restricts Interfaces to that interface.
+func GetNetworkInfoForInterface(name string) (*NetworkInfo, error) {
+	interfaces, err := GetAllInterfaces()
+	if err != nil {
+		return nil, err
 	}
 
-	results := make(chan result, len(services))
-	var wg sync.WaitGroup
-
-	for name, host := range services {
-		wg.Add(1)
-		go func(n, h string) {
-			defer wg.Done()
-			results <- result{name: n, latency: measureTCPLatency(h)}
-		}(name, host)
+	sources := networkSources{
+		interfaces: interfaces,
+		gateway:    getDefaultGateway(),
+		dnsServers: GetDNSServers(),
+		connection: getConnectionMetrics,
 	}
 
-	wg.Wait()
-	close(results)
-
-	lat := ServiceLatency{}
-	for res := range results {
-		switch res.name {
-		case "google":
-			lat.Google = res.latency
-		case "amazon":
-			lat.Amazon = res.latency
-		case "cloudflare":
-			lat.Cloudflare = res.latency
-		case "microsoft":
-			lat.Microsoft = res.latency
-		}
+	if entries, err := GetARPTable(); err == nil {
+		sources.arp = entries
 	}
 
-	lat.DNS = measureDNSLookupLatency()
-	lat.HTTP = measureHTTPSLatency()
+	sources.services = measureServiceLatencies()
 
-	return lat
+	return assembleNetworkInfo(name, sources)
 }
 
-func measureTCPLatency(host string) float64 {
-	ports := []string{"443", "80"}
-	for _, port := range ports {
-		start := time.Now()
-		conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, port), 750*time.Millisecond)
-		if err != nil {
-			continue
+// networkSources holds the collected data a NetworkInfo is assembled from
+type networkSources struct {
+	interfaces []InterfaceInfo
+	gateway    string // default gateway, "N/A" when there is none
+	dnsServers []string
+	arp        []ARPEntry
+	services   []ServiceLatency
+	connection func(gateway string) Connection
+}
+
+// assembleNetworkInfo builds the report for the named interface, or for the
+// primary interface when name is empty
+func assembleNetworkInfo(name string, sources networkSources) (*NetworkInfo, error) {
+	interfaces := sources.interfaces
+
+	var selected *InterfaceInfo
+	for i := range interfaces {
+		if (name == "" && interfaces[i].Primary) || (name != "" && interfaces[i].Name == name) {
+			selected = &interfaces[i]
+			break
 		}
-		latency := float64(time.Since(start).Milliseconds())
-		_ = conn.Close()
-		return latency
 	}
-	return 0
-}
 
-func measureDNSLookupLatency() float64 {
-	start := time.Now()
-	_, err := net.LookupHost("www.google.com")
-	if err != nil {
-		return 0
+	if name != "" {
+		if selected == nil {
+			return nil, fmt.Errorf("%w: %s", ErrInterfaceNotFound, name)
+		}
+		interfaces = []InterfaceInfo{*selected}
+		selected = &interfaces[0]
 	}
-	return float64(time.Since(start).Milliseconds())
-}
 
-func measureHTTPSLatency() float64 {
-	client := &http.Client{
-		Timeout: 3 * time.Second,
+	gateway := sources.gateway
+	if selected != nil && name != "" {
+		gateway = selected.Gateway
+		if gateway == "" {
+			gateway = "N/A"
+		}
 	}
 
-	urls := []string{"https://www.google.com", "https://www.cloudflare.com"}
-	for _, url := range urls {
-		start := time.Now()
-		resp, err := client.Head(url)
-		if err != nil {
-			continue
+	connection := sources.connection(gateway)
+	connection.Status = "disconnected"
+	connection.Uptime = getUptime()
+
+	networkInfo := &NetworkInfo{
+		Gateway:        gateway,
+		DNSServers:     sources.dnsServers,
+		Connection:     connection,
+		ARPEntries:     sources.arp,
+		ServiceLatency: sources.services,
+		Interfaces:     interfaces,
+		Timestamp:      time.Now(),
+	}
+
+	if selected != nil {
+		networkInfo.IPv4Address = selected.IPv4Address
+		networkInfo.IPv6Address = selected.IPv6Address
+		networkInfo.SubnetMask = selected.SubnetMask
+		networkInfo.SSID = selected.SSID
+		networkInfo.EthernetInfo = selected.EthernetInfo
+		networkInfo.DHCPInfo = selected.DHCPInfo
+		networkInfo.VLANInfo = selected.VLANInfo
+		networkInfo.Traffic = selected.Traffic
+		networkInfo.Connection.SignalStrength = selected.SignalStrength
+
+		if selected.IPv4Address != "" || selected.IPv6Address != "" {
+			networkInfo.Connection.Status = "connected"
 		}
-		io.Copy(io.Discard, resp.Body)
-		resp.Body.Close()
-		return float64(time.Since(start).Milliseconds())
 	}
 
-	return 0
+	return networkInfo, nil
 }
 
-// GetNetworkInfo retrieves the current network information
-func GetNetworkInfo() (*NetworkInfo, error) {
-	iface, counter, err := findPrimaryInterface()
+// GetARPTable retrieves the current neighbour table over rtnetlink, falling back
+// to parsing 'ip neigh show' when netlink is unavailable
+func GetARPTable() ([]ARPEntry, error) {
+	neighbors, err := listNeighbors()
 	if err != nil {
-		return nil, err
+		return getARPTableFromIPCommand()
 	}
 
-	ipv4, ipv6, subnet := extractIPInfo(iface)
-
-	gateway := getDefaultGateway()
-	dnsServers := getDNSServers()
-	dhcpServer := getDHCPServer(gateway)
-	uptime := getUptime()
+	names := interfaceNames()
 
-	latencyMS, packetLoss := getConnectionMetrics(gateway)
-
-	traffic := Traffic{}
-	if counter != nil {
-		traffic = Traffic{
-			BytesReceived:    int64(counter.BytesRecv),
-			BytesSent:        int64(counter.BytesSent),
-			PacketsReceived:  int64(counter.PacketsRecv),
-			PacketsSent:      int64(counter.PacketsSent),
-			CurrentBandwidth: calculateBandwidth(*counter),
+	var entries []ARPEntry
+	for _, n := range neighbors {
+		// Skip unresolved entries and the NOARP entries 'ip neigh show' hides by default
+		if len(n.HardwareAddr) == 0 || n.State&nudNoARP != 0 {
+			continue
 		}
-	}
 
-	status := "disconnected"
-	if ipv4 != "" || ipv6 != "" {
-		status = "connected"
+		entries = append(entries, ARPEntry{
+			IPAddress:  n.IP.String(),
+			MACAddress: n.HardwareAddr.String(),
+			Device:     names[n.Index],
+			State:      neighborStateName(n.State),
+		})
 	}
 
-	networkInfo := &NetworkInfo{
-		IPv4Address: ipv4,
-		IPv6Address: ipv6,
-		SubnetMask:  subnet,
-		Gateway:     gateway,
-		DNSServers:  dnsServers,
-		DHCPInfo: DHCPInfo{
-			Enabled:    true,
-			DHCPServer: dhcpServer,
-		},
-		Connection: Connection{
-			Status:     status,
-			Uptime:     uptime,
-			LatencyMS:  latencyMS,
-			PacketLoss: packetLoss,
-		},
-		Traffic:   traffic,
-		Timestamp: time.Now(),
-	}
-
-	if iface != nil {
-		networkInfo.EthernetInfo = EthernetInfo{
-			InterfaceName: iface.Name,
-			MACAddress:    iface.HardwareAddr.String(),
-			Speed:         "1 Gbps",
-			Duplex:        "Full",
-		}
-
-		if isWireless(iface.Name) {
-			networkInfo.SSID = getWirelessSSID(iface.Name)
-			networkInfo.Connection.SignalStrength = getSignalStrength(iface.Name)
-		}
-
-		if strings.Contains(iface.Name, ".") {
-			vlanComponent := iface.Name[strings.LastIndex(iface.Name, ".")+1:]
-			networkInfo.VLANInfo = VLANInfo{
-				Enabled: true,
-				VLANID:  getVLANID(iface.Name),
-				Name:    "VLAN " + vlanComponent,
-			}
-		} else {
-			networkInfo.VLANInfo = VLANInfo{Enabled: false}
-		}
-	}
+	return entries, nil
+}
 
-	if entries, err := GetARPTable(); err == nil {
-		networkInfo.ARPEntries = entries
+// interfaceNames maps interface indexes to names
+func interfaceNames() map[int]string {
+	names := make(map[int]string)
+	if ifaces, err := net.Interfaces(); err == nil {
+		for _, iface := range ifaces {
+			names[iface.Index] = iface.Name
+		}
 	}
-
-	networkInfo.ServiceLatency = measureServiceLatencies()
-
-	return networkInfo, nil
+	return names
 }
 
-// GetARPTable retrieves the current ARP table using the modern 'ip neigh show' command
-// instead of the legacy 'arp -a' command
-func GetARPTable() ([]ARPEntry, error) {
-	// Use the modern 'ip neigh show' command
+func getARPTableFromIPCommand() ([]ARPEntry, error) {
 	cmd := exec.Command("ip", "neigh", "show")
 	var out bytes.Buffer
 	cmd.Stdout = &out
@@ -434,46 +671,163 @@ func GetARPTable() ([]ARPEntry, error) {
 
 // Helper functions to retrieve network information
 func getDefaultGateway() string {
+	for _, route := range ipv4DefaultRoutes() {
+		if route.Gateway != "" {
+			return route.Gateway
+		}
+	}
+	return "N/A"
+}
+
+// getDefaultRoutes returns the default routes ordered by metric, IPv4 first
+func getDefaultRoutes() []defaultRoute {
+	return append(ipv4DefaultRoutes(), ipv6DefaultRoutes()...)
+}
+
+func ipv4DefaultRoutes() []defaultRoute {
+	if routes, err := netlinkDefaultRoutes(syscall.AF_INET); err == nil {
+		return sortRoutesByMetric(routes)
+	}
+	return sortRoutesByMetric(parseIPv4DefaultRoutes())
+}
+
+func ipv6DefaultRoutes() []defaultRoute {
+	if routes, err := netlinkDefaultRoutes(syscall.AF_INET6); err == nil {
+		return sortRoutesByMetric(routes)
+	}
+	return sortRoutesByMetric(parseIPv6DefaultRoutes())
+}
+
+// netlinkDefaultRoutes returns the default routes of a family from rtnetlink
+func netlinkDefaultRoutes(family int) ([]defaultRoute, error) {
+	routes, err := listRoutes(family)
+	if err != nil {
+		return nil, err
+	}
+
+	names := interfaceNames()
+
+	var defaults []defaultRoute
+	for _, r := range routes {
+		name := names[r.OutIndex]
+		if r.DstLen != 0 || name == "" || name == "lo" {
+			continue
+		}
+
+		route := defaultRoute{Iface: name, Metric: r.Priority}
+		if r.Gateway != nil && !r.Gateway.IsUnspecified() {
+			route.Gateway = r.Gateway.String()
+		}
+		defaults = append(defaults, route)
+	}
+
+	return defaults, nil
+}
+
+func sortRoutesByMetric(routes []defaultRoute) []defaultRoute {
+	sort.SliceStable(routes, func(i, j int) bool {
+		return routes[i].Metric < routes[j].Metric
+	})
+	return routes
+}
+
+func parseIPv4DefaultRoutes() []defaultRoute {
 	data, err := os.ReadFile("/proc/net/route")
 	if err != nil {
-		return "N/A"
+		return nil
 	}
 
 	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
 	if len(lines) <= 1 {
-		return "N/A"
+		return nil
 	}
 
+	var routes []defaultRoute
 	for _, line := range lines[1:] {
 		fields := strings.Fields(line)
-		if len(fields) < 3 {
+		if len(fields) < 8 {
 			continue
 		}
 
-		// Destination column equals 0 for default route
-		if fields[1] != "00000000" {
+		// Destination and mask columns equal 0 for the default route
+		if fields[1] != "00000000" || fields[7] != "00000000" {
 			continue
 		}
 
+		// Skip routes that are not up (RTF_UP)
+		flags, err := strconv.ParseUint(fields[3], 16, 32)
+		if err != nil || flags&0x1 == 0 {
+			continue
+		}
+
+		metric, _ := strconv.Atoi(fields[6])
+		route := defaultRoute{Iface: fields[0], Metric: metric}
+
 		value, err := strconv.ParseUint(fields[2], 16, 32)
-		if err != nil {
+		if err == nil {
+			b := make([]byte, 4)
+			binary.LittleEndian.PutUint32(b, uint32(value))
+			ip := net.IP(b)
+			if !ip.Equal(net.IPv4zero) {
+				route.Gateway = ip.String()
+			}
+		}
+
+		routes = append(routes, route)
+	}
+
+	return routes
+}
+
+func parseIPv6DefaultRoutes() []defaultRoute {
+	data, err := os.ReadFile("/proc/net/ipv6_route")
+	if err != nil {
+		return nil
+	}
+
+	var routes []defaultRoute
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		// Format: dest dest_len src src_len next_hop metric refcnt use flags iface
+		fields := strings.Fields(line)
+		if len(fields) < 10 {
 			continue
 		}
 
-		b := make([]byte, 4)
-		binary.LittleEndian.PutUint32(b, uint32(value))
-		ip := net.IP(b)
-		if ip.Equal(net.IPv4zero) {
+		if fields[0] != strings.Repeat("0", 32) || fields[1] != "00" || fields[9] == "lo" {
 			continue
 		}
 
-		return ip.String()
+		metric, _ := strconv.ParseInt(fields[5], 16, 64)
+		route := defaultRoute{Iface: fields[9], Metric: int(metric)}
+
+		if hop, err := hexToIP(fields[4]); err == nil && !hop.IsUnspecified() {
+			route.Gateway = hop.String()
+		}
+
+		routes = append(routes, route)
 	}
 
-	return "N/A"
+	return routes
 }
 
-func getDNSServers() []string {
+func hexToIP(s string) (net.IP, error) {
+	if len(s) != 32 {
+		return nil, fmt.Errorf("invalid IPv6 hex address: %s", s)
+	}
+	ip := make(net.IP, net.IPv6len)
+	for i := 0; i < net.IPv6len; i++ {
+		b, err := strconv.ParseUint(s[i*2:i*2+2], 16, 8)
+		if err != nil {
+			return nil, err
+		}
+		ip[i] = byte(b)
+	}
+	return ip, nil
+}
+
+// GetDNSServers returns the nameservers of /etc/resolv.conf, or "N/A" when
+// there are none
+func GetDNSServers() []string {
 	data, err := os.ReadFile("/etc/resolv.conf")
 	if err != nil {
 		return []string{"N/A"}
@@ -497,13 +851,6 @@ func getDNSServers() []string {
 	return servers
 }
 
-func getDHCPServer(defaultGateway string) string {
-	if defaultGateway != "" && defaultGateway != "N/A" {
-		return defaultGateway
-	}
-	return "N/A"
-}
-
 func getUptime() int64 {
 	data, err := os.ReadFile("/proc/uptime")
 	if err != nil {
@@ -523,38 +870,50 @@ func getUptime() int64 {
 	return int64(uptimeFloat)
 }
 
-func isWireless(ifaceName string) bool {
-	// Check if interface is wireless by checking if it appears in iwconfig output
-	cmd := exec.Command("iwconfig", ifaceName)
-	var out bytes.Buffer
-	cmd.Stdout = &out
-	cmd.Stderr = &out
-	err := cmd.Run()
-	if err == nil && !strings.Contains(out.String(), "no wireless extensions") {
-		return true
+// getWirelessInfo reports whether an interface is wireless along with its SSID
+// and signal strength in dBm. nl80211 is used when available, with sysfs and a
+// single iwconfig run as the fallback.
+func getWirelessInfo(iface net.Interface) (bool, string, int) {
+	status, wireless, err := getWirelessStatus(iface.Index)
+	if err == nil {
+		return wireless, status.SSID, status.Signal
 	}
 
-	// Fallback to naming convention if iwconfig is not available
-	return strings.HasPrefix(ifaceName, "wlan") || strings.HasPrefix(ifaceName, "wlp")
+	if !isWireless(iface.Name) {
+		return false, "", 0
+	}
+
+	ssid, signal := readIwconfig(iface.Name)
+	return true, ssid, signal
 }
 
-func getWirelessSSID(ifaceName string) string {
-	if !isWireless(ifaceName) {
-		return ""
+func isWireless(ifaceName string) bool {
+	// cfg80211 devices expose a phy80211 link, wireless extensions a wireless directory
+	for _, entry := range []string{"phy80211", "wireless"} {
+		if _, err := os.Stat("/sys/class/net/" + ifaceName + "/" + entry); err == nil {
+			return true
+		}
 	}
 
-	// Try using iwconfig
+	// Fallback to naming convention if sysfs is not available
+	return strings.HasPrefix(ifaceName, "wlan") || strings.HasPrefix(ifaceName, "wlp")
+}
+
+// readIwconfig runs iwconfig once and extracts the SSID and signal level
+func readIwconfig(ifaceName string) (string, int) {
 	cmd := exec.Command("iwconfig", ifaceName)
 	var out bytes.Buffer
 	cmd.Stdout = &out
-	err := cmd.Run()
-	if err != nil {
-		return ""
+	if err := cmd.Run(); err != nil {
+		return "", 0
 	}
 
-	// Parse the SSID from the output
 	output := out.String()
-	essidIndex := strings.Index(output, "ESSID:")
+	return parseIwconfigSSID(output), parseIwconfigSignal(output)
+}
+
+func parseIwconfigSSID(output string) string {
+	essidIndex := strings.Index(output, "ESSID:\"")
 	if essidIndex == -1 {
 		return ""
 	}
@@ -569,22 +928,7 @@ func getWirelessSSID(ifaceName string) string {
 	return essidPart[:endQuoteIndex]
 }
 
-func getSignalStrength(ifaceName string) int {
-	if !isWireless(ifaceName) {
-		return 0
-	}
-
-	// Try using iwconfig to get signal strength
-	cmd := exec.Command("iwconfig", ifaceName)
-	var out bytes.Buffer
-	cmd.Stdout = &out
-	err := cmd.Run()
-	if err != nil {
-		return 0
-	}
-
-	// Parse the signal level from the output
-	output := out.String()
+func parseIwconfigSignal(output string) int {
 	signalIndex := strings.Index(output, "Signal level=")
 	if signalIndex == -1 {
 		return 0
@@ -617,13 +961,10 @@ func getVLANID(ifaceName string) int {
 		}
 	}
 
-	// Try reading from sysfs
-	cmd := exec.Command("cat", "/proc/net/vlan/"+ifaceName)
-	var out bytes.Buffer
-	cmd.Stdout = &out
-	err := cmd.Run()
+	// Try reading from procfs
+	data, err := os.ReadFile("/proc/net/vlan/" + ifaceName)
 	if err == nil {
-		output := out.String()
+		output := string(data)
 		vlanIDIndex := strings.Index(output, "VID: ")
 		if vlanIDIndex != -1 {
 			vlanStr := strings.TrimSpace(output[vlanIDIndex+5:])
@@ -641,53 +982,6 @@ func getVLANID(ifaceName string) int {
 	return 0
 }
 
-// Stores the last measured network counter values for bandwidth calculation
-var (
-	bandwidthMu         sync.Mutex
-	lastMeasurementTime time.Time
-	lastBytesRecv       uint64
-	lastBytesSent       uint64
-	currentBandwidth    float64
-)
-
-func calculateBandwidth(counter psnet.IOCountersStat) float64 {
-	bandwidthMu.Lock()
-	defer bandwidthMu.Unlock()
-
-	now := time.Now()
-
-	// Initialize on first call
-	if lastMeasurementTime.IsZero() {
-		lastMeasurementTime = now
-		lastBytesRecv = counter.BytesRecv
-		lastBytesSent = counter.BytesSent
-		return 0 // No history for calculation yet
-	}
-
-	// Calculate time difference in seconds
-	timeDiffSecs := now.Sub(lastMeasurementTime).Seconds()
-
-	// Avoid division by zero or negative time
-	if timeDiffSecs <= 0 {
-		return currentBandwidth // Return last known bandwidth
-	}
-
-	// Calculate bytes transferred since last measurement
-	bytesDiff := (counter.BytesRecv - lastBytesRecv) + (counter.BytesSent - lastBytesSent)
-
-	// Calculate bandwidth in Megabits per second (1 Byte = 8 bits)
-	// bytes/second * 8 / 1024 / 1024 = Mbps
-	bandwidth := float64(bytesDiff) * 8 / 1024 / 1024 / timeDiffSecs
-
-	// Update last values for next calculation
-	lastMeasurementTime = now
-	lastBytesRecv = counter.BytesRecv
-	lastBytesSent = counter.BytesSent
-	currentBandwidth = bandwidth
-
-	return bandwidth
-}
-
 func cidrToSubnet(ones int) string {
 	if ones < 0 || ones > 32 {
 		return "255.255.255.0"
@@ -0,0 +1,123 @@
+package core
+
+import (
+	"encoding/binary"
+	"errors"
+	"sync"
+	"syscall"
+)
+
+// Generic netlink and nl80211 constants
+const (
+	genlIDCtrl             = 0x10
+	genlHdrLen             = 4
+	ctrlCmdGetFamily       = 3
+	ctrlAttrFamilyID       = 1
+	ctrlAttrFamilyName     = 2
+	nl80211CmdGetInterface = 5
+	nl80211CmdGetStation   = 17
+	nl80211AttrIfindex     = 3
+	nl80211AttrStaInfo     = 21
+	nl80211AttrSSID        = 52
+	nl80211StaInfoSignal   = 7
+)
+
+var (
+	nl80211FamilyMu sync.Mutex
+	nl80211FamilyID uint16
+)
+
+// resolveNL80211Family looks up (and caches) the generic netlink family ID of nl80211
+func resolveNL80211Family(conn *netlinkConn) (uint16, error) {
+	nl80211FamilyMu.Lock()
+	defer nl80211FamilyMu.Unlock()
+
+	if nl80211FamilyID != 0 {
+		return nl80211FamilyID, nil
+	}
+
+	body := genlHeader(ctrlCmdGetFamily, 1)
+	body = appendNetlinkAttr(body, ctrlAttrFamilyName, append([]byte("nl80211"), 0))
+
+	msgs, err := conn.request(genlIDCtrl, 0, body)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, m := range msgs {
+		if len(m.Data) < genlHdrLen {
+			continue
+		}
+		attrs := parseNetlinkAttrs(m.Data[genlHdrLen:])
+		if id, ok := attrs[ctrlAttrFamilyID]; ok && len(id) >= 2 {
+			nl80211FamilyID = binary.NativeEndian.Uint16(id)
+			return nl80211FamilyID, nil
+		}
+	}
+
+	return 0, errors.New("nl80211 family not available")
+}
+
+func genlHeader(cmd, version uint8) []byte {
+	return []byte{cmd, version, 0, 0}
+}
+
+// getWirelessStatus queries nl80211 for the SSID and signal of an interface.
+// The boolean result is false when the interface is not a wireless device.
+func getWirelessStatus(ifindex int) (wirelessStatus, bool, error) {
+	conn, err := dialNetlink(syscall.NETLINK_GENERIC)
+	if err != nil {
+		return wirelessStatus{}, false, err
+	}
+	defer conn.Close()
+
+	family, err := resolveNL80211Family(conn)
+	if err != nil {
+		return wirelessStatus{}, false, err
+	}
+
+	index := make([]byte, 4)
+	binary.NativeEndian.PutUint32(index, uint32(ifindex))
+
+	body := appendNetlinkAttr(genlHeader(nl80211CmdGetInterface, 0), nl80211AttrIfindex, index)
+	msgs, err := conn.request(family, 0, body)
+	if errors.Is(err, syscall.ENODEV) || errors.Is(err, syscall.EOPNOTSUPP) {
+		return wirelessStatus{}, false, nil
+	}
+	if err != nil {
+		return wirelessStatus{}, false, err
+	}
+
+	var status wirelessStatus
+	for _, m := range msgs {
+		if len(m.Data) < genlHdrLen {
+			continue
+		}
+		attrs := parseNetlinkAttrs(m.Data[genlHdrLen:])
+		if ssid, ok := attrs[nl80211AttrSSID]; ok {
+			status.SSID = string(ssid)
+		}
+	}
+
+	// The station dump of a managed interface holds exactly the associated AP
+	body = appendNetlinkAttr(genlHeader(nl80211CmdGetStation, 0), nl80211AttrIfindex, index)
+	msgs, err = conn.request(family, syscall.NLM_F_DUMP, body)
+	if err != nil {
+		return status, true, nil
+	}
+
+	for _, m := range msgs {
+		if len(m.Data) < genlHdrLen {
+			continue
+		}
+		attrs := parseNetlinkAttrs(m.Data[genlHdrLen:])
+		info := parseNetlinkAttrs(attrs[nl80211AttrStaInfo])
+		if signal, ok := info[nl80211StaInfoSignal]; ok && len(signal) >= 1 {
+			status.Signal = int(int8(signal[0]))
+			status.HasSignal = true
+			break
+		}
+	}
+
+	return status, true, nil
+}
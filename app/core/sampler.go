@@ -0,0 +1,275 @@
+package core
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// SamplerConfig sets how often each data source of the dashboard is sampled
+type SamplerConfig struct {
+	CounterInterval    time.Duration // traffic counters and rates
+	InterfaceInterval  time.Duration // addresses, link, wireless and DHCP details, gateway and DNS servers
+	ConnectionInterval time.Duration // ICMP connection health
+	ARPInterval        time.Duration // neighbour table
+	ServiceInterval    time.Duration // service latency probes
+}
+
+// DefaultSamplerConfig returns the intervals used by the dashboard
+func DefaultSamplerConfig() SamplerConfig {
+	return SamplerConfig{
+		CounterInterval:    time.Second,
+		InterfaceInterval:  5 * time.Second,
+		ConnectionInterval: 5 * time.Second,
+		ARPInterval:        10 * time.Second,
+		ServiceInterval:    30 * time.Second,
+	}
+}
+
+// Sampler collects network information in the background, each data source
+// on its own interval, and keeps the latest values so that any number of
+// readers can build a report without triggering probes of their own
+type Sampler struct {
+	config SamplerConfig
+
+	mu          sync.RWMutex
+	interfaces  []InterfaceInfo
+	gateway     string
+	dnsServers  []string
+	connections map[string]Connection // by gateway, "N/A" without one
+	arp         []ARPEntry
+	services    []ServiceLatency
+	sampledAt   map[string]time.Time // by data source
+
+	stop    chan struct{}
+	wg      sync.WaitGroup
+	started bool
+}
+
+// NewSampler creates a sampler; zero intervals in config take their defaults
+func NewSampler(config SamplerConfig) *Sampler {
+	defaults := DefaultSamplerConfig()
+	for _, interval := range []struct {
+		value    *time.Duration
+		fallback time.Duration
+	}{
+		{&config.CounterInterval, defaults.CounterInterval},
+		{&config.InterfaceInterval, defaults.InterfaceInterval},
+		{&config.ConnectionInterval, defaults.ConnectionInterval},
+		{&config.ARPInterval, defaults.ARPInterval},
+		{&config.ServiceInterval, defaults.ServiceInterval},
+	} {
+		if *interval.value <= 0 {
+			*interval.value = interval.fallback
+		}
+	}
+
+	return &Sampler{
+		config:      config,
+		gateway:     "N/A",
+		connections: make(map[string]Connection),
+		sampledAt:   make(map[string]time.Time),
+		stop:        make(chan struct{}),
+	}
+}
+
+// Start samples the interfaces once, so that the first snapshot is complete
+// enough to render, and then runs every data source in the background
+func (s *Sampler) Start() {
+	s.mu.Lock()
+	if s.started {
+		s.mu.Unlock()
+		return
+	}
+	s.started = true
+	s.mu.Unlock()
+
+	s.sampleInterfaces()
+
+	s.every(s.config.CounterInterval, s.sampleCounters, false)
+	s.every(s.config.InterfaceInterval, s.sampleInterfaces, false)
+	s.every(s.config.ConnectionInterval, s.sampleConnections, true)
+	s.every(s.config.ARPInterval, s.sampleARP, true)
+	s.every(s.config.ServiceInterval, s.sampleServices, true)
+}
+
+// Stop ends the background sampling and waits for running samples to finish.
+// A stopped sampler cannot be started again.
+func (s *Sampler) Stop() {
+	s.mu.Lock()
+	if !s.started {
+		s.mu.Unlock()
+		return
+	}
+	s.started = false
+	s.mu.Unlock()
+
+	close(s.stop)
+	s.wg.Wait()
+}
+
+// every runs sample on interval until the sampler stops, optionally once right away
+func (s *Sampler) every(interval time.Duration, sample func(), immediately bool) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		if immediately {
+			sample()
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				sample()
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+func (s *Sampler) sampleInterfaces() {
+	interfaces, err := GetAllInterfaces()
+	if err != nil {
+		fmt.Printf("Warning: failed to sample interfaces: %v\n", err)
+		return
+	}
+
+	gateway := getDefaultGateway()
+	dnsServers := GetDNSServers()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.interfaces = interfaces
+	s.gateway = gateway
+	s.dnsServers = dnsServers
+	s.sampledAt["interfaces"] = time.Now()
+}
+
+// sampleCounters refreshes only the traffic section of the known interfaces
+func (s *Sampler) sampleCounters() {
+	states, err := gatherLinks()
+	if err != nil {
+		fmt.Printf("Warning: failed to sample traffic counters: %v\n", err)
+		return
+	}
+
+	now := time.Now()
+	traffic := make(map[string]Traffic, len(states))
+	for _, state := range states {
+		if state.counter != nil {
+			traffic[state.iface.Name] = trafficFromCounter(defaultRateTracker, state.iface.Name, *state.counter, now)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Readers may hold the previous slice, so update a copy
+	interfaces := make([]InterfaceInfo, len(s.interfaces))
+	copy(interfaces, s.interfaces)
+	for i := range interfaces {
+		if t, ok := traffic[interfaces[i].Name]; ok {
+			interfaces[i].Traffic = t
+		}
+	}
+
+	s.interfaces = interfaces
+	s.sampledAt["counters"] = now
+}
+
+// sampleConnections probes the default gateway and the gateway of every
+// interface, so that per-interface reports have connection metrics as well
+func (s *Sampler) sampleConnections() {
+	s.mu.RLock()
+	gateways := []string{s.gateway}
+	for _, iface := range s.interfaces {
+		if iface.Gateway != "" {
+			gateways = append(gateways, iface.Gateway)
+		}
+	}
+	s.mu.RUnlock()
+
+	connections := make(map[string]Connection, len(gateways))
+	for _, gateway := range gateways {
+		if _, done := connections[gateway]; !done {
+			connections[gateway] = getConnectionMetrics(gateway)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.connections = connections
+	s.sampledAt["connection"] = time.Now()
+}
+
+func (s *Sampler) sampleARP() {
+	entries, err := GetARPTable()
+	if err != nil {
+		fmt.Printf("Warning: failed to sample ARP table: %v\n", err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.arp = entries
+	s.sampledAt["arp"] = time.Now()
+}
+
+func (s *Sampler) sampleServices() {
+	services := measureServiceLatencies()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.services = services
+	s.sampledAt["services"] = time.Now()
+}
+
+// Snapshot builds a report from the latest samples for the named interface,
+// or for the primary interface when name is empty. It never probes the network.
+func (s *Sampler) Snapshot(name string) (*NetworkInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return assembleNetworkInfo(name, networkSources{
+		interfaces: append([]InterfaceInfo(nil), s.interfaces...),
+		gateway:    s.gateway,
+		dnsServers: s.dnsServers,
+		arp:        s.arp,
+		services:   s.services,
+		connection: func(gateway string) Connection {
+			if connection, ok := s.connections[gateway]; ok {
+				return connection
+			}
+			return Connection{}
+		},
+	})
+}
+
+// Interfaces returns the latest sample of every interface
+func (s *Sampler) Interfaces() []InterfaceInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]InterfaceInfo(nil), s.interfaces...)
+}
+
+// SampledAt returns when each data source was last sampled successfully
+func (s *Sampler) SampledAt() map[string]time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sampledAt := make(map[string]time.Time, len(s.sampledAt))
+	for source, at := range s.sampledAt {
+		sampledAt[source] = at
+	}
+	return sampledAt
+}
@@ -0,0 +1,390 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ProbeType identifies how a service probe measures latency
+type ProbeType string
+
+const (
+	// ProbeTCP measures the time to establish a TCP connection to Host:Port
+	ProbeTCP ProbeType = "tcp"
+	// ProbeDNS measures the time to resolve Host, optionally through Resolver
+	ProbeDNS ProbeType = "dns"
+	// ProbeHTTP measures the time of a HEAD request to URL
+	ProbeHTTP ProbeType = "http"
+)
+
+// Default timeouts per probe type, used when a probe does not set TimeoutMS
+const (
+	defaultTCPProbeTimeout  = 750 * time.Millisecond
+	defaultDNSProbeTimeout  = 2 * time.Second
+	defaultHTTPProbeTimeout = 3 * time.Second
+)
+
+// DefaultServiceProbeConfigPath is where the probe targets are stored unless
+// another path is passed to LoadServiceProbes
+const DefaultServiceProbeConfigPath = "app/core/service_probes.json"
+
+// ErrInvalidProbe is returned when a probe definition is incomplete or malformed
+var ErrInvalidProbe = errors.New("invalid service probe")
+
+// ErrProbeNotFound is returned when a named probe does not exist
+var ErrProbeNotFound = errors.New("service probe not found")
+
+// ServiceProbe describes a named service-latency probe target
+type ServiceProbe struct {
+	Name           string    `json:"name"`
+	Type           ProbeType `json:"type"`
+	Host           string    `json:"host,omitempty"`           // tcp: host to dial, dns: name to resolve
+	Port           int       `json:"port,omitempty"`           // tcp only
+	Resolver       string    `json:"resolver,omitempty"`       // dns only, "ip[:port]"; empty uses the system resolver
+	URL            string    `json:"url,omitempty"`            // http only
+	ExpectedStatus int       `json:"expectedStatus,omitempty"` // http only, 0 accepts any status below 400
+	TimeoutMS      int       `json:"timeoutMs,omitempty"`
+}
+
+// ServiceLatency represents the outcome of a single service probe
+type ServiceLatency struct {
+	Name       string    `json:"name"`
+	Type       ProbeType `json:"type"`
+	Target     string    `json:"target"`
+	LatencyMS  float64   `json:"latencyMs"` // 0 when the probe failed
+	Success    bool      `json:"success"`
+	StatusCode int       `json:"statusCode,omitempty"` // http only
+	Error      string    `json:"error,omitempty"`
+}
+
+// defaultServiceProbes mirrors the targets NetTool has always probed
+var defaultServiceProbes = []ServiceProbe{
+	{Name: "Google", Type: ProbeTCP, Host: "google.com", Port: 443},
+	{Name: "Amazon", Type: ProbeTCP, Host: "amazon.com", Port: 443},
+	{Name: "Cloudflare", Type: ProbeTCP, Host: "cloudflare.com", Port: 443},
+	{Name: "Microsoft", Type: ProbeTCP, Host: "microsoft.com", Port: 443},
+	{Name: "DNS", Type: ProbeDNS, Host: "www.google.com"},
+	{Name: "HTTPS", Type: ProbeHTTP, URL: "https://www.google.com"},
+}
+
+// The configured probe targets and the file they are persisted to
+var (
+	serviceProbesMu   sync.RWMutex
+	serviceProbesPath = DefaultServiceProbeConfigPath
+	serviceProbes     = append([]ServiceProbe(nil), defaultServiceProbes...)
+)
+
+// LoadServiceProbes loads the probe targets from the given file, writing the
+// defaults there when it does not exist yet. An empty path selects
+// DefaultServiceProbeConfigPath.
+func LoadServiceProbes(path string) error {
+	if path == "" {
+		path = DefaultServiceProbeConfigPath
+	}
+
+	serviceProbesMu.Lock()
+	defer serviceProbesMu.Unlock()
+
+	serviceProbesPath = path
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		serviceProbes = append([]ServiceProbe(nil), defaultServiceProbes...)
+		return saveServiceProbesLocked()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read service probe config: %v", err)
+	}
+
+	var probes []ServiceProbe
+	if err := json.Unmarshal(data, &probes); err != nil {
+		return fmt.Errorf("failed to parse service probe config: %v", err)
+	}
+	if err := validateServiceProbes(probes); err != nil {
+		return err
+	}
+
+	serviceProbes = probes
+	return nil
+}
+
+// GetServiceProbes returns a copy of the configured probe targets
+func GetServiceProbes() []ServiceProbe {
+	serviceProbesMu.RLock()
+	defer serviceProbesMu.RUnlock()
+
+	return append(make([]ServiceProbe, 0, len(serviceProbes)), serviceProbes...)
+}
+
+// SetServiceProbes replaces every probe target and persists the new list
+func SetServiceProbes(probes []ServiceProbe) error {
+	if err := validateServiceProbes(probes); err != nil {
+		return err
+	}
+
+	serviceProbesMu.Lock()
+	defer serviceProbesMu.Unlock()
+
+	serviceProbes = append([]ServiceProbe(nil), probes...)
+	return saveServiceProbesLocked()
+}
+
+// AddServiceProbe adds a probe target, replacing an existing one of the same name
+func AddServiceProbe(probe ServiceProbe) error {
+	if err := validateServiceProbe(probe); err != nil {
+		return err
+	}
+
+	serviceProbesMu.Lock()
+	defer serviceProbesMu.Unlock()
+
+	for i, p := range serviceProbes {
+		if p.Name == probe.Name {
+			serviceProbes[i] = probe
+			return saveServiceProbesLocked()
+		}
+	}
+
+	serviceProbes = append(serviceProbes, probe)
+	return saveServiceProbesLocked()
+}
+
+// RemoveServiceProbe removes the named probe target
+func RemoveServiceProbe(name string) error {
+	serviceProbesMu.Lock()
+	defer serviceProbesMu.Unlock()
+
+	for i, p := range serviceProbes {
+		if p.Name == name {
+			serviceProbes = append(serviceProbes[:i], serviceProbes[i+1:]...)
+			return saveServiceProbesLocked()
+		}
+	}
+
+	return fmt.Errorf("%w: %s", ErrProbeNotFound, name)
+}
+
+func saveServiceProbesLocked() error {
+	data, err := json.MarshalIndent(serviceProbes, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal service probe config: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(serviceProbesPath), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %v", err)
+	}
+
+	if err := os.WriteFile(serviceProbesPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write service probe config: %v", err)
+	}
+
+	return nil
+}
+
+func validateServiceProbes(probes []ServiceProbe) error {
+	names := make(map[string]bool, len(probes))
+	for _, probe := range probes {
+		if err := validateServiceProbe(probe); err != nil {
+			return err
+		}
+		if names[probe.Name] {
+			return fmt.Errorf("%w: duplicate name %q", ErrInvalidProbe, probe.Name)
+		}
+		names[probe.Name] = true
+	}
+	return nil
+}
+
+func validateServiceProbe(probe ServiceProbe) error {
+	if probe.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidProbe)
+	}
+	if probe.TimeoutMS < 0 {
+		return fmt.Errorf("%w: %s: timeout must not be negative", ErrInvalidProbe, probe.Name)
+	}
+
+	switch probe.Type {
+	case ProbeTCP:
+		if probe.Host == "" {
+			return fmt.Errorf("%w: %s: host is required", ErrInvalidProbe, probe.Name)
+		}
+		if probe.Port < 1 || probe.Port > 65535 {
+			return fmt.Errorf("%w: %s: port must be between 1 and 65535", ErrInvalidProbe, probe.Name)
+		}
+	case ProbeDNS:
+		if probe.Host == "" {
+			return fmt.Errorf("%w: %s: host is required", ErrInvalidProbe, probe.Name)
+		}
+		if probe.Resolver != "" {
+			if _, err := resolverAddress(probe.Resolver); err != nil {
+				return fmt.Errorf("%w: %s: %v", ErrInvalidProbe, probe.Name, err)
+			}
+		}
+	case ProbeHTTP:
+		u, err := url.Parse(probe.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%w: %s: url must be an absolute http or https URL", ErrInvalidProbe, probe.Name)
+		}
+		if probe.ExpectedStatus != 0 && (probe.ExpectedStatus < 100 || probe.ExpectedStatus > 599) {
+			return fmt.Errorf("%w: %s: expected status must be a valid HTTP status code", ErrInvalidProbe, probe.Name)
+		}
+	default:
+		return fmt.Errorf("%w: %s: unknown type %q", ErrInvalidProbe, probe.Name, probe.Type)
+	}
+
+	return nil
+}
+
+// resolverAddress normalises a resolver to host:port, defaulting to port 53
+func resolverAddress(resolver string) (string, error) {
+	if ip := net.ParseIP(resolver); ip != nil {
+		return net.JoinHostPort(ip.String(), "53"), nil
+	}
+
+	host, port, err := net.SplitHostPort(resolver)
+	if err != nil || net.ParseIP(host) == nil {
+		return "", fmt.Errorf("resolver must be an IP address with an optional port")
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return "", fmt.Errorf("resolver port must be between 1 and 65535")
+	}
+
+	return resolver, nil
+}
+
+// measureServiceLatencies runs every configured probe concurrently and reports
+// the results in configuration order
+func measureServiceLatencies() []ServiceLatency {
+	probes := GetServiceProbes()
+	results := make([]ServiceLatency, len(probes))
+
+	var wg sync.WaitGroup
+	for i, probe := range probes {
+		wg.Add(1)
+		go func(i int, probe ServiceProbe) {
+			defer wg.Done()
+			results[i] = runServiceProbe(probe)
+		}(i, probe)
+	}
+	wg.Wait()
+
+	return results
+}
+
+func runServiceProbe(probe ServiceProbe) ServiceLatency {
+	result := ServiceLatency{Name: probe.Name, Type: probe.Type}
+
+	var latency time.Duration
+	var err error
+	switch probe.Type {
+	case ProbeTCP:
+		result.Target = net.JoinHostPort(probe.Host, strconv.Itoa(probe.Port))
+		latency, err = probeTCP(result.Target, probe.timeout(defaultTCPProbeTimeout))
+	case ProbeDNS:
+		result.Target = probe.Host
+		if probe.Resolver != "" {
+			result.Target += " @" + probe.Resolver
+		}
+		latency, err = probeDNS(probe.Host, probe.Resolver, probe.timeout(defaultDNSProbeTimeout))
+	case ProbeHTTP:
+		result.Target = probe.URL
+		latency, result.StatusCode, err = probeHTTP(probe.URL, probe.ExpectedStatus, probe.timeout(defaultHTTPProbeTimeout))
+	default:
+		err = fmt.Errorf("unknown probe type %q", probe.Type)
+	}
+
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Success = true
+	result.LatencyMS = float64(latency.Microseconds()) / 1000
+	return result
+}
+
+func (p ServiceProbe) timeout(fallback time.Duration) time.Duration {
+	if p.TimeoutMS > 0 {
+		return time.Duration(p.TimeoutMS) * time.Millisecond
+	}
+	return fallback
+}
+
+func probeTCP(address string, timeout time.Duration) (time.Duration, error) {
+	start := time.Now()
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return 0, err
+	}
+	latency := time.Since(start)
+	_ = conn.Close()
+	return latency, nil
+}
+
+// probeDNS resolves host through the given resolver, or the system resolver
+// when it is empty
+func probeDNS(host, resolver string, timeout time.Duration) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	r := net.DefaultResolver
+	if resolver != "" {
+		address, err := resolverAddress(resolver)
+		if err != nil {
+			return 0, err
+		}
+		r = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, address)
+			},
+		}
+	}
+
+	start := time.Now()
+	if _, err := r.LookupHost(ctx, host); err != nil {
+		return 0, err
+	}
+	return time.Since(start), nil
+}
+
+// probeHTTP sends a HEAD request without following redirects, so that an
+// expected 3xx status can be checked as well
+func probeHTTP(target string, expectedStatus int, timeout time.Duration) (time.Duration, int, error) {
+	client := &http.Client{
+		Timeout: timeout,
+		CheckRedirect: func(_ *http.Request, _ []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	start := time.Now()
+	resp, err := client.Head(target)
+	if err != nil {
+		return 0, 0, err
+	}
+	latency := time.Since(start)
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	if expectedStatus != 0 && resp.StatusCode != expectedStatus {
+		return 0, resp.StatusCode, fmt.Errorf("unexpected status %d, expected %d", resp.StatusCode, expectedStatus)
+	}
+	if expectedStatus == 0 && resp.StatusCode >= 400 {
+		return 0, resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return latency, resp.StatusCode, nil
+}
@@ -0,0 +1,176 @@
+package core
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	psnet "github.com/shirou/gopsutil/v3/net"
+)
+
+// minRateInterval is the shortest interval rates are computed over. Samples
+// taken sooner after the previous one, e.g. by a REST call racing the
+// broadcaster, report the last rates instead of moving the baseline.
+const minRateInterval = time.Second
+
+// trafficCounters holds the cumulative counters of one interface
+type trafficCounters struct {
+	bytesRecv   uint64
+	bytesSent   uint64
+	packetsRecv uint64
+	packetsSent uint64
+	errin       uint64
+	errout      uint64
+	dropin      uint64
+	dropout     uint64
+}
+
+func countersFromStat(counter psnet.IOCountersStat) trafficCounters {
+	return trafficCounters{
+		bytesRecv:   counter.BytesRecv,
+		bytesSent:   counter.BytesSent,
+		packetsRecv: counter.PacketsRecv,
+		packetsSent: counter.PacketsSent,
+		errin:       counter.Errin,
+		errout:      counter.Errout,
+		dropin:      counter.Dropin,
+		dropout:     counter.Dropout,
+	}
+}
+
+// TrafficRates holds the per-second rates of an interface. Bit rates are in
+// SI megabits, i.e. 10^6 bits per second.
+type TrafficRates struct {
+	RxMbps          float64 `json:"rxMbps"`
+	TxMbps          float64 `json:"txMbps"`
+	RxPacketsPerSec float64 `json:"rxPacketsPerSec"`
+	TxPacketsPerSec float64 `json:"txPacketsPerSec"`
+	RxErrorsPerSec  float64 `json:"rxErrorsPerSec"`
+	TxErrorsPerSec  float64 `json:"txErrorsPerSec"`
+	RxDropsPerSec   float64 `json:"rxDropsPerSec"`
+	TxDropsPerSec   float64 `json:"txDropsPerSec"`
+	Interval        float64 `json:"interval"`               // seconds the rates were measured over
+	CounterReset    bool    `json:"counterReset,omitempty"` // counters went backwards, the interval was discarded
+}
+
+// rateState is the baseline of one interface
+type rateState struct {
+	counters   trafficCounters
+	measuredAt time.Time
+	rates      TrafficRates
+}
+
+// RateTracker derives traffic rates from successive counter samples, keeping
+// an independent baseline per interface
+type RateTracker struct {
+	mu     sync.Mutex
+	states map[string]*rateState
+}
+
+// NewRateTracker creates an empty rate tracker. Callers that sample on their
+// own schedule should use their own tracker so their baselines do not
+// interfere with the dashboard's.
+func NewRateTracker() *RateTracker {
+	return &RateTracker{states: make(map[string]*rateState)}
+}
+
+// defaultRateTracker backs the Traffic section of the dashboard
+var defaultRateTracker = NewRateTracker()
+
+// Update records a counter sample of an interface and returns the rates since
+// the previous one. The first sample of an interface reports zero rates.
+func (t *RateTracker) Update(name string, counters trafficCounters, now time.Time) TrafficRates {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	last, ok := t.states[name]
+	if !ok {
+		t.states[name] = &rateState{counters: counters, measuredAt: now}
+		return TrafficRates{}
+	}
+
+	elapsed := now.Sub(last.measuredAt)
+	if elapsed < minRateInterval {
+		return last.rates
+	}
+
+	seconds := elapsed.Seconds()
+	rates := TrafficRates{Interval: seconds}
+
+	deltas := []struct {
+		prev, curr uint64
+		rate       *float64
+		scale      float64
+	}{
+		{last.counters.bytesRecv, counters.bytesRecv, &rates.RxMbps, 8 / 1e6},
+		{last.counters.bytesSent, counters.bytesSent, &rates.TxMbps, 8 / 1e6},
+		{last.counters.packetsRecv, counters.packetsRecv, &rates.RxPacketsPerSec, 1},
+		{last.counters.packetsSent, counters.packetsSent, &rates.TxPacketsPerSec, 1},
+		{last.counters.errin, counters.errin, &rates.RxErrorsPerSec, 1},
+		{last.counters.errout, counters.errout, &rates.TxErrorsPerSec, 1},
+		{last.counters.dropin, counters.dropin, &rates.RxDropsPerSec, 1},
+		{last.counters.dropout, counters.dropout, &rates.TxDropsPerSec, 1},
+	}
+
+	for _, d := range deltas {
+		delta, ok := counterDelta(d.prev, d.curr)
+		if !ok {
+			// The interface was reset or recreated; start over from this sample
+			rates = TrafficRates{Interval: seconds, CounterReset: true}
+			break
+		}
+		*d.rate = float64(delta) * d.scale / seconds
+	}
+
+	last.counters = counters
+	last.measuredAt = now
+	last.rates = rates
+
+	return rates
+}
+
+// Forget drops the baselines of interfaces not in names, e.g. after they were removed
+func (t *RateTracker) Forget(names map[string]bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for name := range t.states {
+		if !names[name] {
+			delete(t.states, name)
+		}
+	}
+}
+
+// trafficFromCounter builds the Traffic section of an interface from its
+// cumulative counters and the rates tracker derives from them
+func trafficFromCounter(tracker *RateTracker, name string, counter psnet.IOCountersStat, now time.Time) Traffic {
+	rates := tracker.Update(name, countersFromStat(counter), now)
+	return Traffic{
+		BytesReceived:    int64(counter.BytesRecv),
+		BytesSent:        int64(counter.BytesSent),
+		PacketsReceived:  int64(counter.PacketsRecv),
+		PacketsSent:      int64(counter.PacketsSent),
+		ErrorsReceived:   int64(counter.Errin),
+		ErrorsSent:       int64(counter.Errout),
+		DropsReceived:    int64(counter.Dropin),
+		DropsSent:        int64(counter.Dropout),
+		CurrentBandwidth: rates.RxMbps + rates.TxMbps,
+		TrafficRates:     rates,
+	}
+}
+
+// counterDelta returns how far a counter advanced. A counter that went
+// backwards from close to the 32-bit limit wrapped around, as counters of
+// drivers and kernels reporting 32-bit values do; any other decrease is a
+// reset and reported as not ok.
+func counterDelta(prev, curr uint64) (uint64, bool) {
+	if curr >= prev {
+		return curr - prev, true
+	}
+
+	if prev <= math.MaxUint32 && prev-curr > math.MaxUint32/2 {
+		return curr + (math.MaxUint32 - prev) + 1, true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,152 @@
+package core
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestCounterDelta(t *testing.T) {
+	tests := []struct {
+		name       string
+		prev, curr uint64
+		delta      uint64
+		ok         bool
+	}{
+		{name: "advanced", prev: 1000, curr: 1500, delta: 500, ok: true},
+		{name: "unchanged", prev: 1000, curr: 1000, delta: 0, ok: true},
+		{name: "32-bit wrap", prev: math.MaxUint32 - 99, curr: 400, delta: 500, ok: true},
+		{name: "32-bit wrap to zero", prev: math.MaxUint32, curr: 0, delta: 1, ok: true},
+		{name: "32-bit reset", prev: 5_000_000, curr: 1000, ok: false},
+		{name: "32-bit reset near the limit", prev: math.MaxUint32 - 99, curr: math.MaxUint32 / 2, ok: false},
+		{name: "64-bit reset", prev: 1 << 40, curr: 400, ok: false},
+		{name: "64-bit reset past the 32-bit limit", prev: 1 << 40, curr: 1 << 33, ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delta, ok := counterDelta(tt.prev, tt.curr)
+			if delta != tt.delta || ok != tt.ok {
+				t.Errorf("counterDelta(%d, %d) = %d, %v; want %d, %v", tt.prev, tt.curr, delta, ok, tt.delta, tt.ok)
+			}
+		})
+	}
+}
+
+func TestRateTrackerUpdate(t *testing.T) {
+	start := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	base := trafficCounters{bytesRecv: 1 << 40, bytesSent: 1 << 40, packetsRecv: 1000, packetsSent: 1000}
+
+	type sample struct {
+		iface    string
+		after    time.Duration // since start
+		counters trafficCounters
+		want     TrafficRates
+	}
+	tests := []struct {
+		name    string
+		samples []sample
+	}{
+		{
+			name: "first sample",
+			samples: []sample{
+				{iface: "eth0", counters: base},
+			},
+		},
+		{
+			// 125,000,000 bytes in 10 s are 100 SI megabits per second, not 95.37 Mibit/s
+			name: "SI megabits",
+			samples: []sample{
+				{iface: "eth0", counters: base},
+				{iface: "eth0", after: 10 * time.Second, counters: trafficCounters{
+					bytesRecv: base.bytesRecv + 125_000_000, bytesSent: base.bytesSent + 12_500_000,
+					packetsRecv: 2000, packetsSent: 1500, errin: 10, dropout: 5,
+				}, want: TrafficRates{
+					RxMbps: 100, TxMbps: 10, RxPacketsPerSec: 100, TxPacketsPerSec: 50,
+					RxErrorsPerSec: 1, TxDropsPerSec: 0.5, Interval: 10,
+				}},
+			},
+		},
+		{
+			name: "32-bit wrap",
+			samples: []sample{
+				{iface: "eth0", counters: trafficCounters{bytesRecv: math.MaxUint32 - 249_999, packetsRecv: math.MaxUint32}},
+				{iface: "eth0", after: 2 * time.Second, counters: trafficCounters{bytesRecv: 250_000, packetsRecv: 99}, want: TrafficRates{
+					RxMbps: 2, RxPacketsPerSec: 50, Interval: 2,
+				}},
+			},
+		},
+		{
+			name: "64-bit reset",
+			samples: []sample{
+				{iface: "eth0", counters: base},
+				{iface: "eth0", after: time.Second, counters: trafficCounters{bytesRecv: 1000, bytesSent: base.bytesSent + 1000},
+					want: TrafficRates{Interval: 1, CounterReset: true}},
+				// The reset sample is the new baseline
+				{iface: "eth0", after: 2 * time.Second, counters: trafficCounters{bytesRecv: 126_000, bytesSent: base.bytesSent + 1000},
+					want: TrafficRates{RxMbps: 1, Interval: 1}},
+			},
+		},
+		{
+			name: "per-interface baselines",
+			samples: []sample{
+				{iface: "eth0", counters: base},
+				{iface: "eth1", after: 500 * time.Millisecond, counters: trafficCounters{bytesRecv: 1000}},
+				{iface: "eth0", after: time.Second, counters: trafficCounters{bytesRecv: base.bytesRecv + 250_000, bytesSent: base.bytesSent, packetsRecv: 1000, packetsSent: 1000},
+					want: TrafficRates{RxMbps: 2, Interval: 1}},
+				// eth1 counts from its own first sample, not from eth0's
+				{iface: "eth1", after: 2500 * time.Millisecond, counters: trafficCounters{bytesRecv: 1_001_000},
+					want: TrafficRates{RxMbps: 4, Interval: 2}},
+			},
+		},
+		{
+			name: "samples too close together",
+			samples: []sample{
+				{iface: "eth0", counters: base},
+				{iface: "eth0", after: time.Second, counters: trafficCounters{bytesRecv: base.bytesRecv + 125_000, bytesSent: base.bytesSent, packetsRecv: 1000, packetsSent: 1000},
+					want: TrafficRates{RxMbps: 1, Interval: 1}},
+				// Reports the last rates and keeps the baseline
+				{iface: "eth0", after: 1500 * time.Millisecond, counters: trafficCounters{bytesRecv: base.bytesRecv + 1_125_000, bytesSent: base.bytesSent, packetsRecv: 1000, packetsSent: 1000},
+					want: TrafficRates{RxMbps: 1, Interval: 1}},
+				{iface: "eth0", after: 2 * time.Second, counters: trafficCounters{bytesRecv: base.bytesRecv + 250_000, bytesSent: base.bytesSent, packetsRecv: 1000, packetsSent: 1000},
+					want: TrafficRates{RxMbps: 1, Interval: 1}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := NewRateTracker()
+			for i, s := range tt.samples {
+				got := tracker.Update(s.iface, s.counters, start.Add(s.after))
+				if !ratesEqual(got, s.want) {
+					t.Errorf("sample %d of %s: rates %+v, want %+v", i, s.iface, got, s.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRateTrackerForget(t *testing.T) {
+	start := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	tracker := NewRateTracker()
+	tracker.Update("eth0", trafficCounters{bytesRecv: 1000}, start)
+	tracker.Update("eth1", trafficCounters{bytesRecv: 1000}, start)
+
+	tracker.Forget(map[string]bool{"eth1": true})
+
+	// eth0 starts over, eth1 keeps its baseline
+	if got := tracker.Update("eth0", trafficCounters{bytesRecv: 126_000}, start.Add(time.Second)); got != (TrafficRates{}) {
+		t.Errorf("forgotten interface reported %+v", got)
+	}
+	if got := tracker.Update("eth1", trafficCounters{bytesRecv: 126_000}, start.Add(time.Second)); !ratesEqual(got, TrafficRates{RxMbps: 1, Interval: 1}) {
+		t.Errorf("kept interface reported %+v", got)
+	}
+}
+
+func ratesEqual(a, b TrafficRates) bool {
+	near := func(x, y float64) bool { return math.Abs(x-y) < 1e-9 }
+	return near(a.RxMbps, b.RxMbps) && near(a.TxMbps, b.TxMbps) &&
+		near(a.RxPacketsPerSec, b.RxPacketsPerSec) && near(a.TxPacketsPerSec, b.TxPacketsPerSec) &&
+		near(a.RxErrorsPerSec, b.RxErrorsPerSec) && near(a.TxErrorsPerSec, b.TxErrorsPerSec) &&
+		near(a.RxDropsPerSec, b.RxDropsPerSec) && near(a.TxDropsPerSec, b.TxDropsPerSec) &&
+		near(a.Interval, b.Interval) && a.CounterReset == b.CounterReset
+}
@@ -0,0 +1,147 @@
+// Package history provides an embedded on-disk time-series store for dashboard telemetry.
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Duration is a time.Duration that is stored as a string such as "24h" in JSON
+type Duration time.Duration
+
+// MarshalJSON encodes the duration in time.Duration string form
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON accepts a duration string or a number of seconds
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		parsed, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		*d = Duration(parsed)
+		return nil
+	}
+
+	var seconds float64
+	if err := json.Unmarshal(data, &seconds); err != nil {
+		return fmt.Errorf("duration must be a string like \"24h\" or a number of seconds")
+	}
+	*d = Duration(seconds * float64(time.Second))
+	return nil
+}
+
+// Tier is one resolution level of the store. Samples are averaged into
+// buckets of Resolution and kept for Retention; a zero resolution keeps every
+// sample as recorded.
+type Tier struct {
+	Resolution Duration `json:"resolution"`
+	Retention  Duration `json:"retention"`
+}
+
+// name returns the directory name of the tier
+func (t Tier) name() string {
+	if t.Resolution == 0 {
+		return "raw"
+	}
+	return time.Duration(t.Resolution).String()
+}
+
+// Config holds the retention and downsampling settings of a store
+type Config struct {
+	Tiers         []Tier   `json:"tiers"`
+	FlushInterval Duration `json:"flushInterval"` // how often buffered samples are written to disk
+}
+
+// DefaultConfig keeps raw samples for six hours, minute averages for a week
+// and hourly averages for a year
+func DefaultConfig() Config {
+	return Config{
+		Tiers: []Tier{
+			{Resolution: 0, Retention: Duration(6 * time.Hour)},
+			{Resolution: Duration(time.Minute), Retention: Duration(7 * 24 * time.Hour)},
+			{Resolution: Duration(time.Hour), Retention: Duration(365 * 24 * time.Hour)},
+		},
+		FlushInterval: Duration(30 * time.Second),
+	}
+}
+
+// LoadConfig reads the store configuration from path, writing the defaults
+// there when it does not exist yet
+func LoadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		config := DefaultConfig()
+		if err := SaveConfig(path, config); err != nil {
+			return config, err
+		}
+		return config, nil
+	}
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read history config: %v", err)
+	}
+
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return Config{}, fmt.Errorf("failed to parse history config: %v", err)
+	}
+	if err := config.validate(); err != nil {
+		return Config{}, err
+	}
+
+	return config, nil
+}
+
+// SaveConfig writes the store configuration to path
+func SaveConfig(path string, config Config) error {
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal history config: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create history directory: %v", err)
+	}
+
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write history config: %v", err)
+	}
+
+	return nil
+}
+
+// validate checks the tiers and orders them from the finest resolution up
+func (c *Config) validate() error {
+	if len(c.Tiers) == 0 {
+		return fmt.Errorf("history config needs at least one tier")
+	}
+
+	sort.Slice(c.Tiers, func(i, j int) bool {
+		return c.Tiers[i].Resolution < c.Tiers[j].Resolution
+	})
+
+	for i, tier := range c.Tiers {
+		if tier.Resolution < 0 {
+			return fmt.Errorf("tier %s: resolution must not be negative", tier.name())
+		}
+		if tier.Retention <= 0 {
+			return fmt.Errorf("tier %s: retention must be positive", tier.name())
+		}
+		if i > 0 && tier.Resolution == c.Tiers[i-1].Resolution {
+			return fmt.Errorf("tier %s: duplicate resolution", tier.name())
+		}
+	}
+
+	if c.FlushInterval <= 0 {
+		c.FlushInterval = DefaultConfig().FlushInterval
+	}
+
+	return nil
+}
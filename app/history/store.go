@@ -0,0 +1,555 @@
+package history
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// segmentLayout names the per-day segment files, in UTC
+const segmentLayout = "20060102"
+
+// Errors returned for queries and samples the store does not accept
+var (
+	ErrInvalidMetric = errors.New("invalid metric name")
+	ErrInvalidRange  = errors.New("invalid time range")
+)
+
+// Point is an aggregate of the samples recorded within one bucket
+type Point struct {
+	Time  time.Time `json:"t"` // start of the bucket
+	Avg   float64   `json:"avg"`
+	Min   float64   `json:"min"`
+	Max   float64   `json:"max"`
+	Count int       `json:"count"`
+}
+
+// bucket accumulates samples until it is written out
+type bucket struct {
+	start time.Time
+	min   float64
+	max   float64
+	sum   float64
+	count int
+}
+
+func (b *bucket) add(min, max, sum float64, count int) {
+	if b.count == 0 {
+		b.min, b.max = min, max
+	} else {
+		b.min = math.Min(b.min, min)
+		b.max = math.Max(b.max, max)
+	}
+	b.sum += sum
+	b.count += count
+}
+
+func (b *bucket) point() Point {
+	return Point{Time: b.start, Avg: b.sum / float64(b.count), Min: b.min, Max: b.max, Count: b.count}
+}
+
+// segmentWriter appends to the segment file of one tier and metric
+type segmentWriter struct {
+	day  string
+	file *os.File
+	buf  *bufio.Writer
+}
+
+// Store is an append-only time-series store with one directory per tier and
+// metric and one file per UTC day. Samples are written to every tier, the
+// coarser ones averaging them into buckets, and segments older than a tier's
+// retention are removed.
+type Store struct {
+	dir    string
+	config Config
+
+	mu      sync.Mutex
+	open    map[string]*segmentWriter // by tier/metric
+	pending map[string]*bucket        // partial buckets by tier/metric
+	closed  bool
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// Open opens or creates the store in dir, reading its configuration from
+// config.json in that directory
+func Open(dir string) (*Store, error) {
+	config, err := LoadConfig(filepath.Join(dir, "config.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Store{
+		dir:     dir,
+		config:  config,
+		open:    make(map[string]*segmentWriter),
+		pending: make(map[string]*bucket),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	s.Prune(time.Now())
+	go s.maintain()
+
+	return s, nil
+}
+
+// Config returns the configuration the store was opened with
+func (s *Store) Config() Config {
+	return s.config
+}
+
+// maintain flushes buffered samples and prunes expired segments in the background
+func (s *Store) maintain() {
+	defer close(s.done)
+
+	flush := time.NewTicker(time.Duration(s.config.FlushInterval))
+	defer flush.Stop()
+	prune := time.NewTicker(time.Hour)
+	defer prune.Stop()
+
+	for {
+		select {
+		case <-flush.C:
+			if err := s.Flush(); err != nil {
+				fmt.Printf("Warning: failed to flush history: %v\n", err)
+			}
+		case now := <-prune.C:
+			s.Prune(now)
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// Record adds a sample for metric at time t
+func (s *Store) Record(metric string, t time.Time, value float64) error {
+	if err := validateMetric(metric); err != nil {
+		return err
+	}
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return fmt.Errorf("invalid value for %s: %v", metric, value)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return errors.New("history store is closed")
+	}
+
+	for _, tier := range s.config.Tiers {
+		key := tier.name() + "/" + metric
+
+		if tier.Resolution == 0 {
+			b := bucket{start: t}
+			b.add(value, value, value, 1)
+			if err := s.writeLocked(tier, metric, &b); err != nil {
+				return err
+			}
+			continue
+		}
+
+		start := t.Truncate(time.Duration(tier.Resolution))
+		current := s.pending[key]
+		if current != nil && !current.start.Equal(start) {
+			if err := s.writeLocked(tier, metric, current); err != nil {
+				return err
+			}
+			current = nil
+		}
+		if current == nil {
+			current = &bucket{start: start}
+			s.pending[key] = current
+		}
+		current.add(value, value, value, 1)
+	}
+
+	return nil
+}
+
+// RecordAll adds several samples taken at the same time
+func (s *Store) RecordAll(t time.Time, values map[string]float64) error {
+	var errs []error
+	for metric, value := range values {
+		if err := s.Record(metric, t, value); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// writeLocked appends a bucket to the segment file of its day
+func (s *Store) writeLocked(tier Tier, metric string, b *bucket) error {
+	key := tier.name() + "/" + metric
+	day := b.start.UTC().Format(segmentLayout)
+
+	w := s.open[key]
+	if w != nil && w.day != day {
+		if err := w.close(); err != nil {
+			return err
+		}
+		w = nil
+		delete(s.open, key)
+	}
+
+	if w == nil {
+		dir := filepath.Join(s.dir, tier.name(), metricDir(metric))
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return fmt.Errorf("failed to create history directory: %v", err)
+		}
+
+		file, err := os.OpenFile(filepath.Join(dir, day+".log"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+		if err != nil {
+			return fmt.Errorf("failed to open history segment: %v", err)
+		}
+
+		w = &segmentWriter{day: day, file: file, buf: bufio.NewWriter(file)}
+		s.open[key] = w
+	}
+
+	_, err := fmt.Fprintf(w.buf, "%d %s %s %s %d\n",
+		b.start.UnixMilli(), formatFloat(b.min), formatFloat(b.max), formatFloat(b.sum), b.count)
+	return err
+}
+
+func (w *segmentWriter) close() error {
+	if err := w.buf.Flush(); err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
+
+// Flush writes buffered samples to disk. Partial buckets of the coarser tiers
+// stay in memory until they are complete.
+func (s *Store) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.flushLocked()
+}
+
+func (s *Store) flushLocked() error {
+	var errs []error
+	for _, w := range s.open {
+		if err := w.buf.Flush(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close writes out partial buckets and buffered samples and stops the
+// background maintenance
+func (s *Store) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+
+	var errs []error
+	for _, tier := range s.config.Tiers {
+		for key, b := range s.pending {
+			if strings.HasPrefix(key, tier.name()+"/") {
+				errs = append(errs, s.writeLocked(tier, strings.TrimPrefix(key, tier.name()+"/"), b))
+			}
+		}
+	}
+	for key, w := range s.open {
+		errs = append(errs, w.close())
+		delete(s.open, key)
+	}
+	s.mu.Unlock()
+
+	close(s.stop)
+	<-s.done
+
+	return errors.Join(errs...)
+}
+
+// Prune removes the segments that lie entirely outside their tier's retention
+func (s *Store) Prune(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, tier := range s.config.Tiers {
+		cutoff := now.Add(-time.Duration(tier.Retention)).UTC()
+
+		metricDirs, err := os.ReadDir(filepath.Join(s.dir, tier.name()))
+		if err != nil {
+			continue
+		}
+
+		for _, entry := range metricDirs {
+			dir := filepath.Join(s.dir, tier.name(), entry.Name())
+			segments, err := os.ReadDir(dir)
+			if err != nil {
+				continue
+			}
+
+			for _, segment := range segments {
+				day, err := time.Parse(segmentLayout, strings.TrimSuffix(segment.Name(), ".log"))
+				if err != nil || !day.AddDate(0, 0, 1).Before(cutoff) {
+					continue
+				}
+
+				metric, _ := url.PathUnescape(entry.Name())
+				key := tier.name() + "/" + metric
+				if w := s.open[key]; w != nil && w.day == day.Format(segmentLayout) {
+					w.close()
+					delete(s.open, key)
+				}
+				os.Remove(filepath.Join(dir, segment.Name()))
+			}
+		}
+	}
+}
+
+// Metrics returns the names of every metric with stored samples
+func (s *Store) Metrics() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seen := make(map[string]bool)
+	for _, tier := range s.config.Tiers {
+		entries, err := os.ReadDir(filepath.Join(s.dir, tier.name()))
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if name, err := url.PathUnescape(entry.Name()); err == nil && entry.IsDir() {
+				seen[name] = true
+			}
+		}
+	}
+	for key := range s.pending {
+		seen[key[strings.Index(key, "/")+1:]] = true
+	}
+
+	metrics := make([]string, 0, len(seen))
+	for name := range seen {
+		metrics = append(metrics, name)
+	}
+	sort.Strings(metrics)
+	return metrics
+}
+
+// Query returns the samples of metric between from and to, averaged into
+// buckets of step. The finest tier that still holds from and is not finer
+// than necessary is read; a step of zero returns that tier's points as stored.
+func (s *Store) Query(metric string, from, to time.Time, step time.Duration) ([]Point, error) {
+	if err := validateMetric(metric); err != nil {
+		return nil, err
+	}
+	if to.Before(from) {
+		return nil, fmt.Errorf("%w: 'to' must not be before 'from'", ErrInvalidRange)
+	}
+	// Nothing older than the longest retention is kept, and every day of the
+	// range is a segment file to look for
+	if longest := s.longestRetention(); to.Sub(from) > longest {
+		return nil, fmt.Errorf("%w: it must not be longer than the %v of history kept", ErrInvalidRange, longest)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.flushLocked(); err != nil {
+		return nil, err
+	}
+
+	tier := s.selectTier(from, step, time.Now())
+	key := tier.name() + "/" + metric
+
+	buckets, err := s.readSegments(tier, metric, from, to)
+	if err != nil {
+		return nil, err
+	}
+	if b := s.pending[key]; b != nil && !b.start.Before(from) && !b.start.After(to) {
+		buckets = append(buckets, *b)
+	}
+
+	// Coarser tiers may hold several entries for one bucket across restarts
+	if step < time.Duration(tier.Resolution) {
+		step = time.Duration(tier.Resolution)
+	}
+
+	return downsample(buckets, step), nil
+}
+
+// selectTier picks the coarsest tier not coarser than step among those whose
+// retention reaches back to from, falling back to the finest of them, or to
+// the tier with the longest retention when none does
+func (s *Store) selectTier(from time.Time, step time.Duration, now time.Time) Tier {
+	var covering []Tier
+	for _, tier := range s.config.Tiers {
+		if !from.Before(now.Add(-time.Duration(tier.Retention))) {
+			covering = append(covering, tier)
+		}
+	}
+
+	if len(covering) == 0 {
+		longest := s.config.Tiers[0]
+		for _, tier := range s.config.Tiers[1:] {
+			if tier.Retention > longest.Retention {
+				longest = tier
+			}
+		}
+		return longest
+	}
+
+	selected := covering[0]
+	for _, tier := range covering[1:] {
+		if time.Duration(tier.Resolution) <= step {
+			selected = tier
+		}
+	}
+	return selected
+}
+
+// longestRetention returns how far back the store keeps samples
+func (s *Store) longestRetention() time.Duration {
+	var longest time.Duration
+	for _, tier := range s.config.Tiers {
+		longest = max(longest, time.Duration(tier.Retention))
+	}
+	return longest
+}
+
+// readSegments reads the buckets of a tier and metric between from and to
+func (s *Store) readSegments(tier Tier, metric string, from, to time.Time) ([]bucket, error) {
+	dir := filepath.Join(s.dir, tier.name(), metricDir(metric))
+
+	var buckets []bucket
+	for day := from.UTC().Truncate(24 * time.Hour); !day.After(to); day = day.AddDate(0, 0, 1) {
+		file, err := os.Open(filepath.Join(dir, day.Format(segmentLayout)+".log"))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to open history segment: %v", err)
+		}
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			b, ok := parseBucket(scanner.Text())
+			if !ok || b.start.Before(from) || b.start.After(to) {
+				continue
+			}
+			buckets = append(buckets, b)
+		}
+		err = scanner.Err()
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read history segment: %v", err)
+		}
+	}
+
+	return buckets, nil
+}
+
+// parseBucket parses a segment line of the form "unixMillis min max sum count"
+func parseBucket(line string) (bucket, bool) {
+	fields := strings.Fields(line)
+	if len(fields) != 5 {
+		return bucket{}, false
+	}
+
+	millis, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return bucket{}, false
+	}
+
+	var values [3]float64
+	for i := range values {
+		if values[i], err = strconv.ParseFloat(fields[i+1], 64); err != nil {
+			return bucket{}, false
+		}
+	}
+
+	count, err := strconv.Atoi(fields[4])
+	if err != nil || count <= 0 {
+		return bucket{}, false
+	}
+
+	return bucket{start: time.UnixMilli(millis), min: values[0], max: values[1], sum: values[2], count: count}, true
+}
+
+// downsample merges buckets into steps, or only orders them when step is zero
+func downsample(buckets []bucket, step time.Duration) []Point {
+	sort.SliceStable(buckets, func(i, j int) bool {
+		return buckets[i].start.Before(buckets[j].start)
+	})
+
+	points := make([]Point, 0, len(buckets))
+	if step <= 0 {
+		for i := range buckets {
+			points = append(points, buckets[i].point())
+		}
+		return points
+	}
+
+	var current *bucket
+	for _, b := range buckets {
+		start := b.start.Truncate(step)
+		if current == nil || !current.start.Equal(start) {
+			if current != nil {
+				points = append(points, current.point())
+			}
+			current = &bucket{start: start}
+		}
+		current.add(b.min, b.max, b.sum, b.count)
+	}
+	if current != nil {
+		points = append(points, current.point())
+	}
+
+	return points
+}
+
+func validateMetric(metric string) error {
+	if metric == "" || len(metric) > 128 || strings.ContainsAny(metric, "\x00\n") || len(metricDir(metric)) > 255 {
+		return fmt.Errorf("%w: %q", ErrInvalidMetric, metric)
+	}
+	return nil
+}
+
+// windowsDeviceNames are file names Windows reserves in every directory
+var windowsDeviceNames = map[string]bool{
+	"con": true, "prn": true, "aux": true, "nul": true,
+	"com1": true, "com2": true, "com3": true, "com4": true, "com5": true, "com6": true, "com7": true, "com8": true, "com9": true,
+	"lpt1": true, "lpt2": true, "lpt3": true, "lpt4": true, "lpt5": true, "lpt6": true, "lpt7": true, "lpt8": true, "lpt9": true,
+}
+
+// metricDir returns the directory name of a metric. Everything but lower case
+// letters, digits, '-' and '_' is percent-encoded, as are the reserved device
+// names of Windows, so that the name is valid on every filesystem and metrics
+// differing only in case never share a directory on case-insensitive ones.
+// url.PathUnescape decodes it.
+func metricDir(metric string) string {
+	var b strings.Builder
+	for i := 0; i < len(metric); i++ {
+		c := metric[i]
+		if ('a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_') && !(i == 0 && windowsDeviceNames[metric]) {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
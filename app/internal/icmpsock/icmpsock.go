@@ -0,0 +1,334 @@
+// Package icmpsock provides the ICMP sockets shared by the dashboard prober and
+// the ping and traceroute plugins.
+package icmpsock
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// IANA protocol numbers used to parse ICMP messages and the packets they quote
+const (
+	ProtocolICMP     = 1
+	ProtocolTCP      = 6
+	ProtocolUDP      = 17
+	ProtocolIPv6ICMP = 58
+)
+
+// ErrUnavailable is returned when neither an unprivileged ICMP datagram
+// socket nor a raw socket can be opened
+var ErrUnavailable = errors.New("ICMP sockets are not available")
+
+// Options configure the socket of a native probe
+type Options struct {
+	Interface    string // interface to send from, using its address as source
+	DontFragment bool   // set the DF bit on IPv4 and never fragment IPv6 locally
+	Raw          bool   // require a raw socket, to receive the ICMP errors caused by any of our packets
+}
+
+// Conn is an ICMP endpoint for one address family. It is an unprivileged
+// datagram socket where the system allows it and a raw socket otherwise.
+type Conn struct {
+	conn     net.PacketConn
+	p4       *ipv4.PacketConn // set for IPv4 sockets whose TTL can be set and read
+	p6       *ipv6.PacketConn // set for IPv6 sockets whose hop limit can be set and read
+	ipv6     bool
+	datagram bool // the system sets the echo identifier and only passes on our replies
+}
+
+// Packet is a message read from a Conn
+type Packet struct {
+	Message  *icmp.Message
+	From     netip.Addr
+	TTL      int // hop limit of the packet on arrival, 0 when unknown
+	Size     int // bytes of the ICMP message
+	Received time.Time
+}
+
+// Listen opens an ICMP endpoint for sending to dst
+func Listen(dst netip.Addr, opts Options) (*Conn, error) {
+	ipv6Dst := dst.Is6() && !dst.Is4In6()
+
+	source := netip.IPv4Unspecified()
+	if ipv6Dst {
+		source = netip.IPv6Unspecified()
+	}
+	if opts.Interface != "" {
+		addr, err := InterfaceSource(opts.Interface, ipv6Dst, dst.IsLinkLocalUnicast())
+		if err != nil {
+			return nil, err
+		}
+		source = addr
+	}
+
+	conn, datagram, err := openICMPSocket(ipv6Dst, source, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Conn{conn: conn, ipv6: ipv6Dst, datagram: datagram}
+	switch conn.(type) {
+	case *net.UDPConn, *net.IPConn:
+		// Without control messages the TTL of packets is reported as unknown
+		if ipv6Dst {
+			c.p6 = ipv6.NewPacketConn(conn)
+			_ = c.p6.SetControlMessage(ipv6.FlagHopLimit, true)
+		} else {
+			c.p4 = ipv4.NewPacketConn(conn)
+			_ = c.p4.SetControlMessage(ipv4.FlagTTL, true)
+		}
+	}
+	return c, nil
+}
+
+// InterfaceSource returns the address of the named interface to send from
+func InterfaceSource(name string, ipv6 bool, linkLocal bool) (netip.Addr, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("no network interface named %q", name)
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("failed to read the addresses of %s: %v", name, err)
+	}
+
+	var fallback netip.Addr
+	for _, a := range addrs {
+		prefix, err := netip.ParsePrefix(a.String())
+		if err != nil {
+			continue
+		}
+		addr := prefix.Addr()
+		if addr.Is4() == ipv6 {
+			continue
+		}
+		if addr.IsLinkLocalUnicast() {
+			addr = addr.WithZone(name)
+		}
+		if addr.IsLinkLocalUnicast() == linkLocal {
+			return addr, nil
+		}
+		if !fallback.IsValid() {
+			fallback = addr
+		}
+	}
+	if fallback.IsValid() {
+		return fallback, nil
+	}
+
+	family := "IPv4"
+	if ipv6 {
+		family = "IPv6"
+	}
+	return netip.Addr{}, fmt.Errorf("network interface %s has no %s address", name, family)
+}
+
+// Close closes the endpoint, ending any read in progress
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+// Privileged reports whether the endpoint is a raw socket, which also
+// receives the ICMP errors caused by our probes
+func (c *Conn) Privileged() bool {
+	return !c.datagram
+}
+
+// OwnsEcho reports whether an echo with identifier id is one of ours. The
+// system rewrites the identifier of datagram sockets and only passes on
+// their own replies.
+func (c *Conn) OwnsEcho(echo *icmp.Echo, id int) bool {
+	return c.datagram || echo.ID == id
+}
+
+// SetTTL sets the TTL, or hop limit, of the requests sent afterwards
+func (c *Conn) SetTTL(ttl int) error {
+	switch {
+	case c.p4 != nil:
+		return c.p4.SetTTL(ttl)
+	case c.p6 != nil:
+		return c.p6.SetHopLimit(ttl)
+	}
+	return fmt.Errorf("setting the TTL of ICMP requests is not supported on this platform")
+}
+
+// SetReadDeadline sets the time after which Read fails with a timeout
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// WriteEcho sends an echo request
+func (c *Conn) WriteEcho(dst netip.Addr, id, seq int, data []byte) error {
+	var typ icmp.Type = ipv4.ICMPTypeEcho
+	if c.ipv6 {
+		typ = ipv6.ICMPTypeEchoRequest
+	}
+	msg := icmp.Message{Type: typ, Body: &icmp.Echo{ID: id, Seq: seq, Data: data}}
+	// The system computes the ICMPv6 checksum
+	b, err := msg.Marshal(nil)
+	if err != nil {
+		return err
+	}
+
+	var addr net.Addr = &net.IPAddr{IP: dst.AsSlice(), Zone: dst.Zone()}
+	if c.datagram {
+		addr = &net.UDPAddr{IP: dst.AsSlice(), Zone: dst.Zone()}
+	}
+	_, err = c.conn.WriteTo(b, addr)
+	return err
+}
+
+// Read reads the next ICMP message using buf, blocking until one arrives or
+// the endpoint is closed
+func (c *Conn) Read(buf []byte) (Packet, error) {
+	var n, ttl int
+	var from net.Addr
+	var err error
+	switch {
+	case c.p4 != nil:
+		var cm *ipv4.ControlMessage
+		n, cm, from, err = c.p4.ReadFrom(buf)
+		if cm != nil {
+			ttl = cm.TTL
+		}
+	case c.p6 != nil:
+		var cm *ipv6.ControlMessage
+		n, cm, from, err = c.p6.ReadFrom(buf)
+		if cm != nil {
+			ttl = cm.HopLimit
+		}
+	default:
+		n, from, err = c.conn.ReadFrom(buf)
+	}
+	if err != nil {
+		return Packet{}, err
+	}
+	received := time.Now()
+
+	proto := ProtocolICMP
+	if c.ipv6 {
+		proto = ProtocolIPv6ICMP
+	}
+	// The message keeps slices of its bytes, which must outlive buf
+	msg, err := icmp.ParseMessage(proto, append([]byte(nil), buf[:n]...))
+	if err != nil {
+		return Packet{}, err
+	}
+	return Packet{Message: msg, From: netAddrToAddr(from), TTL: ttl, Size: n, Received: received}, nil
+}
+
+// ReadLoop passes the messages read from the endpoint to packets until it is
+// closed or done is closed
+func (c *Conn) ReadLoop(packets chan<- Packet, done <-chan struct{}) {
+	buf := make([]byte, 65536)
+	for {
+		packet, err := c.Read(buf)
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil {
+			continue
+		}
+		select {
+		case packets <- packet:
+		case <-done:
+			return
+		}
+	}
+}
+
+// netAddrToAddr returns the IP address of a packet's source
+func netAddrToAddr(addr net.Addr) netip.Addr {
+	var ip net.IP
+	var zone string
+	switch a := addr.(type) {
+	case *net.IPAddr:
+		ip, zone = a.IP, a.Zone
+	case *net.UDPAddr:
+		ip, zone = a.IP, a.Zone
+	}
+	parsed, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return netip.Addr{}
+	}
+	return parsed.Unmap().WithZone(zone)
+}
+
+// Quoted is the start of one of our packets, as quoted by an ICMP
+// error such as destination unreachable or time exceeded
+type Quoted struct {
+	Protocol int // ProtocolICMP or ProtocolIPv6ICMP for echo requests, ProtocolUDP or ProtocolTCP
+	Dst      netip.Addr
+	SrcPort  int // UDP and TCP
+	DstPort  int
+	Echo     bool // an echo request, with EchoID and EchoSeq
+	EchoID   int
+	EchoSeq  int
+}
+
+// ParseQuoted returns the packet quoted by an ICMP error
+func ParseQuoted(msg *icmp.Message, ipv6Packet bool) (Quoted, bool) {
+	var data []byte
+	switch body := msg.Body.(type) {
+	case *icmp.DstUnreach:
+		data = body.Data
+	case *icmp.TimeExceeded:
+		data = body.Data
+	case *icmp.PacketTooBig:
+		data = body.Data
+	default:
+		return Quoted{}, false
+	}
+
+	// The quoted packet starts with its IP header, which for IPv6 is assumed
+	// to have no extension headers, as our probes do not
+	var quoted Quoted
+	var headerLen int
+	if ipv6Packet {
+		if len(data) < ipv6.HeaderLen {
+			return Quoted{}, false
+		}
+		headerLen = ipv6.HeaderLen
+		quoted.Protocol = int(data[6])
+		quoted.Dst = netip.AddrFrom16([16]byte(data[24:40]))
+	} else {
+		if len(data) < ipv4.HeaderLen {
+			return Quoted{}, false
+		}
+		headerLen = int(data[0]&0x0f) << 2
+		quoted.Protocol = int(data[9])
+		quoted.Dst = netip.AddrFrom4([4]byte(data[16:20]))
+	}
+
+	// ICMP errors quote at least 8 bytes past the IP header
+	if len(data) < headerLen+8 {
+		return Quoted{}, false
+	}
+	transport := data[headerLen:]
+	switch quoted.Protocol {
+	case ProtocolICMP, ProtocolIPv6ICMP:
+		echoType := byte(ipv4.ICMPTypeEcho)
+		if ipv6Packet {
+			echoType = byte(ipv6.ICMPTypeEchoRequest)
+		}
+		if transport[0] != echoType {
+			return Quoted{}, false
+		}
+		quoted.Echo = true
+		quoted.EchoID = int(transport[4])<<8 | int(transport[5])
+		quoted.EchoSeq = int(transport[6])<<8 | int(transport[7])
+	case ProtocolUDP, ProtocolTCP:
+		quoted.SrcPort = int(transport[0])<<8 | int(transport[1])
+		quoted.DstPort = int(transport[2])<<8 | int(transport[3])
+	default:
+		return Quoted{}, false
+	}
+	return quoted, true
+}
@@ -0,0 +1,149 @@
+package icmpsock
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"syscall"
+)
+
+// openICMPSocket opens an unprivileged ICMP socket, which the system allows
+// for the groups in net.ipv4.ping_group_range, or else, or when opts.Raw is
+// set, a raw socket, which needs root or CAP_NET_RAW. It reports whether the
+// socket is unprivileged.
+func openICMPSocket(ipv6 bool, source netip.Addr, opts Options) (net.PacketConn, bool, error) {
+	family, proto := syscall.AF_INET, ProtocolICMP
+	if ipv6 {
+		family, proto = syscall.AF_INET6, ProtocolIPv6ICMP
+	}
+
+	var fd int
+	var err error
+	datagram := !opts.Raw
+	if datagram {
+		fd, err = syscall.Socket(family, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, proto)
+	}
+	if !datagram || err != nil {
+		datagram = false
+		fd, err = syscall.Socket(family, syscall.SOCK_RAW|syscall.SOCK_CLOEXEC, proto)
+	}
+	if err != nil {
+		if errors.Is(err, syscall.EPERM) || errors.Is(err, syscall.EACCES) {
+			if opts.Raw {
+				return nil, false, fmt.Errorf("%w: run NetTool as root or grant it CAP_NET_RAW", ErrUnavailable)
+			}
+			return nil, false, fmt.Errorf("%w: run NetTool as root, grant it CAP_NET_RAW or add its group to net.ipv4.ping_group_range", ErrUnavailable)
+		}
+		return nil, false, os.NewSyscallError("socket", err)
+	}
+
+	if err := setupICMPSocket(fd, ipv6, source, opts); err != nil {
+		syscall.Close(fd)
+		return nil, false, err
+	}
+
+	f := os.NewFile(uintptr(fd), "icmp")
+	conn, err := net.FilePacketConn(f)
+	f.Close()
+	if err != nil {
+		return nil, false, err
+	}
+	return conn, datagram, nil
+}
+
+// setupICMPSocket applies opts to the socket and binds it to source
+func setupICMPSocket(fd int, ipv6 bool, source netip.Addr, opts Options) error {
+	if opts.Interface != "" {
+		if err := syscall.SetsockoptString(fd, syscall.SOL_SOCKET, syscall.SO_BINDTODEVICE, opts.Interface); err != nil {
+			return fmt.Errorf("failed to bind to %s: %v", opts.Interface, err)
+		}
+	}
+
+	if opts.DontFragment {
+		var err error
+		if ipv6 {
+			err = syscall.SetsockoptInt(fd, syscall.IPPROTO_IPV6, syscall.IPV6_MTU_DISCOVER, syscall.IPV6_PMTUDISC_DO)
+		} else {
+			err = syscall.SetsockoptInt(fd, syscall.IPPROTO_IP, syscall.IP_MTU_DISCOVER, syscall.IP_PMTUDISC_DO)
+		}
+		if err != nil {
+			return os.NewSyscallError("setsockopt", err)
+		}
+	}
+
+	var sa syscall.Sockaddr
+	if ipv6 {
+		sa6 := &syscall.SockaddrInet6{Addr: source.As16()}
+		if zone := source.Zone(); zone != "" {
+			if iface, err := net.InterfaceByName(zone); err == nil {
+				sa6.ZoneId = uint32(iface.Index)
+			}
+		}
+		sa = sa6
+	} else {
+		sa = &syscall.SockaddrInet4{Addr: source.As4()}
+	}
+	return os.NewSyscallError("bind", syscall.Bind(fd, sa))
+}
+
+// ProbeControl returns a Control function for dialers and listeners
+// creating UDP and TCP probes, which sets the TTL of the socket and binds it
+// to iface. With bound set, the socket is also bound to source and bound is
+// called with its port before anything is sent, so that ICMP errors quoting
+// the probe can be matched to it.
+func ProbeControl(ipv6 bool, ttl int, iface string, source netip.Addr, bound func(port int)) func(network, address string, c syscall.RawConn) error {
+	return func(_, _ string, c syscall.RawConn) error {
+		var sockErr error
+		err := c.Control(func(fd uintptr) {
+			sockErr = setupProbeSocket(int(fd), ipv6, ttl, iface, source, bound)
+		})
+		if err != nil {
+			return err
+		}
+		return sockErr
+	}
+}
+
+func setupProbeSocket(fd int, ipv6 bool, ttl int, iface string, source netip.Addr, bound func(port int)) error {
+	if iface != "" {
+		if err := syscall.SetsockoptString(fd, syscall.SOL_SOCKET, syscall.SO_BINDTODEVICE, iface); err != nil {
+			return fmt.Errorf("failed to bind to %s: %v", iface, err)
+		}
+	}
+
+	var err error
+	if ipv6 {
+		err = syscall.SetsockoptInt(fd, syscall.IPPROTO_IPV6, syscall.IPV6_UNICAST_HOPS, ttl)
+	} else {
+		err = syscall.SetsockoptInt(fd, syscall.IPPROTO_IP, syscall.IP_TTL, ttl)
+	}
+	if err != nil {
+		return os.NewSyscallError("setsockopt", err)
+	}
+	if bound == nil {
+		return nil
+	}
+
+	var sa syscall.Sockaddr
+	if ipv6 {
+		sa = &syscall.SockaddrInet6{Addr: source.As16()}
+	} else {
+		sa = &syscall.SockaddrInet4{Addr: source.As4()}
+	}
+	if err := syscall.Bind(fd, sa); err != nil {
+		return os.NewSyscallError("bind", err)
+	}
+	local, err := syscall.Getsockname(fd)
+	if err != nil {
+		return os.NewSyscallError("getsockname", err)
+	}
+	switch addr := local.(type) {
+	case *syscall.SockaddrInet4:
+		bound(addr.Port)
+	case *syscall.SockaddrInet6:
+		bound(addr.Port)
+	}
+	return nil
+}
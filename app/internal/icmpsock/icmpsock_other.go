@@ -0,0 +1,46 @@
+//go:build !linux
+
+package icmpsock
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"syscall"
+
+	"golang.org/x/net/icmp"
+)
+
+// openICMPSocket opens an unprivileged ICMP socket where the system supports
+// them, or else a raw socket. It reports whether the socket is unprivileged.
+// The interface is only used for its address and the DF bit is only set on
+// Linux.
+func openICMPSocket(ipv6 bool, source netip.Addr, opts Options) (net.PacketConn, bool, error) {
+	if opts.DontFragment {
+		return nil, false, fmt.Errorf("setting the don't fragment bit is only supported on Linux")
+	}
+
+	datagramNetwork, rawNetwork := "udp4", "ip4:icmp"
+	if ipv6 {
+		datagramNetwork, rawNetwork = "udp6", "ip6:ipv6-icmp"
+	}
+
+	if !opts.Raw {
+		if conn, err := icmp.ListenPacket(datagramNetwork, source.String()); err == nil {
+			return conn, true, nil
+		}
+	}
+	conn, err := icmp.ListenPacket(rawNetwork, source.String())
+	if err != nil {
+		return nil, false, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	return conn, false, nil
+}
+
+// ProbeControl fails: setting the TTL of UDP and TCP probes is only
+// supported on Linux
+func ProbeControl(_ bool, _ int, _ string, _ netip.Addr, _ func(port int)) func(network, address string, c syscall.RawConn) error {
+	return func(_, _ string, _ syscall.RawConn) error {
+		return fmt.Errorf("UDP and TCP probes are only supported on Linux")
+	}
+}
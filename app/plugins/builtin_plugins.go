@@ -0,0 +1,98 @@
+package plugins
+
+import (
+	"context"
+
+	"github.com/NetScout-Go/NetTool/app/plugins/types"
+)
+
+// builtinPlugins are the diagnostics compiled into NetTool. They run for the
+// installed plugins of the same ID, whose plugin.json describes their
+// parameters, unless a plugin sets overrideBuiltin.
+var builtinPlugins = map[string]ExecuteContextFunc{
+	"network_latency_heatmap": WithContext(executeNetworkLatencyHeatmap),
+	"port_scanner":            executePortScanner,
+	"bandwidth_test":          executeBandwidthTest,
+	"packet_capture":          WithContext(executePacketCapture),
+	"tc_controller":           WithContext(executeTCController),
+	"arp_manager":             WithContext(executeARPManager),
+	"device_discovery":        WithContext(executeDeviceDiscovery),
+	"network_quality":         WithContext(executeNetworkQuality),
+	"mtu_tester":              WithContext(executeMTUTester),
+	"wifi_scanner":            executeWifiScanner,
+}
+
+// networkInfoDefinition describes the dashboard's network information, which
+// is listed as a plugin without being installed
+var networkInfoDefinition = types.PluginDefinition{
+	ID:          "network_info",
+	Name:        "Network Information",
+	Description: "Displays detailed information about the device's network connections",
+	Version:     "1.0.0",
+	Author:      "NetTool Team",
+	License:     "MIT",
+	Icon:        "network",
+	Parameters:  []types.PluginParam{}, // No parameters needed
+}
+
+// registerBuiltins registers the builtin plugins with r
+func registerBuiltins(r *PluginRegistry) {
+	for id, fn := range builtinPlugins {
+		r.RegisterBuiltin(id, WithEvents(fn), nil)
+	}
+
+	r.RegisterBuiltin("ping", executePing, &pingDefinition)
+	r.RegisterBuiltin("traceroute", executeTraceroute, &tracerouteDefinition)
+	r.RegisterBuiltin("dns_lookup", executeDNSLookup, &dnsLookupDefinition)
+	r.RegisterBuiltin("dns_propagation", executeDNSPropagation, &dnsPropagationDefinition)
+	r.RegisterBuiltin("reverse_dns_lookup", executeReverseDNSLookup, &reverseDNSDefinition)
+	r.RegisterBuiltin("ssl_checker", executeSSLChecker, &sslCheckerDefinition)
+
+	r.RegisterBuiltin("network_info", WithEvents(WithContext(func(_ map[string]interface{}) (interface{}, error) {
+		// This plugin is handled directly by the main dashboard
+		return map[string]interface{}{"message": "Network info plugin is handled by the dashboard"}, nil
+	})), &networkInfoDefinition)
+}
+
+// iterableBuiltins create the builtins that keep state across iterations,
+// such as the per-hop statistics of traceroute's MTR mode, or that decide
+// when to stop, such as the DNS propagation check
+var iterableBuiltins = map[string]func(types.PluginDefinition) types.IterablePlugin{
+	"traceroute":      newTracerouteIterator,
+	"dns_propagation": newDNSPropagationIterator,
+}
+
+// IterableBuiltin returns a builtin that describes itself, for tools running
+// plugins outside the plugin manager. Builtins with a parameter that can
+// iterate run each iteration with its number in iterationCount, as the plugin
+// page does, and always continue until the caller stops.
+func IterableBuiltin(id string) (types.IterablePlugin, bool) {
+	builtin, ok := GetRegistry().Builtins()[id]
+	if !ok || builtin.Definition == nil {
+		return nil, false
+	}
+	if create, ok := iterableBuiltins[id]; ok {
+		return create(*builtin.Definition), true
+	}
+
+	execute := func(params map[string]interface{}) (interface{}, error) {
+		return builtin.Execute(context.Background(), params, func(types.Event) {})
+	}
+	plugin := types.NewIterablePlugin(*builtin.Definition, execute, func(params map[string]interface{}, iterationCount int) (interface{}, bool, error) {
+		iterationParams := make(map[string]interface{}, len(params)+1)
+		for key, value := range params {
+			iterationParams[key] = value
+		}
+		iterationParams["iterationCount"] = float64(iterationCount)
+		result, err := execute(iterationParams)
+		return result, true, err
+	})
+
+	plugin.SupportsIterationFlag = false
+	for _, param := range builtin.Definition.Parameters {
+		if param.CanIterate {
+			plugin.SupportsIterationFlag = true
+		}
+	}
+	return plugin, true
+}
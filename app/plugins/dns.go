@@ -0,0 +1,662 @@
+package plugins
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/NetScout-Go/NetTool/app/core"
+)
+
+// Transports of DNS queries
+const (
+	dnsUDP = "udp"
+	dnsTCP = "tcp"
+	dnsDoT = "dot" // DNS over TLS, RFC 7858
+	dnsDoH = "doh" // DNS over HTTPS, RFC 8484
+)
+
+// Limits of the DNS client
+const (
+	dnsUDPSize        = 1232 // EDNS0 payload size recommended by DNS Flag Day 2020
+	maxDNSMessageSize = 65535
+	defaultDNSTimeout = 5 * time.Second
+	dohContentType    = "application/dns-message"
+)
+
+// DNSSEC status of a response
+const (
+	dnssecSecure   = "secure"   // the resolver validated the answer and set AD
+	dnssecSigned   = "signed"   // the answer carries signatures the resolver did not validate
+	dnssecInsecure = "insecure" // the answer is neither validated nor signed
+)
+
+// Record types without a constant in dnsmessage
+const (
+	dnsTypeDS     dnsmessage.Type = 43
+	dnsTypeRRSIG  dnsmessage.Type = 46
+	dnsTypeNSEC   dnsmessage.Type = 47
+	dnsTypeDNSKEY dnsmessage.Type = 48
+	dnsTypeNSEC3  dnsmessage.Type = 50
+	dnsTypeCAA    dnsmessage.Type = 257
+)
+
+// ErrNoDNSServers is returned when no resolver is given and the system has none
+var ErrNoDNSServers = errors.New("no DNS servers are configured")
+
+// dnsTypes are the record types by their names
+var dnsTypes = map[string]dnsmessage.Type{
+	"A":      dnsmessage.TypeA,
+	"NS":     dnsmessage.TypeNS,
+	"CNAME":  dnsmessage.TypeCNAME,
+	"SOA":    dnsmessage.TypeSOA,
+	"PTR":    dnsmessage.TypePTR,
+	"MX":     dnsmessage.TypeMX,
+	"TXT":    dnsmessage.TypeTXT,
+	"AAAA":   dnsmessage.TypeAAAA,
+	"SRV":    dnsmessage.TypeSRV,
+	"DS":     dnsTypeDS,
+	"RRSIG":  dnsTypeRRSIG,
+	"NSEC":   dnsTypeNSEC,
+	"DNSKEY": dnsTypeDNSKEY,
+	"NSEC3":  dnsTypeNSEC3,
+	"CAA":    dnsTypeCAA,
+}
+
+// dnsRcodes are the names of response codes, as dig prints them
+var dnsRcodes = map[dnsmessage.RCode]string{
+	0:  "NOERROR",
+	1:  "FORMERR",
+	2:  "SERVFAIL",
+	3:  "NXDOMAIN",
+	4:  "NOTIMP",
+	5:  "REFUSED",
+	6:  "YXDOMAIN",
+	7:  "YXRRSET",
+	8:  "NXRRSET",
+	9:  "NOTAUTH",
+	10: "NOTZONE",
+	16: "BADVERS",
+}
+
+// dnsQuery is a question for a resolver
+type dnsQuery struct {
+	Name      string
+	Type      dnsmessage.Type
+	Resolver  string // address for UDP and TCP, host for DoT, URL or host for DoH
+	Transport string
+	DNSSEC    bool // set the DO bit, asking for signatures
+	NoRecurse bool // clear the RD bit, for querying authoritative servers
+}
+
+// dnsRecord is a resource record of a response
+type dnsRecord struct {
+	Name  string      `json:"name"`
+	Type  string      `json:"type"`
+	TTL   uint32      `json:"ttl"`
+	Data  string      `json:"data"`            // presentation format, as dig prints it
+	Value interface{} `json:"value,omitempty"` // fields of MX, SRV, SOA and CAA records
+}
+
+// Fields of records with more than one
+type (
+	mxValue struct {
+		Preference uint16 `json:"preference"`
+		Exchange   string `json:"exchange"`
+	}
+	srvValue struct {
+		Priority uint16 `json:"priority"`
+		Weight   uint16 `json:"weight"`
+		Port     uint16 `json:"port"`
+		Target   string `json:"target"`
+	}
+	soaValue struct {
+		MName   string `json:"mname"`
+		RName   string `json:"rname"`
+		Serial  uint32 `json:"serial"`
+		Refresh uint32 `json:"refresh"`
+		Retry   uint32 `json:"retry"`
+		Expire  uint32 `json:"expire"`
+		MinTTL  uint32 `json:"minTtl"`
+	}
+	caaValue struct {
+		Flags uint8  `json:"flags"`
+		Tag   string `json:"tag"`
+		Value string `json:"value"`
+	}
+)
+
+// dnsFlags are the header flags of a response
+type dnsFlags struct {
+	Authoritative      bool `json:"aa"`
+	Truncated          bool `json:"tc"`
+	RecursionDesired   bool `json:"rd"`
+	RecursionAvailable bool `json:"ra"`
+	AuthenticData      bool `json:"ad"`
+	CheckingDisabled   bool `json:"cd"`
+}
+
+// dnsResponse is the answer of a resolver
+type dnsResponse struct {
+	Resolver    string      `json:"resolver"`
+	Transport   string      `json:"transport"`
+	Rcode       string      `json:"rcode"`
+	Flags       dnsFlags    `json:"flags"`
+	DNSSEC      string      `json:"dnssec"`
+	Answers     []dnsRecord `json:"answers"`
+	Authority   []dnsRecord `json:"authority"`
+	Additional  []dnsRecord `json:"additional"`
+	Size        int         `json:"messageSize"`
+	Time        float64     `json:"timeMs"`                // including connecting to the resolver
+	TCPFallback bool        `json:"tcpFallback,omitempty"` // a truncated UDP answer was asked again over TCP
+}
+
+// dnsClient sends DNS queries. The zero value uses the system's roots of
+// trust for DoT and DoH.
+type dnsClient struct {
+	Timeout    time.Duration
+	TLSConfig  *tls.Config  // for DoT; nil for the defaults
+	HTTPClient *http.Client // for DoH; nil for http.DefaultClient
+}
+
+// Exchange sends the query and returns the resolver's answer. Truncated UDP
+// answers are asked again over TCP.
+func (c *dnsClient) Exchange(ctx context.Context, q dnsQuery) (*dnsResponse, error) {
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = defaultDNSTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var id uint16
+	if q.Transport != dnsDoH {
+		// DoH uses 0, so that answers can be cached
+		var b [2]byte
+		if _, err := rand.Read(b[:]); err != nil {
+			return nil, err
+		}
+		id = binary.BigEndian.Uint16(b[:])
+	}
+	msg, err := buildDNSQuery(q, id)
+	if err != nil {
+		return nil, err
+	}
+
+	started := time.Now()
+	var answer []byte
+	var fallback bool
+	switch q.Transport {
+	case dnsUDP, "":
+		q.Transport = dnsUDP
+		answer, err = c.exchangeUDP(ctx, q.Resolver, msg, id)
+		if err == nil && truncated(answer) {
+			fallback = true
+			answer, err = c.exchangeStream(ctx, q.Resolver, dnsTCP, msg)
+		}
+	case dnsTCP, dnsDoT:
+		answer, err = c.exchangeStream(ctx, q.Resolver, q.Transport, msg)
+	case dnsDoH:
+		answer, err = c.exchangeHTTPS(ctx, q.Resolver, msg)
+	default:
+		return nil, fmt.Errorf("unknown DNS transport %q", q.Transport)
+	}
+	if err != nil {
+		return nil, err
+	}
+	elapsed := time.Since(started)
+
+	resp, err := parseDNSResponse(answer, id)
+	if err != nil {
+		return nil, err
+	}
+	resp.Resolver = q.Resolver
+	resp.Transport = q.Transport
+	resp.Time = float64(elapsed.Microseconds()) / 1000
+	resp.TCPFallback = fallback
+	return resp, nil
+}
+
+// buildDNSQuery builds the query message. It always carries an EDNS0 record
+// and sets AD, asking the resolver whether it validated the answer.
+func buildDNSQuery(q dnsQuery, id uint16) ([]byte, error) {
+	name, err := dnsmessage.NewName(dnsFQDN(q.Name))
+	if err != nil {
+		return nil, fmt.Errorf("invalid domain name %q: %v", q.Name, err)
+	}
+
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: id, RecursionDesired: !q.NoRecurse, AuthenticData: true})
+	b.EnableCompression()
+	if err := b.StartQuestions(); err != nil {
+		return nil, err
+	}
+	if err := b.Question(dnsmessage.Question{Name: name, Type: q.Type, Class: dnsmessage.ClassINET}); err != nil {
+		return nil, err
+	}
+	if err := b.StartAdditionals(); err != nil {
+		return nil, err
+	}
+	var opt dnsmessage.ResourceHeader
+	if err := opt.SetEDNS0(dnsUDPSize, dnsmessage.RCodeSuccess, q.DNSSEC); err != nil {
+		return nil, err
+	}
+	if err := b.OPTResource(opt, dnsmessage.OPTResource{}); err != nil {
+		return nil, err
+	}
+	return b.Finish()
+}
+
+// dnsFQDN returns name with the trailing dot of a fully qualified name
+func dnsFQDN(name string) string {
+	if strings.HasSuffix(name, ".") {
+		return name
+	}
+	return name + "."
+}
+
+// truncated reports whether the TC bit of a message is set
+func truncated(msg []byte) bool {
+	return len(msg) > 2 && msg[2]&0x02 != 0
+}
+
+// exchangeUDP sends msg in a datagram and waits for the answer with its ID.
+// Answers with other IDs, late or forged, are ignored.
+func (c *dnsClient) exchangeUDP(ctx context.Context, resolver string, msg []byte, id uint16) ([]byte, error) {
+	addr, _, err := dnsServerAddress(resolver, "53")
+	if err != nil {
+		return nil, err
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	// End a read in progress when the caller gives up
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	if _, err := conn.Write(msg); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, maxDNSMessageSize)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, dnsTimeoutError(ctx, resolver, err)
+		}
+		if n >= 2 && binary.BigEndian.Uint16(buf) == id {
+			return append([]byte(nil), buf[:n]...), nil
+		}
+	}
+}
+
+// exchangeStream sends msg over TCP or DoT, prefixed with its length
+func (c *dnsClient) exchangeStream(ctx context.Context, resolver, transport string, msg []byte) ([]byte, error) {
+	port := "53"
+	if transport == dnsDoT {
+		port = "853"
+	}
+	addr, host, err := dnsServerAddress(resolver, port)
+	if err != nil {
+		return nil, err
+	}
+
+	var conn net.Conn
+	if transport == dnsDoT {
+		config := &tls.Config{}
+		if c.TLSConfig != nil {
+			config = c.TLSConfig.Clone()
+		}
+		if config.ServerName == "" {
+			config.ServerName = host
+		}
+		d := tls.Dialer{Config: config}
+		conn, err = d.DialContext(ctx, "tcp", addr)
+	} else {
+		var d net.Dialer
+		conn, err = d.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	framed := make([]byte, 2+len(msg))
+	binary.BigEndian.PutUint16(framed, uint16(len(msg)))
+	copy(framed[2:], msg)
+	if _, err := conn.Write(framed); err != nil {
+		return nil, err
+	}
+
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return nil, dnsTimeoutError(ctx, resolver, err)
+	}
+	answer := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, answer); err != nil {
+		return nil, dnsTimeoutError(ctx, resolver, err)
+	}
+	return answer, nil
+}
+
+// exchangeHTTPS posts msg to a DoH resolver
+func (c *dnsClient) exchangeHTTPS(ctx context.Context, resolver string, msg []byte) ([]byte, error) {
+	endpoint, err := dohURL(resolver)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(msg))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", dohContentType)
+	req.Header.Set("Accept", dohContentType)
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, dnsTimeoutError(ctx, resolver, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DoH resolver %s answered %s", resolver, resp.Status)
+	}
+	if contentType := resp.Header.Get("Content-Type"); !strings.HasPrefix(contentType, dohContentType) {
+		return nil, fmt.Errorf("DoH resolver %s answered with %q instead of %s", resolver, contentType, dohContentType)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxDNSMessageSize))
+}
+
+// dnsTimeoutError describes err, reporting timeouts with the resolver
+func dnsTimeoutError(ctx context.Context, resolver string, err error) error {
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return ctx.Err()
+	}
+	var netErr net.Error
+	if ctx.Err() != nil || errors.As(err, &netErr) && netErr.Timeout() {
+		return fmt.Errorf("no answer from %s: %w", resolver, context.DeadlineExceeded)
+	}
+	return err
+}
+
+// dnsServerAddress returns the host:port of a resolver given as an address,
+// a host name, or either with a port, and its host
+func dnsServerAddress(resolver, port string) (string, string, error) {
+	resolver = strings.TrimSpace(resolver)
+	if resolver == "" {
+		return "", "", fmt.Errorf("resolver is required")
+	}
+	if addr, err := netip.ParseAddr(strings.Trim(resolver, "[]")); err == nil {
+		return net.JoinHostPort(addr.String(), port), addr.String(), nil
+	}
+	if host, p, err := net.SplitHostPort(resolver); err == nil {
+		if _, err := strconv.ParseUint(p, 10, 16); err != nil {
+			return "", "", fmt.Errorf("invalid port in resolver %q", resolver)
+		}
+		return net.JoinHostPort(host, p), host, nil
+	}
+	if !validHostname(resolver) {
+		return "", "", fmt.Errorf("invalid resolver %q", resolver)
+	}
+	return net.JoinHostPort(resolver, port), resolver, nil
+}
+
+// dohURL returns the URL of a DoH resolver, given as a URL or a host whose
+// resolver is at the usual /dns-query
+func dohURL(resolver string) (string, error) {
+	resolver = strings.TrimSpace(resolver)
+	if !strings.Contains(resolver, "://") {
+		addr, _, err := dnsServerAddress(resolver, "443")
+		if err != nil {
+			return "", err
+		}
+		resolver = "https://" + strings.TrimSuffix(addr, ":443") + "/dns-query"
+	}
+
+	u, err := url.Parse(resolver)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("invalid DoH resolver %q", resolver)
+	}
+	if u.Scheme != "https" {
+		return "", fmt.Errorf("DoH resolver %q must use https", resolver)
+	}
+	return u.String(), nil
+}
+
+// systemDNSServers returns the resolvers configured on the system
+func systemDNSServers() []string {
+	var servers []string
+	for _, server := range core.GetDNSServers() {
+		if _, err := netip.ParseAddr(server); err == nil {
+			servers = append(servers, server)
+		}
+	}
+	return servers
+}
+
+// parseDNSResponse parses the answer to the query with id
+func parseDNSResponse(msg []byte, id uint16) (*dnsResponse, error) {
+	var p dnsmessage.Parser
+	header, err := p.Start(msg)
+	if err != nil {
+		return nil, fmt.Errorf("malformed DNS response: %v", err)
+	}
+	if !header.Response || header.ID != id {
+		return nil, fmt.Errorf("malformed DNS response: not an answer to the query")
+	}
+	if err := p.SkipAllQuestions(); err != nil {
+		return nil, fmt.Errorf("malformed DNS response: %v", err)
+	}
+
+	resp := &dnsResponse{
+		Flags: dnsFlags{
+			Authoritative:      header.Authoritative,
+			Truncated:          header.Truncated,
+			RecursionDesired:   header.RecursionDesired,
+			RecursionAvailable: header.RecursionAvailable,
+			AuthenticData:      header.AuthenticData,
+			CheckingDisabled:   header.CheckingDisabled,
+		},
+		Size: len(msg),
+	}
+
+	sections := []*[]dnsRecord{&resp.Answers, &resp.Authority, &resp.Additional}
+	parsers := []func() ([]dnsmessage.Resource, error){p.AllAnswers, p.AllAuthorities, p.AllAdditionals}
+	rcode := header.RCode
+	for i, parse := range parsers {
+		resources, err := parse()
+		if err != nil {
+			return nil, fmt.Errorf("malformed DNS response: %v", err)
+		}
+		records := make([]dnsRecord, 0, len(resources))
+		for _, r := range resources {
+			if r.Header.Type == dnsmessage.TypeOPT {
+				// The EDNS0 pseudo record carries the upper bits of the rcode
+				rcode = r.Header.ExtendedRCode(header.RCode)
+				continue
+			}
+			records = append(records, newDNSRecord(r))
+		}
+		*sections[i] = records
+	}
+
+	resp.Rcode = dnsRcodeName(rcode)
+	resp.DNSSEC = dnssecInsecure
+	switch {
+	case header.AuthenticData:
+		resp.DNSSEC = dnssecSecure
+	case hasDNSType(resp.Answers, dnsTypeRRSIG) || hasDNSType(resp.Authority, dnsTypeRRSIG):
+		resp.DNSSEC = dnssecSigned
+	}
+	return resp, nil
+}
+
+func hasDNSType(records []dnsRecord, typ dnsmessage.Type) bool {
+	name := dnsTypeName(typ)
+	for _, r := range records {
+		if r.Type == name {
+			return true
+		}
+	}
+	return false
+}
+
+// dnsTypeName returns the name of a record type, as TYPEn for unknown ones
+func dnsTypeName(typ dnsmessage.Type) string {
+	for name, t := range dnsTypes {
+		if t == typ {
+			return name
+		}
+	}
+	return "TYPE" + strconv.Itoa(int(typ))
+}
+
+// dnsRcodeName returns the name of a response code
+func dnsRcodeName(rcode dnsmessage.RCode) string {
+	if name, ok := dnsRcodes[rcode]; ok {
+		return name
+	}
+	return "RCODE" + strconv.Itoa(int(rcode))
+}
+
+// newDNSRecord converts a parsed resource record
+func newDNSRecord(r dnsmessage.Resource) dnsRecord {
+	record := dnsRecord{Name: r.Header.Name.String(), Type: dnsTypeName(r.Header.Type), TTL: r.Header.TTL}
+
+	switch body := r.Body.(type) {
+	case *dnsmessage.AResource:
+		record.Data = netip.AddrFrom4(body.A).String()
+	case *dnsmessage.AAAAResource:
+		record.Data = netip.AddrFrom16(body.AAAA).String()
+	case *dnsmessage.NSResource:
+		record.Data = body.NS.String()
+	case *dnsmessage.CNAMEResource:
+		record.Data = body.CNAME.String()
+	case *dnsmessage.PTRResource:
+		record.Data = body.PTR.String()
+	case *dnsmessage.MXResource:
+		record.Value = mxValue{Preference: body.Pref, Exchange: body.MX.String()}
+		record.Data = fmt.Sprintf("%d %s", body.Pref, body.MX)
+	case *dnsmessage.TXTResource:
+		quoted := make([]string, len(body.TXT))
+		for i, s := range body.TXT {
+			quoted[i] = strconv.Quote(s)
+		}
+		record.Data = strings.Join(quoted, " ")
+	case *dnsmessage.SRVResource:
+		record.Value = srvValue{Priority: body.Priority, Weight: body.Weight, Port: body.Port, Target: body.Target.String()}
+		record.Data = fmt.Sprintf("%d %d %d %s", body.Priority, body.Weight, body.Port, body.Target)
+	case *dnsmessage.SOAResource:
+		soa := soaValue{MName: body.NS.String(), RName: body.MBox.String(), Serial: body.Serial, Refresh: body.Refresh, Retry: body.Retry, Expire: body.Expire, MinTTL: body.MinTTL}
+		record.Value = soa
+		record.Data = fmt.Sprintf("%s %s %d %d %d %d %d", soa.MName, soa.RName, soa.Serial, soa.Refresh, soa.Retry, soa.Expire, soa.MinTTL)
+	case *dnsmessage.UnknownResource:
+		record.Data, record.Value = unknownRecordData(r.Header.Type, body.Data)
+	}
+	return record
+}
+
+// unknownRecordData presents the record types dnsmessage does not parse:
+// CAA and the summary of RRSIG records, and others in the generic format of
+// RFC 3597
+func unknownRecordData(typ dnsmessage.Type, data []byte) (string, interface{}) {
+	switch typ {
+	case dnsTypeCAA:
+		if len(data) < 2 {
+			break
+		}
+		// Converted before adding, as 2+data[1] would wrap in a byte
+		if n := 2 + int(data[1]); len(data) >= n {
+			caa := caaValue{Flags: data[0], Tag: string(data[2:n]), Value: string(data[n:])}
+			return fmt.Sprintf("%d %s %s", caa.Flags, caa.Tag, strconv.Quote(caa.Value)), caa
+		}
+	case dnsTypeRRSIG:
+		// Type covered, algorithm, labels, original TTL, expiration,
+		// inception, key tag and signer; the signature is left out
+		if len(data) >= 18 {
+			if signer, ok := wireName(data[18:]); ok {
+				return fmt.Sprintf("%s %d %d %d %s %s %d %s",
+					dnsTypeName(dnsmessage.Type(binary.BigEndian.Uint16(data))), data[2], data[3],
+					binary.BigEndian.Uint32(data[4:]),
+					rrsigTime(binary.BigEndian.Uint32(data[8:])), rrsigTime(binary.BigEndian.Uint32(data[12:])),
+					binary.BigEndian.Uint16(data[16:]), signer), nil
+			}
+		}
+	}
+	return fmt.Sprintf("\\# %d %s", len(data), hex.EncodeToString(data)), nil
+}
+
+// wireName reads an uncompressed domain name, as signers are stored
+func wireName(data []byte) (string, bool) {
+	var labels []string
+	for i := 0; i < len(data); {
+		n := int(data[i])
+		if n == 0 {
+			return strings.Join(labels, ".") + ".", true
+		}
+		if n > 63 || i+1+n > len(data) {
+			return "", false
+		}
+		labels = append(labels, string(data[i+1:i+1+n]))
+		i += 1 + n
+	}
+	return "", false
+}
+
+// rrsigTime formats a signature's validity time as YYYYMMDDHHmmSS
+func rrsigTime(t uint32) string {
+	return time.Unix(int64(t), 0).UTC().Format("20060102150405")
+}
+
+// reverseDNSName returns the name of the PTR records of an address
+func reverseDNSName(addr netip.Addr) string {
+	addr = addr.Unmap()
+	var b strings.Builder
+	if addr.Is4() {
+		ip := addr.As4()
+		for i := len(ip) - 1; i >= 0; i-- {
+			b.WriteString(strconv.Itoa(int(ip[i])) + ".")
+		}
+		b.WriteString("in-addr.arpa.")
+		return b.String()
+	}
+
+	const digits = "0123456789abcdef"
+	ip := addr.As16()
+	for i := len(ip) - 1; i >= 0; i-- {
+		b.WriteByte(digits[ip[i]&0x0f])
+		b.WriteByte('.')
+		b.WriteByte(digits[ip[i]>>4])
+		b.WriteByte('.')
+	}
+	b.WriteString("ip6.arpa.")
+	return b.String()
+}
@@ -0,0 +1,170 @@
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/NetScout-Go/NetTool/app/plugins/types"
+)
+
+// dnsLookupTypes are the record types the lookup offers
+var dnsLookupTypes = []string{"A", "AAAA", "MX", "TXT", "NS", "SOA", "CNAME", "SRV", "CAA", "PTR"}
+
+// dnsLookupDefinition describes the native DNS lookup, so it is listed without a plugin.json
+var dnsLookupDefinition = types.PluginDefinition{
+	ID:          "dns_lookup",
+	Name:        "DNS Lookup",
+	Description: "Queries a resolver over UDP, TCP, DNS over TLS or DNS over HTTPS and reports the records with their TTLs, flags and DNSSEC status",
+	Version:     "2.0.0",
+	Author:      "NetTool Team",
+	License:     "MIT",
+	Icon:        "dns",
+	Parameters: []types.PluginParam{
+		{ID: "domain", Name: "Domain", Description: "Name to look up; an IP address is looked up by its reverse name for PTR records", Type: types.TypeHostname, Required: true},
+		{ID: "recordType", Name: "Record Type", Description: "Type of the records to ask for", Type: types.TypeSelect, Default: "A", Options: dnsTypeOptions(dnsLookupTypes)},
+		{ID: "resolver", Name: "Resolver", Description: "Address of the resolver, a host for DoT, or a URL for DoH; the system's resolvers when empty", Type: types.TypeString},
+		{ID: "transport", Name: "Transport", Description: "How the query is sent", Type: types.TypeSelect, Default: dnsUDP, Options: []types.Option{
+			{Value: dnsUDP, Label: "UDP"},
+			{Value: dnsTCP, Label: "TCP"},
+			{Value: dnsDoT, Label: "DNS over TLS"},
+			{Value: dnsDoH, Label: "DNS over HTTPS"},
+		}},
+		{ID: "dnssec", Name: "DNSSEC", Description: "Ask for the DNSSEC signatures of the records", Type: types.TypeBoolean, Default: false},
+		{ID: "timeout", Name: "Timeout", Description: "Time to wait for each resolver", Type: types.TypeDuration, Default: "5s", Min: types.FloatPtr(0.1), Max: types.FloatPtr(60)},
+	},
+}
+
+// dnsTypeOptions returns the options of a record type parameter
+func dnsTypeOptions(names []string) []types.Option {
+	options := make([]types.Option, len(names))
+	for i, name := range names {
+		options[i] = types.Option{Value: name, Label: name}
+	}
+	return options
+}
+
+// dnsResolverError is a resolver that did not answer
+type dnsResolverError struct {
+	Resolver string `json:"resolver"`
+	Error    string `json:"error"`
+}
+
+// dnsLookupResult is the result of the native DNS lookup
+type dnsLookupResult struct {
+	Domain     string `json:"domain"`
+	Query      string `json:"query"` // name asked for, the reverse name for PTR lookups of addresses
+	RecordType string `json:"recordType"`
+	*dnsResponse
+	Failed []dnsResolverError `json:"failedResolvers,omitempty"` // system resolvers tried before the one that answered
+}
+
+// executeDNSLookup looks up the records of a name. Answers such as NXDOMAIN
+// are part of the result; only resolvers that do not answer are errors.
+func executeDNSLookup(ctx context.Context, params map[string]interface{}, emit func(types.Event)) (interface{}, error) {
+	domain, _ := params["domain"].(string)
+	domain = strings.TrimSpace(domain)
+	if domain == "" {
+		return nil, fmt.Errorf("domain parameter is required")
+	}
+
+	recordType := "A"
+	if t, ok := params["recordType"].(string); ok && t != "" {
+		recordType = strings.ToUpper(t)
+	}
+	typ, ok := dnsTypes[recordType]
+	if !ok {
+		return nil, fmt.Errorf("unknown record type %q", recordType)
+	}
+
+	query := dnsQuery{Name: domain, Type: typ, Transport: dnsUDP}
+	if addr, err := netip.ParseAddr(strings.Trim(domain, "[]")); err == nil && recordType == "PTR" {
+		query.Name = reverseDNSName(addr)
+	}
+	if transport, ok := params["transport"].(string); ok && transport != "" {
+		query.Transport = transport
+	}
+	query.DNSSEC, _ = params["dnssec"].(bool)
+
+	client := dnsClient{}
+	if timeout, ok := params["timeout"].(float64); ok && timeout > 0 {
+		client.Timeout = time.Duration(timeout * float64(time.Second))
+	}
+
+	resolvers := []string{}
+	if resolver, ok := params["resolver"].(string); ok && strings.TrimSpace(resolver) != "" {
+		resolvers = append(resolvers, strings.TrimSpace(resolver))
+	} else {
+		if query.Transport == dnsDoH {
+			return nil, fmt.Errorf("a DoH resolver URL is required")
+		}
+		if resolvers = systemDNSServers(); len(resolvers) == 0 {
+			return nil, ErrNoDNSServers
+		}
+	}
+
+	result := &dnsLookupResult{Domain: domain, Query: dnsFQDN(query.Name), RecordType: recordType}
+	for _, resolver := range resolvers {
+		query.Resolver = resolver
+		resp, err := client.Exchange(ctx, query)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			emit(types.Event{Type: types.EventLine, Line: fmt.Sprintf(";; %s: %v", resolver, err)})
+			result.Failed = append(result.Failed, dnsResolverError{Resolver: resolver, Error: err.Error()})
+			continue
+		}
+		result.dnsResponse = resp
+		break
+	}
+	if result.dnsResponse == nil {
+		return nil, fmt.Errorf("DNS lookup of %s failed: %s", domain, result.Failed[len(result.Failed)-1].Error)
+	}
+
+	for _, line := range formatDNSResponse(result.Query, recordType, result.dnsResponse) {
+		emit(types.Event{Type: types.EventLine, Line: line})
+	}
+	return result, nil
+}
+
+// formatDNSResponse renders a response as dig prints it
+func formatDNSResponse(name, recordType string, resp *dnsResponse) []string {
+	var flags []string
+	for _, flag := range []struct {
+		set  bool
+		name string
+	}{
+		{resp.Flags.Authoritative, "aa"}, {resp.Flags.Truncated, "tc"}, {resp.Flags.RecursionDesired, "rd"},
+		{resp.Flags.RecursionAvailable, "ra"}, {resp.Flags.AuthenticData, "ad"}, {resp.Flags.CheckingDisabled, "cd"},
+	} {
+		if flag.set {
+			flags = append(flags, flag.name)
+		}
+	}
+
+	lines := []string{
+		fmt.Sprintf(";; status: %s, flags: %s; ANSWER: %d, AUTHORITY: %d, ADDITIONAL: %d",
+			resp.Rcode, strings.Join(flags, " "), len(resp.Answers), len(resp.Authority), len(resp.Additional)),
+		";; QUESTION SECTION:",
+		fmt.Sprintf(";%s\t\tIN\t%s", name, recordType),
+	}
+	for _, section := range []struct {
+		name    string
+		records []dnsRecord
+	}{{"ANSWER", resp.Answers}, {"AUTHORITY", resp.Authority}, {"ADDITIONAL", resp.Additional}} {
+		if len(section.records) == 0 {
+			continue
+		}
+		lines = append(lines, "", ";; "+section.name+" SECTION:")
+		for _, r := range section.records {
+			lines = append(lines, fmt.Sprintf("%s\t%d\tIN\t%s\t%s", r.Name, r.TTL, r.Type, r.Data))
+		}
+	}
+	return append(lines, "",
+		fmt.Sprintf(";; Query time: %.3f ms", resp.Time),
+		fmt.Sprintf(";; SERVER: %s (%s)", resp.Resolver, strings.ToUpper(resp.Transport)),
+		fmt.Sprintf(";; MSG SIZE  rcvd: %d", resp.Size))
+}
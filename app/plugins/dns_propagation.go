@@ -0,0 +1,450 @@
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NetScout-Go/NetTool/app/plugins/types"
+)
+
+// maxPropagationResolvers limits the resolvers asked at once
+const maxPropagationResolvers = 50
+
+// publicDNSResolvers are asked when no resolvers are given, along with the
+// system's
+var publicDNSResolvers = []struct {
+	Address string
+	Name    string
+}{
+	{"1.1.1.1", "Cloudflare"},
+	{"8.8.8.8", "Google"},
+	{"9.9.9.9", "Quad9"},
+	{"208.67.222.222", "OpenDNS"},
+}
+
+// dnsPropagationDefinition describes the DNS propagation checker, so it is listed without a plugin.json
+var dnsPropagationDefinition = types.PluginDefinition{
+	ID:          "dns_propagation",
+	Name:        "DNS Propagation",
+	Description: "Asks many resolvers for the same records at once and groups them by the answers they give, to follow a DNS change",
+	Version:     "2.0.0",
+	Author:      "NetTool Team",
+	License:     "MIT",
+	Icon:        "dns",
+	Timeout:     86400, // polling until the resolvers agree
+	Parameters: []types.PluginParam{
+		{ID: "domain", Name: "Domain", Description: "Name whose records are compared", Type: types.TypeHostname, Required: true},
+		{ID: "recordType", Name: "Record Type", Description: "Type of the records to compare", Type: types.TypeSelect, Default: "A", Options: dnsTypeOptions(dnsLookupTypes)},
+		{ID: "resolvers", Name: "Resolvers", Description: "Resolvers to ask, separated by commas; public resolvers and the system's when empty", Type: types.TypeString},
+		{ID: "expected", Name: "Expected Answer", Description: "Records the resolvers should answer with once the change propagated, separated by commas; optional", Type: types.TypeString},
+		{ID: "transport", Name: "Transport", Description: "How the queries are sent", Type: types.TypeSelect, Default: dnsUDP, Options: []types.Option{
+			{Value: dnsUDP, Label: "UDP"},
+			{Value: dnsTCP, Label: "TCP"},
+			{Value: dnsDoT, Label: "DNS over TLS"},
+			{Value: dnsDoH, Label: "DNS over HTTPS"},
+		}},
+		{ID: "timeout", Name: "Timeout", Description: "Time to wait for each resolver", Type: types.TypeDuration, Default: "5s", Min: types.FloatPtr(0.1), Max: types.FloatPtr(60)},
+		{ID: "interval", Name: "Poll Interval", Description: "Time between checks while iterating", Type: types.TypeDuration, Default: "30s", Min: types.FloatPtr(1), Max: types.FloatPtr(3600)},
+		{ID: "deadline", Name: "Deadline", Description: "Time after which iterating stops, even if the resolvers disagree", Type: types.TypeDuration, Default: "10m", Min: types.FloatPtr(1), Max: types.FloatPtr(86400)},
+		types.CreateIterationParams(),
+	},
+}
+
+// propagationOptions are the parameters of a propagation check
+type propagationOptions struct {
+	Domain     string
+	RecordType string
+	Query      dnsQuery
+	Resolvers  []propagationTarget
+	Expected   []string
+	Client     dnsClient
+	Interval   time.Duration
+	Deadline   time.Duration
+	Iterate    bool
+}
+
+// propagationTarget is a resolver to ask
+type propagationTarget struct {
+	Address string
+	Name    string
+}
+
+// propagationAnswer is what one resolver answered
+type propagationAnswer struct {
+	Resolver string   `json:"resolver"`
+	Name     string   `json:"name,omitempty"`
+	Rcode    string   `json:"rcode,omitempty"`
+	Answers  []string `json:"answers"`
+	TTL      uint32   `json:"ttl"` // lowest TTL of the answers, the time until the resolver asks again
+	Time     float64  `json:"timeMs,omitempty"`
+	Error    string   `json:"error,omitempty"`
+	Group    int      `json:"group"`             // index in groups, -1 for resolvers that did not answer
+	Matches  *bool    `json:"matches,omitempty"` // answers are the expected ones
+}
+
+// propagationGroup is a set of resolvers giving the same answer
+type propagationGroup struct {
+	Rcode     string   `json:"rcode"`
+	Answers   []string `json:"answers"`
+	Resolvers []string `json:"resolvers"`
+	MinTTL    uint32   `json:"minTtl"`
+	MaxTTL    uint32   `json:"maxTtl"`
+	Expected  bool     `json:"expected,omitempty"`
+}
+
+// propagationResult is the result of a propagation check
+type propagationResult struct {
+	Domain     string              `json:"domain"`
+	RecordType string              `json:"recordType"`
+	Transport  string              `json:"transport"`
+	Expected   []string            `json:"expected,omitempty"`
+	Resolvers  []propagationAnswer `json:"resolvers"`
+	Groups     []propagationGroup  `json:"groups"` // largest first
+	Failed     int                 `json:"failed"` // resolvers that did not answer
+	Consistent bool                `json:"consistent"`
+	Propagated int                 `json:"propagated,omitempty"` // resolvers with the expected answers
+	Complete   bool                `json:"complete"`             // consistent, with the expected answers if given
+	Divergent  []string            `json:"divergent"`            // resolvers outside the largest group, or without the expected answers
+	// SettlesWithin is the highest TTL of the divergent resolvers' answers,
+	// after which they ask again, in seconds
+	SettlesWithin uint32  `json:"settlesWithin"`
+	Polls         int     `json:"polls"`
+	Duration      float64 `json:"durationMs"`
+}
+
+// executeDNSPropagation compares the answers of many resolvers. With
+// continueToIterate set it checks again every interval, until the check is
+// complete or the deadline passes.
+func executeDNSPropagation(ctx context.Context, params map[string]interface{}, emit func(types.Event)) (interface{}, error) {
+	opts, err := parsePropagationParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	started := time.Now()
+	deadline := started.Add(opts.Deadline)
+	for poll := 1; ; poll++ {
+		result := checkPropagation(ctx, opts)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		result.Polls = poll
+		result.Duration = float64(time.Since(started).Microseconds()) / 1000
+
+		if !opts.Iterate || result.Complete || time.Now().Add(opts.Interval).After(deadline) {
+			for _, line := range formatPropagation(result) {
+				emit(types.Event{Type: types.EventLine, Line: line})
+			}
+			return result, nil
+		}
+
+		emit(types.Event{Type: types.EventPartial, Data: result})
+		emit(types.Event{Type: types.EventLine, Line: fmt.Sprintf("poll %d: %d of %d resolvers diverge, checking again in %s", poll, len(result.Divergent), len(result.Resolvers), opts.Interval)})
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(opts.Interval):
+		}
+	}
+}
+
+// parsePropagationParams reads the parameters of a propagation check
+func parsePropagationParams(params map[string]interface{}) (propagationOptions, error) {
+	opts := propagationOptions{
+		RecordType: "A",
+		Interval:   30 * time.Second,
+		Deadline:   10 * time.Minute,
+	}
+
+	domain, _ := params["domain"].(string)
+	opts.Domain = strings.TrimSpace(domain)
+	if opts.Domain == "" {
+		return opts, fmt.Errorf("domain parameter is required")
+	}
+	if t, ok := params["recordType"].(string); ok && t != "" {
+		opts.RecordType = strings.ToUpper(t)
+	}
+	typ, ok := dnsTypes[opts.RecordType]
+	if !ok {
+		return opts, fmt.Errorf("unknown record type %q", opts.RecordType)
+	}
+
+	opts.Query = dnsQuery{Name: opts.Domain, Type: typ, Transport: dnsUDP}
+	if addr, err := netip.ParseAddr(strings.Trim(opts.Domain, "[]")); err == nil && opts.RecordType == "PTR" {
+		opts.Query.Name = reverseDNSName(addr)
+	}
+	if transport, ok := params["transport"].(string); ok && transport != "" {
+		opts.Query.Transport = transport
+	}
+
+	if resolvers, ok := params["resolvers"].(string); ok && strings.TrimSpace(resolvers) != "" {
+		for _, resolver := range splitList(resolvers) {
+			opts.Resolvers = append(opts.Resolvers, propagationTarget{Address: resolver, Name: publicResolverName(resolver)})
+		}
+	} else {
+		for _, resolver := range publicDNSResolvers {
+			opts.Resolvers = append(opts.Resolvers, propagationTarget{Address: resolver.Address, Name: resolver.Name})
+		}
+		if opts.Query.Transport == dnsUDP || opts.Query.Transport == dnsTCP {
+			for _, resolver := range systemDNSServers() {
+				opts.Resolvers = append(opts.Resolvers, propagationTarget{Address: resolver, Name: "System"})
+			}
+		}
+	}
+	if len(opts.Resolvers) > maxPropagationResolvers {
+		return opts, fmt.Errorf("at most %d resolvers can be compared", maxPropagationResolvers)
+	}
+
+	if expected, ok := params["expected"].(string); ok {
+		for _, answer := range splitList(expected) {
+			opts.Expected = append(opts.Expected, normalizeAnswer(opts.RecordType, answer))
+		}
+		sort.Strings(opts.Expected)
+	}
+
+	if timeout, ok := params["timeout"].(float64); ok && timeout > 0 {
+		opts.Client.Timeout = time.Duration(timeout * float64(time.Second))
+	}
+	if interval, ok := params["interval"].(float64); ok && interval > 0 {
+		opts.Interval = time.Duration(interval * float64(time.Second))
+	}
+	if deadline, ok := params["deadline"].(float64); ok && deadline > 0 {
+		opts.Deadline = time.Duration(deadline * float64(time.Second))
+	}
+	opts.Iterate, _ = params["continueToIterate"].(bool)
+	return opts, nil
+}
+
+// splitList splits a list separated by commas or white space
+func splitList(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+	})
+}
+
+// publicResolverName returns the operator of a well-known resolver
+func publicResolverName(address string) string {
+	for _, resolver := range publicDNSResolvers {
+		if resolver.Address == address {
+			return resolver.Name
+		}
+	}
+	return ""
+}
+
+// checkPropagation asks every resolver at once and compares their answers
+func checkPropagation(ctx context.Context, opts propagationOptions) *propagationResult {
+	answers := make([]propagationAnswer, len(opts.Resolvers))
+	var wg sync.WaitGroup
+	for i, target := range opts.Resolvers {
+		wg.Add(1)
+		go func(i int, target propagationTarget) {
+			defer wg.Done()
+			query := opts.Query
+			query.Resolver = target.Address
+			answers[i] = askResolver(ctx, &opts.Client, query, opts.RecordType, target)
+		}(i, target)
+	}
+	wg.Wait()
+
+	result := &propagationResult{
+		Domain:     opts.Domain,
+		RecordType: opts.RecordType,
+		Transport:  opts.Query.Transport,
+		Expected:   opts.Expected,
+		Resolvers:  answers,
+		Groups:     []propagationGroup{},
+		Divergent:  []string{},
+	}
+	groupAnswers(result)
+	return result
+}
+
+// askResolver asks one resolver for the records
+func askResolver(ctx context.Context, client *dnsClient, query dnsQuery, recordType string, target propagationTarget) propagationAnswer {
+	answer := propagationAnswer{Resolver: target.Address, Name: target.Name, Answers: []string{}, Group: -1}
+	resp, err := client.Exchange(ctx, query)
+	if err != nil {
+		answer.Error = err.Error()
+		return answer
+	}
+
+	answer.Rcode = resp.Rcode
+	answer.Time = resp.Time
+	for _, record := range resp.Answers {
+		// CNAMEs leading to the records are part of the answer
+		if record.Type != recordType && record.Type != "CNAME" {
+			continue
+		}
+		answer.Answers = append(answer.Answers, normalizeAnswer(record.Type, record.Data))
+		if answer.TTL == 0 || record.TTL < answer.TTL {
+			answer.TTL = record.TTL
+		}
+	}
+	sort.Strings(answer.Answers)
+	return answer
+}
+
+// normalizeAnswer returns the data of a record in a form that compares equal
+// for equal records: addresses in their canonical form, names in lower case
+// without the final dot, and single TXT strings without quotes
+func normalizeAnswer(recordType, data string) string {
+	data = strings.TrimSpace(data)
+	if addr, err := netip.ParseAddr(data); err == nil {
+		return addr.String()
+	}
+	switch recordType {
+	case "TXT", "CAA":
+		if s, err := strconv.Unquote(data); err == nil {
+			return s
+		}
+		return data
+	}
+	return strings.TrimSuffix(strings.ToLower(data), ".")
+}
+
+// groupAnswers groups the resolvers by their answers and finds the ones that
+// diverge
+func groupAnswers(result *propagationResult) {
+	index := make(map[string]int)
+	for i := range result.Resolvers {
+		answer := &result.Resolvers[i]
+		if answer.Error != "" {
+			result.Failed++
+			continue
+		}
+		key := answer.Rcode + "\x00" + strings.Join(answer.Answers, "\x00")
+		g, ok := index[key]
+		if !ok {
+			g = len(result.Groups)
+			index[key] = g
+			result.Groups = append(result.Groups, propagationGroup{Rcode: answer.Rcode, Answers: answer.Answers, MinTTL: answer.TTL, MaxTTL: answer.TTL})
+		}
+		group := &result.Groups[g]
+		group.Resolvers = append(group.Resolvers, answer.Resolver)
+		group.MinTTL = min(group.MinTTL, answer.TTL)
+		group.MaxTTL = max(group.MaxTTL, answer.TTL)
+	}
+
+	// Largest group first, keeping the order of the resolvers among equals
+	order := make([]int, len(result.Groups))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return len(result.Groups[order[a]].Resolvers) > len(result.Groups[order[b]].Resolvers)
+	})
+	groups := make([]propagationGroup, len(order))
+	position := make([]int, len(order))
+	for i, g := range order {
+		groups[i] = result.Groups[g]
+		position[g] = i
+	}
+	result.Groups = groups
+	for key, g := range index {
+		index[key] = position[g]
+	}
+
+	expected := strings.Join(result.Expected, "\x00")
+	for i := range result.Resolvers {
+		answer := &result.Resolvers[i]
+		if answer.Error != "" {
+			result.Divergent = append(result.Divergent, answer.Resolver)
+			continue
+		}
+		answer.Group = index[answer.Rcode+"\x00"+strings.Join(answer.Answers, "\x00")]
+
+		diverges := answer.Group != 0
+		if result.Expected != nil {
+			matches := answer.Rcode == "NOERROR" && strings.Join(answer.Answers, "\x00") == expected
+			answer.Matches = &matches
+			result.Groups[answer.Group].Expected = matches
+			diverges = !matches
+			if matches {
+				result.Propagated++
+			}
+		}
+		if diverges {
+			result.Divergent = append(result.Divergent, answer.Resolver)
+			result.SettlesWithin = max(result.SettlesWithin, answer.TTL)
+		}
+	}
+
+	result.Consistent = result.Failed == 0 && len(result.Groups) == 1
+	result.Complete = result.Consistent
+	if result.Expected != nil {
+		result.Complete = result.Propagated == len(result.Resolvers)
+	}
+}
+
+// formatPropagation renders the answers of the resolvers, one per line
+func formatPropagation(result *propagationResult) []string {
+	lines := make([]string, 0, len(result.Resolvers)+1)
+	for _, answer := range result.Resolvers {
+		resolver := answer.Resolver
+		if answer.Name != "" {
+			resolver += " (" + answer.Name + ")"
+		}
+		switch {
+		case answer.Error != "":
+			lines = append(lines, fmt.Sprintf("%-32s error: %s", resolver, answer.Error))
+		case len(answer.Answers) == 0:
+			lines = append(lines, fmt.Sprintf("%-32s %s, no records", resolver, answer.Rcode))
+		default:
+			lines = append(lines, fmt.Sprintf("%-32s %s (TTL %d)", resolver, strings.Join(answer.Answers, ", "), answer.TTL))
+		}
+	}
+
+	switch {
+	case result.Complete:
+		lines = append(lines, fmt.Sprintf("all %d resolvers agree", len(result.Resolvers)))
+	default:
+		lines = append(lines, fmt.Sprintf("%d of %d resolvers diverge in %d groups; their answers expire within %ds",
+			len(result.Divergent), len(result.Resolvers), len(result.Groups), result.SettlesWithin))
+	}
+	return lines
+}
+
+// dnsPropagationIterator checks the propagation for the iterate command, once
+// per iteration, until the check is complete or the deadline passes
+type dnsPropagationIterator struct {
+	types.BaseIterablePlugin
+	started time.Time
+	polls   int
+}
+
+// newDNSPropagationIterator creates the iterable propagation checker
+func newDNSPropagationIterator(definition types.PluginDefinition) types.IterablePlugin {
+	it := &dnsPropagationIterator{}
+	it.Definition = definition
+	it.SupportsIterationFlag = true
+	it.ExecuteFunc = func(params map[string]interface{}) (interface{}, error) {
+		return executeDNSPropagation(context.Background(), params, func(types.Event) {})
+	}
+	return it
+}
+
+// ExecuteIteration checks once and continues while the resolvers diverge
+// and the deadline has not passed
+func (it *dnsPropagationIterator) ExecuteIteration(params map[string]interface{}, iterationCount int) (interface{}, bool, error) {
+	opts, err := parsePropagationParams(params)
+	if err != nil {
+		return nil, false, err
+	}
+	if iterationCount == 0 || it.started.IsZero() {
+		it.started = time.Now()
+		it.polls = 0
+	}
+
+	result := checkPropagation(context.Background(), opts)
+	it.polls++
+	result.Polls = it.polls
+	result.Duration = float64(time.Since(it.started).Microseconds()) / 1000
+	return result, !result.Complete && time.Since(it.started) < opts.Deadline, nil
+}
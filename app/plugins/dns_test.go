@@ -0,0 +1,453 @@
+package plugins
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/NetScout-Go/NetTool/app/plugins/types"
+)
+
+// fakeDNSHandler answers a query received over transport; a nil answer is
+// dropped, leaving the client to time out
+type fakeDNSHandler func(query dnsmessage.Message, transport string) *dnsmessage.Message
+
+// fakeDNSServer answers queries over UDP and TCP on the same port of 127.0.0.1
+type fakeDNSServer struct {
+	Addr string
+
+	mu      sync.Mutex
+	queries []dnsmessage.Message
+}
+
+// newFakeDNSServer starts a server answering with handler until the test ends
+func newFakeDNSServer(t *testing.T, handler fakeDNSHandler) *fakeDNSServer {
+	t.Helper()
+
+	// The TCP fallback goes to the port of the UDP resolver, so both
+	// listeners need the same one
+	var pc net.PacketConn
+	var ln net.Listener
+	for attempt := 0; ln == nil; attempt++ {
+		var err error
+		if pc, err = net.ListenPacket("udp", "127.0.0.1:0"); err != nil {
+			t.Fatalf("listen udp: %v", err)
+		}
+		if ln, err = net.Listen("tcp", pc.LocalAddr().String()); err != nil {
+			pc.Close()
+			if attempt == 10 {
+				t.Fatalf("listen tcp: %v", err)
+			}
+		}
+	}
+	t.Cleanup(func() {
+		pc.Close()
+		ln.Close()
+	})
+
+	s := &fakeDNSServer{Addr: pc.LocalAddr().String()}
+	go func() {
+		buf := make([]byte, maxDNSMessageSize)
+		for {
+			n, addr, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if answer := s.answer(t, buf[:n], dnsUDP, handler); answer != nil {
+				pc.WriteTo(answer, addr)
+			}
+		}
+	}()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				var length [2]byte
+				if _, err := io.ReadFull(conn, length[:]); err != nil {
+					return
+				}
+				msg := make([]byte, binary.BigEndian.Uint16(length[:]))
+				if _, err := io.ReadFull(conn, msg); err != nil {
+					return
+				}
+				answer := s.answer(t, msg, dnsTCP, handler)
+				if answer == nil {
+					// Hold the connection open, as a silent resolver does
+					io.Copy(io.Discard, conn)
+					return
+				}
+				binary.BigEndian.PutUint16(length[:], uint16(len(answer)))
+				conn.Write(append(length[:], answer...))
+			}()
+		}
+	}()
+	return s
+}
+
+// answer parses a query, records it, and packs the handler's answer
+func (s *fakeDNSServer) answer(t *testing.T, msg []byte, transport string, handler fakeDNSHandler) []byte {
+	var query dnsmessage.Message
+	if err := query.Unpack(msg); err != nil {
+		t.Errorf("server received a malformed query: %v", err)
+		return nil
+	}
+	s.mu.Lock()
+	s.queries = append(s.queries, query)
+	s.mu.Unlock()
+
+	answer := handler(query, transport)
+	if answer == nil {
+		return nil
+	}
+	answer.Header.ID = query.Header.ID
+	answer.Header.Response = true
+	answer.Header.RecursionDesired = query.Header.RecursionDesired
+	answer.Questions = query.Questions
+	packed, err := answer.Pack()
+	if err != nil {
+		t.Errorf("pack answer: %v", err)
+		return nil
+	}
+	return packed
+}
+
+// Queries returns the queries received so far
+func (s *fakeDNSServer) Queries() []dnsmessage.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]dnsmessage.Message(nil), s.queries...)
+}
+
+// aRecord returns an A record of name
+func aRecord(name string, ttl uint32, addr string) dnsmessage.Resource {
+	return dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(name), Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: ttl},
+		Body:   &dnsmessage.AResource{A: netip.MustParseAddr(addr).As4()},
+	}
+}
+
+// cannedDNS answers the names of the tests:
+//
+//	a.test.     A 192.0.2.1, validated and authoritative
+//	nx.test.    NXDOMAIN with the SOA of the zone
+//	fail.test.  SERVFAIL
+//	big.test.   truncated over UDP, three A records over TCP
+//	slow.test.  never answered
+func cannedDNS(query dnsmessage.Message, transport string) *dnsmessage.Message {
+	name := query.Questions[0].Name.String()
+	answer := &dnsmessage.Message{Header: dnsmessage.Header{RecursionAvailable: true}}
+	switch name {
+	case "a.test.":
+		answer.Header.Authoritative = true
+		answer.Header.AuthenticData = true
+		answer.Answers = []dnsmessage.Resource{aRecord(name, 300, "192.0.2.1")}
+	case "nx.test.":
+		answer.Header.RCode = dnsmessage.RCodeNameError
+		answer.Authorities = []dnsmessage.Resource{{
+			Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName("test."), Type: dnsmessage.TypeSOA, Class: dnsmessage.ClassINET, TTL: 900},
+			Body: &dnsmessage.SOAResource{
+				NS: dnsmessage.MustNewName("ns.test."), MBox: dnsmessage.MustNewName("hostmaster.test."),
+				Serial: 2024010101, Refresh: 3600, Retry: 600, Expire: 86400, MinTTL: 60,
+			},
+		}}
+	case "fail.test.":
+		answer.Header.RCode = dnsmessage.RCodeServerFailure
+	case "big.test.":
+		if transport == dnsUDP {
+			answer.Header.Truncated = true
+			break
+		}
+		for _, addr := range []string{"192.0.2.10", "192.0.2.11", "192.0.2.12"} {
+			answer.Answers = append(answer.Answers, aRecord(name, 60, addr))
+		}
+	case "slow.test.":
+		return nil
+	default:
+		answer.Header.RCode = dnsmessage.RCodeRefused
+	}
+	return answer
+}
+
+func TestDNSClientAnswers(t *testing.T) {
+	server := newFakeDNSServer(t, cannedDNS)
+	client := dnsClient{Timeout: 2 * time.Second}
+
+	tests := []struct {
+		name      string
+		transport string
+		rcode     string
+		flags     dnsFlags
+		dnssec    string
+		answers   []string
+		ttl       uint32
+		authority int
+	}{
+		{
+			name: "a.test", transport: dnsUDP, rcode: "NOERROR",
+			flags:  dnsFlags{Authoritative: true, RecursionDesired: true, RecursionAvailable: true, AuthenticData: true},
+			dnssec: dnssecSecure, answers: []string{"192.0.2.1"}, ttl: 300,
+		},
+		{
+			name: "a.test", transport: dnsTCP, rcode: "NOERROR",
+			flags:  dnsFlags{Authoritative: true, RecursionDesired: true, RecursionAvailable: true, AuthenticData: true},
+			dnssec: dnssecSecure, answers: []string{"192.0.2.1"}, ttl: 300,
+		},
+		{
+			name: "nx.test", transport: dnsUDP, rcode: "NXDOMAIN",
+			flags:  dnsFlags{RecursionDesired: true, RecursionAvailable: true},
+			dnssec: dnssecInsecure, authority: 1,
+		},
+		{
+			name: "fail.test", transport: dnsUDP, rcode: "SERVFAIL",
+			flags:  dnsFlags{RecursionDesired: true, RecursionAvailable: true},
+			dnssec: dnssecInsecure,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name+"/"+tt.transport, func(t *testing.T) {
+			resp, err := client.Exchange(context.Background(), dnsQuery{Name: tt.name, Type: dnsmessage.TypeA, Resolver: server.Addr, Transport: tt.transport})
+			if err != nil {
+				t.Fatalf("Exchange: %v", err)
+			}
+			if resp.Rcode != tt.rcode {
+				t.Errorf("rcode = %s, want %s", resp.Rcode, tt.rcode)
+			}
+			if resp.Flags != tt.flags {
+				t.Errorf("flags = %+v, want %+v", resp.Flags, tt.flags)
+			}
+			if resp.DNSSEC != tt.dnssec {
+				t.Errorf("dnssec = %s, want %s", resp.DNSSEC, tt.dnssec)
+			}
+			if resp.Transport != tt.transport || resp.TCPFallback {
+				t.Errorf("transport = %s, fallback %v, want %s without fallback", resp.Transport, resp.TCPFallback, tt.transport)
+			}
+			if len(resp.Answers) != len(tt.answers) {
+				t.Fatalf("got %d answers, want %d", len(resp.Answers), len(tt.answers))
+			}
+			for i, record := range resp.Answers {
+				if record.Data != tt.answers[i] || record.TTL != tt.ttl || record.Type != "A" {
+					t.Errorf("answer %d = %s %d %s, want A %d %s", i, record.Type, record.TTL, record.Data, tt.ttl, tt.answers[i])
+				}
+			}
+			if len(resp.Authority) != tt.authority {
+				t.Errorf("got %d authority records, want %d", len(resp.Authority), tt.authority)
+			}
+		})
+	}
+}
+
+func TestDNSClientQuery(t *testing.T) {
+	server := newFakeDNSServer(t, cannedDNS)
+	client := dnsClient{Timeout: 2 * time.Second}
+
+	if _, err := client.Exchange(context.Background(), dnsQuery{Name: "a.test", Type: dnsmessage.TypeA, Resolver: server.Addr, DNSSEC: true}); err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if _, err := client.Exchange(context.Background(), dnsQuery{Name: "a.test", Type: dnsmessage.TypeA, Resolver: server.Addr, NoRecurse: true}); err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+
+	queries := server.Queries()
+	if len(queries) != 2 {
+		t.Fatalf("server received %d queries, want 2", len(queries))
+	}
+	for i, want := range []struct{ rd, do bool }{{true, true}, {false, false}} {
+		query := queries[i]
+		if query.Header.RecursionDesired != want.rd {
+			t.Errorf("query %d: RD = %v, want %v", i, query.Header.RecursionDesired, want.rd)
+		}
+		if !query.Header.AuthenticData {
+			t.Errorf("query %d: AD is not set", i)
+		}
+		if len(query.Additionals) != 1 || query.Additionals[0].Header.Type != dnsmessage.TypeOPT {
+			t.Fatalf("query %d has no EDNS0 record", i)
+		}
+		if do := query.Additionals[0].Header.DNSSECAllowed(); do != want.do {
+			t.Errorf("query %d: DO = %v, want %v", i, do, want.do)
+		}
+	}
+}
+
+func TestDNSClientTruncatedFallsBackToTCP(t *testing.T) {
+	server := newFakeDNSServer(t, cannedDNS)
+	client := dnsClient{Timeout: 2 * time.Second}
+
+	resp, err := client.Exchange(context.Background(), dnsQuery{Name: "big.test", Type: dnsmessage.TypeA, Resolver: server.Addr})
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if !resp.TCPFallback {
+		t.Error("the truncated answer was not asked again over TCP")
+	}
+	if resp.Flags.Truncated {
+		t.Error("the TCP answer is marked truncated")
+	}
+	if len(resp.Answers) != 3 {
+		t.Errorf("got %d answers, want the 3 of the TCP answer", len(resp.Answers))
+	}
+}
+
+func TestDNSClientTimeout(t *testing.T) {
+	server := newFakeDNSServer(t, cannedDNS)
+
+	for _, transport := range []string{dnsUDP, dnsTCP} {
+		t.Run(transport, func(t *testing.T) {
+			client := dnsClient{Timeout: 200 * time.Millisecond}
+			started := time.Now()
+			_, err := client.Exchange(context.Background(), dnsQuery{Name: "slow.test", Type: dnsmessage.TypeA, Resolver: server.Addr, Transport: transport})
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("Exchange error = %v, want a timeout", err)
+			}
+			if !strings.Contains(err.Error(), server.Addr) {
+				t.Errorf("error %q does not name the resolver", err)
+			}
+			if elapsed := time.Since(started); elapsed > 2*time.Second {
+				t.Errorf("Exchange took %s with a timeout of %s", elapsed, client.Timeout)
+			}
+		})
+	}
+
+	t.Run("cancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(100*time.Millisecond, cancel)
+		client := dnsClient{Timeout: 5 * time.Second}
+		_, err := client.Exchange(ctx, dnsQuery{Name: "slow.test", Type: dnsmessage.TypeA, Resolver: server.Addr})
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("Exchange error = %v, want cancellation", err)
+		}
+	})
+}
+
+func TestDNSClientDoH(t *testing.T) {
+	var contentType string
+	// DoH needs https, so the test server uses TLS and the client trusts it
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/dns-query" || r.Header.Get("Content-Type") != dohContentType {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		var query dnsmessage.Message
+		if err := query.Unpack(body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if query.Header.ID != 0 {
+			http.Error(w, "DoH queries use ID 0", http.StatusBadRequest)
+			return
+		}
+		answer := cannedDNS(query, dnsDoH)
+		answer.Header.Response = true
+		answer.Header.RecursionDesired = query.Header.RecursionDesired
+		answer.Questions = query.Questions
+		packed, err := answer.Pack()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Write(packed)
+	}))
+	defer server.Close()
+	client := dnsClient{Timeout: 2 * time.Second, HTTPClient: server.Client()}
+
+	contentType = dohContentType
+	resp, err := client.Exchange(context.Background(), dnsQuery{Name: "a.test", Type: dnsmessage.TypeA, Resolver: server.URL + "/dns-query", Transport: dnsDoH})
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if resp.Rcode != "NOERROR" || len(resp.Answers) != 1 || resp.Answers[0].Data != "192.0.2.1" || resp.Answers[0].TTL != 300 {
+		t.Errorf("answer = %s %+v, want NOERROR with A 300 192.0.2.1", resp.Rcode, resp.Answers)
+	}
+	if resp.Transport != dnsDoH {
+		t.Errorf("transport = %s, want %s", resp.Transport, dnsDoH)
+	}
+
+	contentType = "text/html"
+	if _, err := client.Exchange(context.Background(), dnsQuery{Name: "a.test", Type: dnsmessage.TypeA, Resolver: server.URL + "/dns-query", Transport: dnsDoH}); err == nil {
+		t.Error("an answer that is not a DNS message was accepted")
+	}
+	if _, err := client.Exchange(context.Background(), dnsQuery{Name: "a.test", Type: dnsmessage.TypeA, Resolver: server.URL + "/other", Transport: dnsDoH}); err == nil {
+		t.Error("an HTTP error was accepted")
+	}
+	if _, err := client.Exchange(context.Background(), dnsQuery{Name: "a.test", Type: dnsmessage.TypeA, Resolver: strings.Replace(server.URL, "https", "http", 1), Transport: dnsDoH}); err == nil {
+		t.Error("a DoH resolver without https was accepted")
+	}
+}
+
+func TestExecuteDNSLookup(t *testing.T) {
+	server := newFakeDNSServer(t, cannedDNS)
+
+	var lines []string
+	result, err := executeDNSLookup(context.Background(), map[string]interface{}{
+		"domain": "big.test", "recordType": "A", "resolver": server.Addr, "timeout": 2.0,
+	}, func(e types.Event) {
+		if e.Type == types.EventLine {
+			lines = append(lines, e.Line)
+		}
+	})
+	if err != nil {
+		t.Fatalf("executeDNSLookup: %v", err)
+	}
+	lookup := result.(*dnsLookupResult)
+	if lookup.Query != "big.test." || lookup.RecordType != "A" || len(lookup.Answers) != 3 || !lookup.TCPFallback {
+		t.Errorf("result = %s %s with %d answers, fallback %v; want big.test. A with 3 answers over TCP",
+			lookup.Query, lookup.RecordType, len(lookup.Answers), lookup.TCPFallback)
+	}
+	if len(lines) == 0 || !strings.HasPrefix(lines[0], ";; status: NOERROR") {
+		t.Errorf("output starts with %q, want the status line", lines)
+	}
+
+	// Answers such as NXDOMAIN are results, silent resolvers are errors
+	result, err = executeDNSLookup(context.Background(), map[string]interface{}{
+		"domain": "nx.test", "resolver": server.Addr,
+	}, func(types.Event) {})
+	if err != nil || result.(*dnsLookupResult).Rcode != "NXDOMAIN" {
+		t.Errorf("lookup of nx.test = %v, %v; want an NXDOMAIN result", result, err)
+	}
+	if _, err := executeDNSLookup(context.Background(), map[string]interface{}{
+		"domain": "slow.test", "resolver": server.Addr, "timeout": 0.2,
+	}, func(types.Event) {}); err == nil || !strings.Contains(err.Error(), "no answer") {
+		t.Errorf("lookup of slow.test error = %v, want a timeout", err)
+	}
+}
+
+func TestUnknownRecordDataCAA(t *testing.T) {
+	caa := func(tagLen byte, rest string) []byte { return append([]byte{0, tagLen}, rest...) }
+	longTag := strings.Repeat("t", 255)
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{name: "issue", data: caa(5, "issueletsencrypt.org"), want: `0 issue "letsencrypt.org"`},
+		{name: "empty value", data: caa(9, "issuewild"), want: `0 issuewild ""`},
+		{name: "longest tag", data: caa(255, longTag+"v"), want: `0 ` + longTag + ` "v"`},
+		// Malformed records are shown in the generic format
+		{name: "tag past the end", data: caa(255, "issue"), want: `\# 7 00ff6973737565`},
+		{name: "no tag length", data: []byte{0}, want: `\# 1 00`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, _ := unknownRecordData(dnsTypeCAA, tt.data); got != tt.want {
+				t.Errorf("unknownRecordData = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,398 @@
+package plugins
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/NetScout-Go/NetTool/app/plugins/types"
+)
+
+// JobState is the lifecycle state of a plugin job
+type JobState string
+
+const (
+	// JobQueued is the state of a job waiting for a free worker.
+	JobQueued JobState = "queued"
+	// JobRunning is the state of a job whose plugin is executing.
+	JobRunning JobState = "running"
+	// JobSucceeded is the state of a job whose plugin returned a result.
+	JobSucceeded JobState = "succeeded"
+	// JobFailed is the state of a job whose plugin returned an error.
+	JobFailed JobState = "failed"
+	// JobCancelled is the state of a job cancelled before it finished.
+	JobCancelled JobState = "cancelled"
+)
+
+// Finished reports whether the state is final
+func (s JobState) Finished() bool {
+	return s == JobSucceeded || s == JobFailed || s == JobCancelled
+}
+
+// Limits of the job manager
+const (
+	defaultMaxConcurrentJobs = 4
+	finishedJobRetention     = time.Hour
+	maxFinishedJobs          = 100
+	maxJobEvents             = 1000 // events kept per job, the oldest are dropped first
+)
+
+// Job errors
+var (
+	ErrJobNotFound = errors.New("job not found")
+	ErrJobFinished = errors.New("job already finished")
+)
+
+// Job is a plugin run submitted to the job manager
+type Job struct {
+	ID         string                 `json:"id"`
+	PluginID   string                 `json:"pluginId"`
+	Params     map[string]interface{} `json:"params"`
+	State      JobState               `json:"state"`
+	Progress   float64                `json:"progress"`          // percentage, 100 once finished
+	Partial    interface{}            `json:"partial,omitempty"` // latest partial result of a streaming plugin
+	Result     interface{}            `json:"result,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+	CreatedAt  time.Time              `json:"createdAt"`
+	StartedAt  *time.Time             `json:"startedAt,omitempty"`
+	FinishedAt *time.Time             `json:"finishedAt,omitempty"`
+	EventCount int                    `json:"eventCount"` // events emitted so far, see JobManager.Events
+}
+
+// JobEvent is an event emitted by a job's plugin, numbered from 1 in order
+type JobEvent struct {
+	Seq  int       `json:"seq"`
+	Time time.Time `json:"time"`
+	types.Event
+}
+
+// JobFilter selects jobs from the job list; empty fields match every job
+type JobFilter struct {
+	PluginID string
+	State    JobState
+}
+
+// JobManager runs plugins in the background, a limited number at a time, and
+// keeps their state and result for later retrieval
+type JobManager struct {
+	plugins *PluginManager
+	slots   chan struct{}
+
+	mu       sync.Mutex
+	jobs     map[string]*Job
+	events   map[string][]JobEvent
+	cancels  map[string]context.CancelFunc // of unfinished jobs
+	onEvent  func(Job)
+	onOutput func(string, JobEvent)
+}
+
+// NewJobManager creates a job manager running plugins of pm, at most
+// maxConcurrent at a time. A maxConcurrent of zero selects the default.
+func NewJobManager(pm *PluginManager, maxConcurrent int) *JobManager {
+	if maxConcurrent <= 0 {
+		maxConcurrent = defaultMaxConcurrentJobs
+	}
+	return &JobManager{
+		plugins: pm,
+		slots:   make(chan struct{}, maxConcurrent),
+		jobs:    make(map[string]*Job),
+		events:  make(map[string][]JobEvent),
+		cancels: make(map[string]context.CancelFunc),
+	}
+}
+
+// OnEvent sets a callback receiving a copy of a job whenever its state changes
+func (m *JobManager) OnEvent(fn func(Job)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onEvent = fn
+}
+
+// OnOutput sets a callback receiving the ID of a job and each event its
+// plugin emits while running
+func (m *JobManager) OnOutput(fn func(jobID string, event JobEvent)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onOutput = fn
+}
+
+// Submit validates the plugin and its parameters, applying their defaults,
+// and queues a job for it
+func (m *JobManager) Submit(pluginID string, params map[string]interface{}) (Job, error) {
+	plugin, err := m.plugins.GetPlugin(pluginID)
+	if err != nil {
+		return Job{}, err
+	}
+	params, err = validateParams(plugin, params)
+	if err != nil {
+		return Job{}, err
+	}
+
+	id, err := newJobID()
+	if err != nil {
+		return Job{}, fmt.Errorf("failed to create job ID: %v", err)
+	}
+
+	job := &Job{
+		ID:        id,
+		PluginID:  pluginID,
+		Params:    params,
+		State:     JobQueued,
+		CreatedAt: time.Now(),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	m.mu.Lock()
+	m.prune(job.CreatedAt)
+	m.jobs[id] = job
+	m.cancels[id] = cancel
+	m.mu.Unlock()
+
+	m.emit(job)
+	go m.run(ctx, job, plugin)
+
+	return m.snapshot(job), nil
+}
+
+// run waits for a free slot and executes the job's plugin until the job is
+// cancelled or the plugin's timeout passes
+func (m *JobManager) run(ctx context.Context, job *Job, plugin *Plugin) {
+	defer m.release(job.ID)
+
+	select {
+	case m.slots <- struct{}{}:
+		defer func() { <-m.slots }()
+	case <-ctx.Done():
+		return
+	}
+
+	if !m.transition(job, JobQueued, JobRunning, nil, nil) {
+		return
+	}
+
+	// Plugins run outside of any request handler, so a panic would take the
+	// whole server down
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Plugin %s panicked in job %s: %v\n%s", job.PluginID, job.ID, r, debug.Stack())
+			m.transition(job, JobRunning, JobFailed, nil, fmt.Errorf("plugin %s panicked: %v", job.PluginID, r))
+		}
+	}()
+
+	result, err := plugin.run(ctx, job.Params, func(event types.Event) {
+		m.record(job, event)
+	})
+	if err != nil {
+		m.transition(job, JobRunning, JobFailed, nil, err)
+		return
+	}
+	m.transition(job, JobRunning, JobSucceeded, result, nil)
+}
+
+// transition moves a job from one state to another, doing nothing when the
+// job is no longer in the expected state
+func (m *JobManager) transition(job *Job, from, to JobState, result interface{}, err error) bool {
+	m.mu.Lock()
+	if job.State != from {
+		m.mu.Unlock()
+		return false
+	}
+
+	now := time.Now()
+	job.State = to
+	switch {
+	case to == JobRunning:
+		job.StartedAt = &now
+	case to.Finished():
+		job.FinishedAt = &now
+		job.Progress = 100
+		job.Result = result
+		if err != nil {
+			job.Error = err.Error()
+		}
+	}
+	m.mu.Unlock()
+
+	m.emit(job)
+	return true
+}
+
+// record stores an event of a running job and updates its progress or
+// partial result. Events arriving after the job finished are dropped.
+func (m *JobManager) record(job *Job, event types.Event) {
+	m.mu.Lock()
+	if job.State != JobRunning {
+		m.mu.Unlock()
+		return
+	}
+
+	switch event.Type {
+	case types.EventProgress:
+		job.Progress = math.Max(0, math.Min(100, event.Progress))
+	case types.EventPartial:
+		job.Partial = event.Data
+	}
+
+	job.EventCount++
+	jobEvent := JobEvent{Seq: job.EventCount, Time: time.Now(), Event: event}
+	events := append(m.events[job.ID], jobEvent)
+	if len(events) > maxJobEvents {
+		events = events[len(events)-maxJobEvents:]
+	}
+	m.events[job.ID] = events
+	fn := m.onOutput
+	m.mu.Unlock()
+
+	if fn != nil {
+		fn(job.ID, jobEvent)
+	}
+}
+
+// Events returns the events of a job with a sequence number above after.
+// Only the most recent events of a job are kept.
+func (m *JobManager) Events(id string, after int) ([]JobEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.jobs[id]; !ok {
+		return nil, ErrJobNotFound
+	}
+
+	events := []JobEvent{}
+	for _, event := range m.events[id] {
+		if event.Seq > after {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// release cancels the context of a job once its run is over
+func (m *JobManager) release(id string) {
+	m.mu.Lock()
+	cancel := m.cancels[id]
+	delete(m.cancels, id)
+	m.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+}
+
+// Cancel cancels a queued or running job. The plugin's context is cancelled,
+// which kills any processes it started; plugins without context support keep
+// running in the background and their result is discarded.
+func (m *JobManager) Cancel(id string) (Job, error) {
+	m.mu.Lock()
+	job, ok := m.jobs[id]
+	cancel := m.cancels[id]
+	m.mu.Unlock()
+	if !ok {
+		return Job{}, ErrJobNotFound
+	}
+
+	if !m.transition(job, JobQueued, JobCancelled, nil, nil) &&
+		!m.transition(job, JobRunning, JobCancelled, nil, nil) {
+		return m.snapshot(job), ErrJobFinished
+	}
+
+	if cancel != nil {
+		cancel()
+	}
+	return m.snapshot(job), nil
+}
+
+// Get returns a job by ID
+func (m *JobManager) Get(id string) (Job, error) {
+	m.mu.Lock()
+	job, ok := m.jobs[id]
+	m.mu.Unlock()
+	if !ok {
+		return Job{}, ErrJobNotFound
+	}
+	return m.snapshot(job), nil
+}
+
+// List returns the jobs matching filter, newest first
+func (m *JobManager) List(filter JobFilter) []Job {
+	m.mu.Lock()
+	m.prune(time.Now())
+	jobs := make([]Job, 0, len(m.jobs))
+	for _, job := range m.jobs {
+		if filter.PluginID != "" && job.PluginID != filter.PluginID {
+			continue
+		}
+		if filter.State != "" && job.State != filter.State {
+			continue
+		}
+		jobs = append(jobs, *job)
+	}
+	m.mu.Unlock()
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.After(jobs[j].CreatedAt)
+	})
+	return jobs
+}
+
+// prune forgets finished jobs past their retention and the oldest finished
+// jobs beyond the limit. The caller must hold m.mu.
+func (m *JobManager) prune(now time.Time) {
+	var finished []*Job
+	for id, job := range m.jobs {
+		if !job.State.Finished() {
+			continue
+		}
+		if now.Sub(*job.FinishedAt) > finishedJobRetention {
+			delete(m.jobs, id)
+			delete(m.events, id)
+			continue
+		}
+		finished = append(finished, job)
+	}
+
+	if len(finished) <= maxFinishedJobs {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool {
+		return finished[i].FinishedAt.Before(*finished[j].FinishedAt)
+	})
+	for _, job := range finished[:len(finished)-maxFinishedJobs] {
+		delete(m.jobs, job.ID)
+		delete(m.events, job.ID)
+	}
+}
+
+func (m *JobManager) snapshot(job *Job) Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return *job
+}
+
+func (m *JobManager) emit(job *Job) {
+	m.mu.Lock()
+	fn := m.onEvent
+	copied := *job
+	m.mu.Unlock()
+
+	if fn != nil {
+		fn(copied)
+	}
+}
+
+func newJobID() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,64 @@
+package plugins
+
+import (
+	"context"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// waitForJob polls a job until it finishes
+func waitForJob(t *testing.T, m *JobManager, id string) Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, err := m.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.State.Finished() {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job still %s", job.State)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestJobManagerRecoversPluginPanics(t *testing.T) {
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	pm := NewPluginManager()
+	pm.RegisterPlugin(&Plugin{ID: "panics", ExecuteContext: func(context.Context, map[string]interface{}) (interface{}, error) {
+		var m map[string]int
+		m["boom"]++
+		return nil, nil
+	}})
+	pm.RegisterPlugin(&Plugin{ID: "works", ExecuteContext: func(context.Context, map[string]interface{}) (interface{}, error) {
+		return "ok", nil
+	}})
+	m := NewJobManager(pm, 1)
+
+	submitted, err := m.Submit("panics", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job := waitForJob(t, m, submitted.ID)
+	if job.State != JobFailed || !strings.Contains(job.Error, "plugin panics panicked: assignment to entry in nil map") {
+		t.Errorf("job %s with error %q, want a failure reporting the panic", job.State, job.Error)
+	}
+
+	// The worker slot of the panicking job is free again
+	submitted, err = m.Submit("works", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job := waitForJob(t, m, submitted.ID); job.State != JobSucceeded || job.Result != "ok" {
+		t.Errorf("job %s with result %v after a panic, want it to succeed", job.State, job.Result)
+	}
+}
@@ -1,46 +1,201 @@
 package plugins
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/NetScout-Go/NetTool/app/plugins/types"
 )
 
-// PluginRegistry is a simple registry for plugin execution functions
+// Execution limits of plugins and the commands they run
+const (
+	// defaultPluginTimeout applies to plugins whose plugin.json declares no timeout
+	defaultPluginTimeout = 5 * time.Minute
+	// definitionTimeout bounds "go run plugin.go --definition", which compiles the plugin
+	definitionTimeout = 2 * time.Minute
+	// commandWaitDelay is how long a killed command may keep its output open
+	commandWaitDelay = 5 * time.Second
+)
+
+// ExecuteContextFunc runs a plugin until ctx is done
+type ExecuteContextFunc func(ctx context.Context, params map[string]interface{}) (interface{}, error)
+
+// WithContext adapts an execute function without context support. When ctx
+// is done first, its error is returned and the function's result discarded.
+func WithContext(fn func(map[string]interface{}) (interface{}, error)) ExecuteContextFunc {
+	return func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		return types.RunWithContext(ctx, fn, params)
+	}
+}
+
+// ExecuteStreamFunc runs a plugin until ctx is done, passing incremental
+// events to emit while it runs
+type ExecuteStreamFunc func(ctx context.Context, params map[string]interface{}, emit func(types.Event)) (interface{}, error)
+
+// WithEvents adapts an execute function that emits no events
+func WithEvents(fn ExecuteContextFunc) ExecuteStreamFunc {
+	return func(ctx context.Context, params map[string]interface{}, _ func(types.Event)) (interface{}, error) {
+		return fn(ctx, params)
+	}
+}
+
+// Provenance tells where the code run for a plugin comes from
+type Provenance string
+
+const (
+	// ProvenanceBuiltin is code compiled into NetTool.
+	ProvenanceBuiltin Provenance = "builtin"
+	// ProvenanceInstalled is a Go plugin installed in the plugins directory.
+	ProvenanceInstalled Provenance = "installed"
+	// ProvenanceExternal is an out-of-process plugin speaking RPCProtocol.
+	ProvenanceExternal Provenance = "external"
+)
+
+// Registration is an execution function registered for a plugin ID
+type Registration struct {
+	Provenance Provenance
+	Execute    ExecuteStreamFunc
+	// Definition describes builtins that need no plugin.json; nil otherwise
+	Definition *types.PluginDefinition
+}
+
+// Resolution is the registration that runs for a plugin ID, and the
+// provenance of the registration it shadows, if any
+type Resolution struct {
+	Registration
+	Shadowed Provenance
+}
+
+// PluginRegistry holds the execution functions of plugins. An ID may have both
+// a builtin and an installed or external registration; the builtin runs
+// unless the other one was registered to override it.
 type PluginRegistry struct {
-	pluginFuncs map[string]func(map[string]interface{}) (interface{}, error)
-	mutex       sync.RWMutex
+	builtins  map[string]Registration
+	plugins   map[string]Registration // installed and external
+	overrides map[string]bool         // plugins overriding the builtin of their ID
+	mutex     sync.RWMutex
 }
 
 // NewPluginRegistry creates a new plugin registry
 func NewPluginRegistry() *PluginRegistry {
 	return &PluginRegistry{
-		pluginFuncs: make(map[string]func(map[string]interface{}) (interface{}, error)),
+		builtins:  make(map[string]Registration),
+		plugins:   make(map[string]Registration),
+		overrides: make(map[string]bool),
 	}
 }
 
-// RegisterPluginFunc registers a plugin execution function
+// RegisterPluginFunc registers a plugin execution function without context support
 func (r *PluginRegistry) RegisterPluginFunc(id string, fn func(map[string]interface{}) (interface{}, error)) {
+	r.RegisterPluginContextFunc(id, WithContext(fn))
+}
+
+// RegisterPluginContextFunc registers a plugin execution function that emits no events
+func (r *PluginRegistry) RegisterPluginContextFunc(id string, fn ExecuteContextFunc) {
+	r.RegisterPluginStreamFunc(id, WithEvents(fn))
+}
+
+// RegisterPluginStreamFunc registers a streaming execution function of an
+// installed plugin, which does not override a builtin
+func (r *PluginRegistry) RegisterPluginStreamFunc(id string, fn ExecuteStreamFunc) {
+	r.RegisterPlugin(id, ProvenanceInstalled, fn, false)
+}
+
+// RegisterPlugin registers the execution function of an installed or
+// external plugin. With overrideBuiltin it runs instead of a builtin of the
+// same ID.
+func (r *PluginRegistry) RegisterPlugin(id string, provenance Provenance, fn ExecuteStreamFunc, overrideBuiltin bool) {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
-	r.pluginFuncs[id] = fn
+	r.plugins[id] = Registration{Provenance: provenance, Execute: fn}
+	r.overrides[id] = overrideBuiltin
 }
 
-// GetPluginFunc returns a plugin execution function
-func (r *PluginRegistry) GetPluginFunc(id string) (func(map[string]interface{}) (interface{}, error), error) {
+// RegisterBuiltin registers an execution function compiled into NetTool.
+// Builtins with a definition are listed even when no plugin.json describes them.
+func (r *PluginRegistry) RegisterBuiltin(id string, fn ExecuteStreamFunc, definition *types.PluginDefinition) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.builtins[id] = Registration{Provenance: ProvenanceBuiltin, Execute: fn, Definition: definition}
+}
+
+// resetPlugins forgets the installed and external plugins before they are
+// loaded again
+func (r *PluginRegistry) resetPlugins() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.plugins = make(map[string]Registration)
+	r.overrides = make(map[string]bool)
+}
+
+// Resolve returns the registration that runs for a plugin ID
+func (r *PluginRegistry) Resolve(id string) (Resolution, error) {
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
-	fn, ok := r.pluginFuncs[id]
-	if !ok {
-		return nil, fmt.Errorf("plugin function not found: %s", id)
+
+	builtin, hasBuiltin := r.builtins[id]
+	plugin, hasPlugin := r.plugins[id]
+	switch {
+	case hasBuiltin && hasPlugin && r.overrides[id]:
+		return Resolution{Registration: plugin, Shadowed: ProvenanceBuiltin}, nil
+	case hasBuiltin && hasPlugin:
+		return Resolution{Registration: builtin, Shadowed: plugin.Provenance}, nil
+	case hasBuiltin:
+		return Resolution{Registration: builtin}, nil
+	case hasPlugin:
+		return Resolution{Registration: plugin}, nil
 	}
-	return fn, nil
+	return Resolution{}, fmt.Errorf("plugin function not found: %s", id)
+}
+
+// Builtins returns the builtin registrations by ID
+func (r *PluginRegistry) Builtins() map[string]Registration {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	builtins := make(map[string]Registration, len(r.builtins))
+	for id, registration := range r.builtins {
+		builtins[id] = registration
+	}
+	return builtins
+}
+
+// GetPluginFunc returns a plugin execution function running without a deadline
+func (r *PluginRegistry) GetPluginFunc(id string) (func(map[string]interface{}) (interface{}, error), error) {
+	fn, err := r.GetPluginContextFunc(id)
+	if err != nil {
+		return nil, err
+	}
+	return func(params map[string]interface{}) (interface{}, error) {
+		return fn(context.Background(), params)
+	}, nil
+}
+
+// GetPluginContextFunc returns a plugin execution function discarding its events
+func (r *PluginRegistry) GetPluginContextFunc(id string) (ExecuteContextFunc, error) {
+	fn, err := r.GetPluginStreamFunc(id)
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		return fn(ctx, params, func(types.Event) {})
+	}, nil
+}
+
+// GetPluginStreamFunc returns the streaming execution function that runs for
+// a plugin ID
+func (r *PluginRegistry) GetPluginStreamFunc(id string) (ExecuteStreamFunc, error) {
+	resolution, err := r.Resolve(id)
+	if err != nil {
+		return nil, err
+	}
+	return resolution.Execute, nil
 }
 
 // The global plugin registry
@@ -51,7 +206,8 @@ var registryOnce sync.Once
 func GetRegistry() *PluginRegistry {
 	registryOnce.Do(func() {
 		registry = NewPluginRegistry()
-		// Dynamic registry initialization happens via LoadPlugins
+		registerBuiltins(registry)
+		// Installed plugins are registered by LoadPlugins
 	})
 	return registry
 }
@@ -62,7 +218,8 @@ type Command struct {
 	args []string
 }
 
-// NewCommand creates a new command
+// NewCommand creates a command running the binary cmd, which is never split
+// or passed to a shell; use NewCommandWithArgs for arguments
 func NewCommand(cmd string) *Command {
 	return &Command{cmd: cmd, args: []string{}}
 }
@@ -74,8 +231,17 @@ func NewCommandWithArgs(cmd string, args ...string) *Command {
 
 // Run executes the command and returns its output
 func (c *Command) Run() (string, error) {
-	cmd := exec.Command(c.cmd, c.args...)
+	return c.RunContext(context.Background())
+}
+
+// RunContext executes the command and returns its output. When ctx is done
+// the command and every process it started are killed.
+func (c *Command) RunContext(ctx context.Context) (string, error) {
+	cmd := commandContext(ctx, c.cmd, c.args...)
 	output, err := cmd.CombinedOutput()
+	if ctx.Err() != nil {
+		return string(output), ctx.Err()
+	}
 	return string(output), err
 }
 
@@ -84,7 +250,7 @@ type PluginLoader struct {
 	pluginsDir         string
 	plugins            []types.Plugin // Change to use the interface instead of struct
 	mutex              sync.Mutex
-	pluginExecuteFuncs map[string]func(map[string]interface{}) (interface{}, error)
+	pluginExecuteFuncs map[string]ExecuteStreamFunc
 }
 
 // NewPluginLoader creates a new plugin loader
@@ -92,7 +258,7 @@ func NewPluginLoader(pluginsDir string) *PluginLoader {
 	return &PluginLoader{
 		pluginsDir:         pluginsDir,
 		plugins:            []types.Plugin{},
-		pluginExecuteFuncs: make(map[string]func(map[string]interface{}) (interface{}, error)),
+		pluginExecuteFuncs: make(map[string]ExecuteStreamFunc),
 	}
 }
 
@@ -103,10 +269,12 @@ func (p *PluginLoader) LoadPlugins() ([]types.Plugin, error) {
 
 	// Reset plugins
 	p.plugins = []types.Plugin{}
-	p.pluginExecuteFuncs = make(map[string]func(map[string]interface{}) (interface{}, error))
+	p.pluginExecuteFuncs = make(map[string]ExecuteStreamFunc)
 
-	// Initialize plugin registry if not already done
+	// Initialize plugin registry if not already done, and forget the
+	// plugins of the previous load
 	registry := GetRegistry()
+	registry.resetPlugins()
 
 	// List all directories in the plugins directory
 	entries, err := os.ReadDir(p.pluginsDir)
@@ -115,6 +283,7 @@ func (p *PluginLoader) LoadPlugins() ([]types.Plugin, error) {
 	}
 
 	// Process each directory as a potential plugin
+	rpcDirs := make(map[string]bool)
 	for _, entry := range entries {
 		if !entry.IsDir() {
 			continue
@@ -129,11 +298,6 @@ func (p *PluginLoader) LoadPlugins() ([]types.Plugin, error) {
 			continue
 		}
 
-		// Check if plugin.go exists
-		if _, err := os.Stat(pluginGoPath); os.IsNotExist(err) {
-			continue
-		}
-
 		// Read plugin.json
 		data, err := os.ReadFile(pluginJSONPath)
 		if err != nil {
@@ -154,10 +318,32 @@ func (p *PluginLoader) LoadPlugins() ([]types.Plugin, error) {
 		}
 
 		pluginID := pluginDef.ID
+
+		// Out-of-process plugins are started from their runtime command and
+		// need no plugin.go
+		if pluginDef.Runtime != nil {
+			rpcPlugin, err := loadRPCPlugin(pluginDir, pluginDef)
+			if err != nil {
+				fmt.Printf("Warning: Failed to load plugin %s: %v\n", entry.Name(), err)
+				continue
+			}
+
+			rpcDirs[pluginDir] = true
+			fmt.Printf("Registering out-of-process plugin from filesystem: %s\n", pluginID)
+			p.pluginExecuteFuncs[pluginID] = rpcPlugin.ExecuteStream
+			registry.RegisterPlugin(pluginID, ProvenanceExternal, rpcPlugin.ExecuteStream, pluginDef.OverrideBuiltin)
+			continue
+		}
+
+		// Check if plugin.go exists
+		if _, err := os.Stat(pluginGoPath); os.IsNotExist(err) {
+			continue
+		}
+
 		fmt.Printf("Registering plugin from filesystem: %s\n", pluginID)
 
 		// Create a wrapper execution function that dynamically imports and executes the plugin
-		p.pluginExecuteFuncs[pluginID] = func(params map[string]interface{}) (interface{}, error) {
+		p.pluginExecuteFuncs[pluginID] = func(ctx context.Context, params map[string]interface{}, emit func(types.Event)) (interface{}, error) {
 			// Try to build and load the plugin dynamically
 			pluginInstance, err := p.loadPlugin(pluginDir, pluginID)
 			if err != nil {
@@ -165,27 +351,27 @@ func (p *PluginLoader) LoadPlugins() ([]types.Plugin, error) {
 			}
 
 			// Execute the plugin
-			return pluginInstance.Execute(params)
+			return types.ExecuteWithEvents(ctx, pluginInstance, params, emit)
 		}
 
-		// Register with the registry
-		registry.RegisterPluginFunc(pluginID, p.pluginExecuteFuncs[pluginID])
-
-		// Also register the plugin execution functions from the helper
-		// Skip override for plugins that have proper standalone implementations
-		if pluginID != "dns_lookup" {
-			if helperFunc, err := LoadPluginFunc(pluginDir, pluginID); err == nil {
-				// Override with the helper function if available
-				registry.RegisterPluginFunc(pluginID, helperFunc)
-			}
-		}
+		// Register with the registry; a builtin of the same ID runs instead
+		// unless the plugin overrides it
+		registry.RegisterPlugin(pluginID, ProvenanceInstalled, p.pluginExecuteFuncs[pluginID], pluginDef.OverrideBuiltin)
 	}
 
+	// Stop the processes of out-of-process plugins that were removed
+	closeRPCPluginsExcept(p.pluginsDir, rpcDirs)
+
 	return p.plugins, nil
 }
 
 // loadPlugin loads a plugin from the given directory
 func (p *PluginLoader) loadPlugin(pluginDir string, pluginID string) (types.Plugin, error) {
+	// Out-of-process plugins were started by LoadPlugins
+	if rpcPlugin := loadedRPCPlugin(pluginDir); rpcPlugin != nil {
+		return rpcPlugin, nil
+	}
+
 	// Try to build the plugin
 	pluginGoPath := filepath.Join(pluginDir, "plugin.go")
 
@@ -232,7 +418,10 @@ func (p *DynamicPlugin) GetDefinition() types.PluginDefinition {
 	}
 
 	// If plugin.json read failed, try running plugin.go with --definition flag
-	cmd := exec.Command("go", "run", "plugin.go", "--definition")
+	ctx, cancel := context.WithTimeout(context.Background(), definitionTimeout)
+	defer cancel()
+
+	cmd := commandContext(ctx, "go", "run", "plugin.go", "--definition")
 	cmd.Dir = p.pluginDir
 	output, err := cmd.CombinedOutput()
 	if err != nil {
@@ -268,6 +457,17 @@ func (p *DynamicPlugin) GetDefinition() types.PluginDefinition {
 
 // Execute runs the plugin with the given parameters
 func (p *DynamicPlugin) Execute(params map[string]interface{}) (interface{}, error) {
+	return p.ExecuteContext(context.Background(), params)
+}
+
+// ExecuteContext runs the plugin with the given parameters until ctx is done
+func (p *DynamicPlugin) ExecuteContext(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	return p.ExecuteStream(ctx, params, func(types.Event) {})
+}
+
+// ExecuteStream runs the plugin until ctx is done. Plugins with a main
+// function have their stdout forwarded to emit line by line.
+func (p *DynamicPlugin) ExecuteStream(ctx context.Context, params map[string]interface{}, emit func(types.Event)) (interface{}, error) {
 	// Check if the plugin has a main function by looking for package main
 	pluginGoPath := filepath.Join(p.pluginDir, "plugin.go")
 	pluginContent, err := os.ReadFile(pluginGoPath)
@@ -278,14 +478,14 @@ func (p *DynamicPlugin) Execute(params map[string]interface{}) (interface{}, err
 	// Check if plugin uses package main
 	if strings.Contains(string(pluginContent), "package main") {
 		// Plugin has main function, run it with command line arguments
-		return p.executeWithMain(params)
+		return p.executeWithMain(ctx, params, emit)
 	}
 	// Plugin doesn't have main function, try to use it as a library
-	return p.executeWithLibrary(params)
+	return p.executeWithLibrary(ctx, params, emit)
 }
 
 // executeWithMain runs plugins that have a main function
-func (p *DynamicPlugin) executeWithMain(params map[string]interface{}) (interface{}, error) {
+func (p *DynamicPlugin) executeWithMain(ctx context.Context, params map[string]interface{}, emit func(types.Event)) (interface{}, error) {
 	// Convert parameters to JSON
 	paramsJSON, err := json.Marshal(params)
 	if err != nil {
@@ -293,16 +493,23 @@ func (p *DynamicPlugin) executeWithMain(params map[string]interface{}) (interfac
 	}
 
 	// Run the plugin.go file with the parameters
-	cmd := exec.Command("go", "run", "plugin.go", "--execute="+string(paramsJSON))
+	cmd := commandContext(ctx, "go", "run", "plugin.go", "--execute="+string(paramsJSON))
 	cmd.Dir = p.pluginDir
-	output, err := cmd.CombinedOutput()
+	output, stderr, err := runStreaming(cmd, emit)
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("plugin %s stopped: %w", p.pluginID, ctx.Err())
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute plugin %s: %v\nOutput: %s", p.pluginID, err, string(output))
+		return nil, fmt.Errorf("failed to execute plugin %s: %v\nOutput: %s%s", p.pluginID, err, string(stderr), string(output))
 	}
 
-	// Try to parse the output as JSON
+	// Try to parse the output as JSON, or else its last line, which is where
+	// plugins streaming their progress print the result
 	var result interface{}
 	if err := json.Unmarshal(output, &result); err != nil {
+		if result, ok := lastLineJSON(output); ok {
+			return result, nil
+		}
 		// If not valid JSON, return as string
 		return map[string]interface{}{
 			"result": string(output),
@@ -314,16 +521,14 @@ func (p *DynamicPlugin) executeWithMain(params map[string]interface{}) (interfac
 }
 
 // executeWithLibrary runs plugins that don't have a main function
-func (p *DynamicPlugin) executeWithLibrary(params map[string]interface{}) (interface{}, error) {
-	// For plugins without main function, we need to call them through the registry
-	// or fall back to the plugin helper functions
-	registry := GetRegistry()
-	executeFunc, err := registry.GetPluginFunc(p.pluginID)
+func (p *DynamicPlugin) executeWithLibrary(ctx context.Context, params map[string]interface{}, emit func(types.Event)) (interface{}, error) {
+	// Plugins without a main function are built as Go plugins and loaded
+	executeFunc, err := LoadPluginFunc(p.pluginDir, p.pluginID)
 	if err != nil {
-		return nil, fmt.Errorf("plugin %s not found in registry and cannot be executed directly: %v", p.pluginID, err)
+		return nil, fmt.Errorf("plugin %s cannot be executed directly: %v", p.pluginID, err)
 	}
 
-	return executeFunc(params)
+	return WithEvents(executeFunc)(ctx, params, emit)
 }
 
 // IsIterable checks if the plugin implements the IterablePlugin interface
@@ -362,10 +567,17 @@ func (p *PluginLoader) GetPluginExecuteFunc(pluginID string) (func(map[string]in
 	p.mutex.Lock()
 	defer p.mutex.Unlock()
 
-	executeFunc, ok := p.pluginExecuteFuncs[pluginID]
-	if !ok {
+	if _, ok := p.pluginExecuteFuncs[pluginID]; !ok {
 		return nil, fmt.Errorf("plugin not found: %s", pluginID)
 	}
 
-	return executeFunc, nil
+	// A builtin of the same ID may run instead of the plugin's own code
+	executeFunc, err := GetRegistry().GetPluginStreamFunc(pluginID)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(params map[string]interface{}) (interface{}, error) {
+		return executeFunc(context.Background(), params, func(types.Event) {})
+	}, nil
 }
@@ -0,0 +1,396 @@
+package plugins
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NetScout-Go/NetTool/app/plugins/types"
+)
+
+// Limits of the network-aware parameter types
+const (
+	maxHostListSize  = 1024             // hosts in a host list
+	maxFileParamSize = 10 * 1024 * 1024 // bytes of an uploaded file
+)
+
+// typePlaceholders are shown in empty fields of parameters without their own placeholder
+var typePlaceholders = map[ParameterType]string{
+	TypeHostname: "example.com or 192.0.2.1",
+	TypeIP:       "192.0.2.1 or 2001:db8::1",
+	TypeCIDR:     "192.168.1.0/24",
+	TypePort:     "443",
+	TypePortList: "22,80,443,8000-8100",
+	TypeHostList: "example.com, 192.0.2.1",
+	TypeDuration: "30s",
+}
+
+// PortRange is an inclusive range of ports; a single port has First == Last
+type PortRange struct {
+	First int `json:"first"`
+	Last  int `json:"last"`
+}
+
+// FileParam is the value of a file parameter. It is sent as a JSON object
+// with the name and base64 content of the file, or as a base64 data URL.
+type FileParam struct {
+	Name string `json:"name"`
+	Data []byte `json:"data"` // base64 in JSON
+}
+
+// ReadFileParam reads a file from disk as the value of a file parameter, as
+// the command line tools do for "@path" arguments
+func ReadFileParam(path string) (FileParam, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return FileParam{}, err
+	}
+	if info.Size() > maxFileParamSize {
+		return FileParam{}, fmt.Errorf("%s is larger than %d bytes", path, maxFileParamSize)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return FileParam{}, err
+	}
+	return FileParam{Name: filepath.Base(path), Data: data}, nil
+}
+
+// ParseParamArgs parses command line arguments of the form name=value into
+// plugin parameters. Values stay strings, to be converted by ValidateParams,
+// except for file parameters, whose value "@path" names the file to read.
+func ParseParamArgs(definition types.PluginDefinition, args []string) (map[string]interface{}, error) {
+	fileParams := make(map[string]bool)
+	for _, param := range definition.Parameters {
+		if ParameterType(param.Type) == TypeFile {
+			fileParams[param.ID] = true
+		}
+	}
+
+	params := make(map[string]interface{}, len(args))
+	for _, arg := range args {
+		name, value, ok := strings.Cut(arg, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("parameter %q is not of the form name=value", arg)
+		}
+		if fileParams[name] && strings.HasPrefix(value, "@") {
+			file, err := ReadFileParam(strings.TrimPrefix(value, "@"))
+			if err != nil {
+				return nil, fmt.Errorf("parameter %s: %v", name, err)
+			}
+			params[name] = file
+			continue
+		}
+		params[name] = value
+	}
+	return params, nil
+}
+
+// ParsePortList parses a comma-separated list of ports and port ranges such
+// as "22,80,8000-8100" into sorted, merged ranges
+func ParsePortList(list string) ([]PortRange, error) {
+	var ranges []PortRange
+	for _, item := range strings.FieldsFunc(list, func(r rune) bool { return r == ',' || r == ' ' }) {
+		first, last, isRange := strings.Cut(item, "-")
+		from, err := parsePort(first)
+		if err != nil {
+			return nil, err
+		}
+		to := from
+		if isRange {
+			if to, err = parsePort(last); err != nil {
+				return nil, err
+			}
+			if to < from {
+				return nil, fmt.Errorf("port range %s is reversed", item)
+			}
+		}
+		ranges = append(ranges, PortRange{First: from, Last: to})
+	}
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("must list at least one port")
+	}
+
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].First < ranges[j].First })
+	merged := ranges[:1]
+	for _, r := range ranges[1:] {
+		last := &merged[len(merged)-1]
+		if r.First <= last.Last+1 {
+			if r.Last > last.Last {
+				last.Last = r.Last
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged, nil
+}
+
+// formatPortList is the inverse of ParsePortList
+func formatPortList(ranges []PortRange) string {
+	items := make([]string, len(ranges))
+	for i, r := range ranges {
+		items[i] = strconv.Itoa(r.First)
+		if r.Last != r.First {
+			items[i] += "-" + strconv.Itoa(r.Last)
+		}
+	}
+	return strings.Join(items, ",")
+}
+
+func parsePort(s string) (int, error) {
+	port, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || port < 1 || port > 65535 {
+		return 0, fmt.Errorf("%q is not a port between 1 and 65535", s)
+	}
+	return port, nil
+}
+
+// validHostname reports whether name is a DNS name. Underscores are allowed
+// for service names such as _sip._tcp.example.com.
+func validHostname(name string) bool {
+	name = strings.TrimSuffix(name, ".")
+	if name == "" || len(name) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(name, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// toHostname accepts a host name or an IP address
+func toHostname(value interface{}) (string, error) {
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("must be a host name or IP address")
+	}
+	s = strings.TrimSpace(s)
+	if addr, err := netip.ParseAddr(strings.Trim(s, "[]")); err == nil {
+		return addr.String(), nil
+	}
+	if !validHostname(s) {
+		return "", fmt.Errorf("%q is not a valid host name or IP address", s)
+	}
+	return s, nil
+}
+
+func toIP(value interface{}) (string, error) {
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("must be an IP address")
+	}
+	addr, err := netip.ParseAddr(strings.Trim(strings.TrimSpace(s), "[]"))
+	if err != nil {
+		return "", fmt.Errorf("%q is not a valid IP address", s)
+	}
+	return addr.String(), nil
+}
+
+// toCIDR accepts a network prefix, or a single address as a prefix of its full length
+func toCIDR(value interface{}) (string, error) {
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("must be a network such as 192.168.1.0/24")
+	}
+	s = strings.TrimSpace(s)
+	if addr, err := netip.ParseAddr(s); err == nil {
+		return netip.PrefixFrom(addr, addr.BitLen()).String(), nil
+	}
+	prefix, err := netip.ParsePrefix(s)
+	if err != nil {
+		return "", fmt.Errorf("%q is not a valid network such as 192.168.1.0/24", s)
+	}
+	return prefix.Masked().String(), nil
+}
+
+func toPort(param Parameter, value interface{}) (float64, error) {
+	n, err := toParamNumber(value)
+	if err != nil || n != float64(int(n)) || n < 1 || n > 65535 {
+		return 0, fmt.Errorf("must be a port between 1 and 65535")
+	}
+	if err := checkRange(param, n); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// toPortList normalizes a port list to sorted, merged ranges; a JSON array of
+// ports is accepted too
+func toPortList(value interface{}) (string, error) {
+	var list string
+	switch v := value.(type) {
+	case string:
+		list = v
+	case float64:
+		list = formatParamNumber(v)
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = fmt.Sprint(item)
+		}
+		list = strings.Join(items, ",")
+	default:
+		return "", fmt.Errorf("must be a list of ports such as 22,80,8000-8100")
+	}
+
+	ranges, err := ParsePortList(list)
+	if err != nil {
+		return "", err
+	}
+	return formatPortList(ranges), nil
+}
+
+// toHostList accepts hosts separated by commas, spaces or new lines, or a
+// JSON array of hosts, and returns them without duplicates
+func toHostList(value interface{}) ([]string, error) {
+	var items []string
+	switch v := value.(type) {
+	case string:
+		items = strings.FieldsFunc(v, func(r rune) bool {
+			return r == ',' || r == ';' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+		})
+	case []interface{}:
+		for _, item := range v {
+			items = append(items, fmt.Sprint(item))
+		}
+	case []string:
+		items = v
+	default:
+		return nil, fmt.Errorf("must be a list of host names or IP addresses")
+	}
+
+	seen := make(map[string]bool, len(items))
+	hosts := make([]string, 0, len(items))
+	for _, item := range items {
+		host, err := toHostname(item)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[host] {
+			seen[host] = true
+			hosts = append(hosts, host)
+		}
+	}
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("must list at least one host")
+	}
+	if len(hosts) > maxHostListSize {
+		return nil, fmt.Errorf("must list at most %d hosts", maxHostListSize)
+	}
+	return hosts, nil
+}
+
+// toDuration accepts a Go duration such as "1m30s" or a number of seconds and
+// returns seconds, to which Min and Max also apply
+func toDuration(param Parameter, value interface{}) (float64, error) {
+	var seconds float64
+	if s, ok := value.(string); ok {
+		if d, err := time.ParseDuration(strings.TrimSpace(s)); err == nil {
+			seconds = d.Seconds()
+		} else if n, err := toParamNumber(s); err == nil {
+			seconds = n
+		} else {
+			return 0, fmt.Errorf("must be a duration such as 30s or 1m30s")
+		}
+	} else {
+		n, err := toParamNumber(value)
+		if err != nil {
+			return 0, fmt.Errorf("must be a duration such as 30s or 1m30s")
+		}
+		seconds = n
+	}
+
+	if seconds < 0 {
+		return 0, fmt.Errorf("must not be negative")
+	}
+	if err := checkRange(param, seconds); err != nil {
+		return 0, err
+	}
+	return seconds, nil
+}
+
+// toInterface accepts the name of one of the system's network interfaces
+func toInterface(value interface{}) (string, error) {
+	name, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("must be a network interface name")
+	}
+	name = strings.TrimSpace(name)
+	if _, err := net.InterfaceByName(name); err != nil {
+		return "", fmt.Errorf("no network interface named %q", name)
+	}
+	return name, nil
+}
+
+// interfaceOptions lists the system's network interfaces as select options
+func interfaceOptions() []Option {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+
+	options := make([]Option, 0, len(interfaces))
+	for _, iface := range interfaces {
+		label := iface.Name
+		if iface.Flags&net.FlagUp == 0 {
+			label += " (down)"
+		}
+		options = append(options, Option{Value: iface.Name, Label: label})
+	}
+	return options
+}
+
+// toFileParam accepts {"name": ..., "data": <base64>} or a base64 data URL
+func toFileParam(value interface{}) (FileParam, error) {
+	var file FileParam
+	var encoded string
+	switch v := value.(type) {
+	case FileParam:
+		file = v
+	case map[string]interface{}:
+		file.Name, _ = v["name"].(string)
+		data, ok := v["data"].(string)
+		if !ok {
+			return FileParam{}, fmt.Errorf("must be an uploaded file")
+		}
+		encoded = data
+	case string:
+		header, data, ok := strings.Cut(v, ",")
+		if !ok || !strings.HasPrefix(header, "data:") || !strings.HasSuffix(header, ";base64") {
+			return FileParam{}, fmt.Errorf("must be an uploaded file")
+		}
+		encoded = data
+	default:
+		return FileParam{}, fmt.Errorf("must be an uploaded file")
+	}
+
+	if encoded != "" {
+		if base64.StdEncoding.DecodedLen(len(encoded)) > maxFileParamSize+3 {
+			return FileParam{}, fmt.Errorf("must not be larger than %d bytes", maxFileParamSize)
+		}
+		data, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return FileParam{}, fmt.Errorf("file content is not valid base64")
+		}
+		file.Data = data
+	}
+	if len(file.Data) > maxFileParamSize {
+		return FileParam{}, fmt.Errorf("must not be larger than %d bytes", maxFileParamSize)
+	}
+	return file, nil
+}
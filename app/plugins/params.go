@@ -0,0 +1,281 @@
+package plugins
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/NetScout-Go/NetTool/app/plugins/types"
+)
+
+// ErrInvalidParameters is returned for parameters that do not match the
+// plugin's definition; the error is a *ParamsError
+var ErrInvalidParameters = errors.New("invalid parameters")
+
+// FieldError describes a parameter that does not match its definition
+type FieldError struct {
+	Param   string `json:"param"`
+	Message string `json:"message"`
+	missing bool
+}
+
+// ParamsError lists every parameter of a run that does not match the plugin's
+// definition. It matches ErrInvalidParameters, and ErrMissingParameter when a
+// required parameter is missing.
+type ParamsError struct {
+	Fields []FieldError `json:"fields"`
+}
+
+func (e *ParamsError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		messages[i] = field.Param + " " + field.Message
+	}
+	return fmt.Sprintf("%v: %s", ErrInvalidParameters, strings.Join(messages, "; "))
+}
+
+// Unwrap returns the sentinel errors the error matches
+func (e *ParamsError) Unwrap() []error {
+	errs := []error{ErrInvalidParameters}
+	for _, field := range e.Fields {
+		if field.missing {
+			return append(errs, ErrMissingParameter)
+		}
+	}
+	return errs
+}
+
+// ValidateParams checks params against the parameters of a plugin definition
+// as plugin runs do, for tools running plugins outside the plugin manager
+func ValidateParams(definition types.PluginDefinition, params map[string]interface{}) (map[string]interface{}, error) {
+	return validateParams(&Plugin{Parameters: convertParameters(definition.Parameters)}, params)
+}
+
+// validateParams checks params against the plugin's parameter definitions and
+// returns them with defaults applied and values converted to the declared
+// types: float64 for numbers, ranges, ports and durations (in seconds), bool
+// for booleans, []string for host lists, FileParam for files, string for the
+// other types and the option's own value for selects. Parameters the plugin
+// does not declare are passed through unchanged.
+func validateParams(plugin *Plugin, params map[string]interface{}) (map[string]interface{}, error) {
+	validated := make(map[string]interface{}, len(params)+len(plugin.Parameters))
+	for key, value := range params {
+		validated[key] = value
+	}
+
+	var fields []FieldError
+	for _, param := range plugin.Parameters {
+		value, ok := params[param.ID]
+		if !ok || isEmptyParam(param, value) {
+			delete(validated, param.ID)
+			switch {
+			case param.Default != nil:
+				// Defaults come from the definition and are trusted, but are
+				// still given the declared type
+				if coerced, err := coerceParam(param, param.Default); err == nil {
+					validated[param.ID] = coerced
+				} else {
+					validated[param.ID] = param.Default
+				}
+			case param.Required:
+				fields = append(fields, FieldError{Param: param.ID, Message: "is required", missing: true})
+			}
+			continue
+		}
+
+		coerced, err := coerceParam(param, value)
+		if err != nil {
+			fields = append(fields, FieldError{Param: param.ID, Message: err.Error()})
+			continue
+		}
+		validated[param.ID] = coerced
+	}
+
+	if len(fields) > 0 {
+		return nil, &ParamsError{Fields: fields}
+	}
+	return validated, nil
+}
+
+// isEmptyParam reports whether value stands for an absent parameter, as sent
+// by forms for fields left blank
+func isEmptyParam(param Parameter, value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(v) == "" && (param.Required || param.Type != TypeString)
+	case []interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+// coerceParam converts value to the parameter's type and checks its range or options
+func coerceParam(param Parameter, value interface{}) (interface{}, error) {
+	switch param.Type {
+	case TypeString:
+		return toParamString(value)
+
+	case TypeNumber, TypeRange:
+		n, err := toParamNumber(value)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkRange(param, n); err != nil {
+			return nil, err
+		}
+		return n, nil
+
+	case TypeBoolean:
+		return toParamBool(value)
+
+	case TypeSelect:
+		return matchOption(param, value)
+
+	case TypeHostname:
+		return toHostname(value)
+	case TypeIP:
+		return toIP(value)
+	case TypeCIDR:
+		return toCIDR(value)
+	case TypePort:
+		return toPort(param, value)
+	case TypePortList:
+		return toPortList(value)
+	case TypeHostList:
+		return toHostList(value)
+	case TypeDuration:
+		return toDuration(param, value)
+	case TypeInterface:
+		return toInterface(value)
+	case TypeFile:
+		return toFileParam(value)
+	}
+
+	// Types unknown to the validator are left to the plugin
+	return value, nil
+}
+
+func toParamString(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	}
+	return "", fmt.Errorf("must be a string")
+}
+
+func toParamNumber(value interface{}) (float64, error) {
+	var n float64
+	switch v := value.(type) {
+	case float64:
+		n = v
+	case int:
+		n = float64(v)
+	case json.Number:
+		parsed, err := v.Float64()
+		if err != nil {
+			return 0, fmt.Errorf("must be a number")
+		}
+		n = parsed
+	case string:
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return 0, fmt.Errorf("must be a number")
+		}
+		n = parsed
+	default:
+		return 0, fmt.Errorf("must be a number")
+	}
+
+	if math.IsNaN(n) || math.IsInf(n, 0) {
+		return 0, fmt.Errorf("must be a finite number")
+	}
+	return n, nil
+}
+
+func toParamBool(value interface{}) (bool, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case float64:
+		if v == 0 || v == 1 {
+			return v == 1, nil
+		}
+	case string:
+		switch strings.ToLower(strings.TrimSpace(v)) {
+		case "true", "1", "yes", "on":
+			return true, nil
+		case "false", "0", "no", "off":
+			return false, nil
+		}
+	}
+	return false, fmt.Errorf("must be true or false")
+}
+
+// checkRange enforces Min, Max and Step, which counts from Min or else zero
+func checkRange(param Parameter, n float64) error {
+	if param.Min != nil && n < *param.Min {
+		return fmt.Errorf("must be at least %s", formatParamNumber(*param.Min))
+	}
+	if param.Max != nil && n > *param.Max {
+		return fmt.Errorf("must be at most %s", formatParamNumber(*param.Max))
+	}
+	if param.Step != nil && *param.Step > 0 {
+		base := 0.0
+		if param.Min != nil {
+			base = *param.Min
+		}
+		steps := (n - base) / *param.Step
+		if math.Abs(steps-math.Round(steps)) > 1e-9 {
+			return fmt.Errorf("must be a multiple of %s", formatParamNumber(*param.Step))
+		}
+	}
+	return nil
+}
+
+// matchOption returns the option whose value equals value, comparing numbers
+// numerically and everything else as text
+func matchOption(param Parameter, value interface{}) (interface{}, error) {
+	if len(param.Options) == 0 {
+		return value, nil
+	}
+
+	text, err := toParamString(value)
+	if err != nil {
+		return nil, fmt.Errorf("must be one of %s", optionList(param.Options))
+	}
+	for _, option := range param.Options {
+		if _, isNumber := option.Value.(float64); isNumber {
+			if n, err := toParamNumber(value); err == nil && n == option.Value.(float64) {
+				return option.Value, nil
+			}
+			continue
+		}
+		if fmt.Sprint(option.Value) == text {
+			return option.Value, nil
+		}
+	}
+	return nil, fmt.Errorf("must be one of %s", optionList(param.Options))
+}
+
+func optionList(options []Option) string {
+	values := make([]string, len(options))
+	for i, option := range options {
+		values[i] = fmt.Sprint(option.Value)
+	}
+	return strings.Join(values, ", ")
+}
+
+func formatParamNumber(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
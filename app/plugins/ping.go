@@ -0,0 +1,474 @@
+package plugins
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/netip"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+
+	"github.com/NetScout-Go/NetTool/app/internal/icmpsock"
+	"github.com/NetScout-Go/NetTool/app/plugins/types"
+)
+
+// Limits of the native ping
+const (
+	maxPingCount     = 1000
+	minPingInterval  = 0.2 // seconds, as for unprivileged users of ping
+	maxPingSize      = 65000
+	defaultPingCount = 4
+	defaultPingSize  = 56
+)
+
+// Outcomes of a ping probe
+const (
+	probeReply       = "reply"
+	probeTimeout     = "timeout"
+	probeUnreachable = "unreachable"
+	probeTTLExceeded = "ttl_exceeded"
+	probeTooBig      = "too_big"
+	probeError       = "error"
+)
+
+// pingDefinition describes the native ping, so it is listed without a plugin.json
+var pingDefinition = types.PluginDefinition{
+	ID:          "ping",
+	Name:        "Ping",
+	Description: "Sends ICMP echo requests to a host and reports round-trip times, TTL and packet loss",
+	Version:     "2.0.0",
+	Author:      "NetTool Team",
+	License:     "MIT",
+	Icon:        "ping",
+	Timeout:     3600, // long runs at long intervals
+	Parameters: []types.PluginParam{
+		{ID: "host", Name: "Host", Description: "Host name or IP address to ping", Type: types.TypeHostname, Required: true},
+		{ID: "count", Name: "Count", Description: "Number of echo requests to send", Type: types.TypeNumber, Default: float64(defaultPingCount), Min: types.FloatPtr(1), Max: types.FloatPtr(maxPingCount), Step: types.FloatPtr(1)},
+		{ID: "interval", Name: "Interval", Description: "Time between echo requests", Type: types.TypeDuration, Default: "1s", Min: types.FloatPtr(minPingInterval), Max: types.FloatPtr(60)},
+		{ID: "timeout", Name: "Timeout", Description: "Time to wait for each reply", Type: types.TypeDuration, Default: "2s", Min: types.FloatPtr(0.1), Max: types.FloatPtr(60)},
+		{ID: "size", Name: "Packet Size", Description: "Bytes of data in each echo request", Type: types.TypeNumber, Default: float64(defaultPingSize), Min: types.FloatPtr(0), Max: types.FloatPtr(maxPingSize), Step: types.FloatPtr(1)},
+		{ID: "dontFragment", Name: "Don't Fragment", Description: "Set the DF bit, so that packets larger than the path MTU fail instead of being fragmented", Type: types.TypeBoolean, Default: false},
+		{ID: "interface", Name: "Source Interface", Description: "Interface to send from; the system chooses when empty", Type: types.TypeInterface},
+		{ID: "ipVersion", Name: "IP Version", Description: "Address family used for host names", Type: types.TypeSelect, Default: "auto", Options: []types.Option{
+			{Value: "auto", Label: "Automatic"},
+			{Value: "4", Label: "IPv4"},
+			{Value: "6", Label: "IPv6"},
+		}},
+		types.CreateIterationParams(),
+	},
+}
+
+// pingOptions are the parameters of a ping run
+type pingOptions struct {
+	Host         string
+	Count        int
+	Interval     time.Duration
+	Timeout      time.Duration
+	Size         int
+	DontFragment bool
+	Interface    string
+	IPVersion    string
+	Iteration    int // set by iterating callers, continues the sequence numbers of earlier runs
+}
+
+// pingProbe is the outcome of one echo request
+type pingProbe struct {
+	Seq        int     `json:"seq"`
+	Status     string  `json:"status"`
+	RTT        float64 `json:"rttMs,omitempty"`
+	TTL        int     `json:"ttl,omitempty"`
+	Bytes      int     `json:"bytes,omitempty"`
+	From       string  `json:"from,omitempty"`
+	Error      string  `json:"error,omitempty"`
+	Duplicates int     `json:"duplicates,omitempty"`
+	OutOfOrder bool    `json:"outOfOrder,omitempty"`
+}
+
+// pingResult is the result of the native ping
+type pingResult struct {
+	Host         string      `json:"host"`
+	Address      string      `json:"address"`
+	IPVersion    int         `json:"ipVersion"`
+	Privileged   bool        `json:"privileged"` // raw socket instead of an ICMP datagram socket
+	Size         int         `json:"size"`
+	Interval     float64     `json:"interval"` // seconds
+	DontFragment bool        `json:"dontFragment"`
+	Interface    string      `json:"interface,omitempty"`
+	Iteration    int         `json:"iteration,omitempty"`
+	Probes       []pingProbe `json:"probes"`
+	Sent         int         `json:"sent"`
+	Received     int         `json:"received"`
+	Duplicates   int         `json:"duplicates"`
+	OutOfOrder   int         `json:"outOfOrder"`
+	Loss         float64     `json:"lossPercent"`
+	Min          float64     `json:"minMs"`
+	Avg          float64     `json:"avgMs"`
+	Max          float64     `json:"maxMs"`
+	Mdev         float64     `json:"mdevMs"`
+	Duration     float64     `json:"durationMs"`
+}
+
+// executePing pings a host with native ICMP sockets. Lost packets are part of
+// the result rather than an error.
+func executePing(ctx context.Context, params map[string]interface{}, emit func(types.Event)) (interface{}, error) {
+	opts, err := parsePingParams(params)
+	if err != nil {
+		return nil, err
+	}
+	return runPing(ctx, opts, emit)
+}
+
+// parsePingParams reads the parameters of a ping run. Defaults are applied
+// here too, for installed ping plugins whose plugin.json lacks parameters.
+func parsePingParams(params map[string]interface{}) (pingOptions, error) {
+	opts := pingOptions{
+		Count:     defaultPingCount,
+		Interval:  time.Second,
+		Timeout:   2 * time.Second,
+		Size:      defaultPingSize,
+		IPVersion: "auto",
+	}
+
+	host, _ := params["host"].(string)
+	if host == "" {
+		return opts, fmt.Errorf("host parameter is required")
+	}
+	opts.Host = host
+
+	if count, ok := params["count"].(float64); ok && count > 0 {
+		opts.Count = int(math.Min(count, maxPingCount))
+	}
+	if interval, ok := params["interval"].(float64); ok && interval > 0 {
+		opts.Interval = time.Duration(math.Max(interval, minPingInterval) * float64(time.Second))
+	}
+	if timeout, ok := params["timeout"].(float64); ok && timeout > 0 {
+		opts.Timeout = time.Duration(timeout * float64(time.Second))
+	}
+	if size, ok := params["size"].(float64); ok && size >= 0 {
+		opts.Size = int(math.Min(size, maxPingSize))
+	}
+	opts.DontFragment, _ = params["dontFragment"].(bool)
+	opts.Interface, _ = params["interface"].(string)
+	if version, ok := params["ipVersion"].(string); ok && version != "" {
+		opts.IPVersion = version
+	}
+	// The plugin page passes the number of the iteration
+	if iteration, ok := params["iterationCount"].(float64); ok && iteration > 0 {
+		opts.Iteration = int(iteration)
+	}
+	return opts, nil
+}
+
+// resolveHost returns the address to probe for host. version is "4", "6" or
+// "auto" for the system's preferred address.
+func resolveHost(ctx context.Context, host, version string) (netip.Addr, error) {
+	network := "ip"
+	switch version {
+	case "4":
+		network = "ip4"
+	case "6":
+		network = "ip6"
+	}
+
+	if addr, err := netip.ParseAddr(host); err == nil {
+		addr = addr.Unmap()
+		if network == "ip4" && !addr.Is4() || network == "ip6" && addr.Is4() {
+			return netip.Addr{}, fmt.Errorf("%s is not an IPv%s address", host, version)
+		}
+		return addr, nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, network, host)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("failed to resolve %s: %v", host, err)
+	}
+	if len(addrs) == 0 {
+		return netip.Addr{}, fmt.Errorf("no addresses found for %s", host)
+	}
+	return addrs[0].Unmap(), nil
+}
+
+// echoID returns a random echo identifier, so that concurrent runs on raw
+// sockets tell their replies apart
+func echoID() int {
+	var b [2]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return int(time.Now().UnixNano() & 0xffff)
+	}
+	return int(binary.BigEndian.Uint16(b[:]))
+}
+
+// runPing sends opts.Count echo requests and collects the replies, passing
+// each probe's outcome to emit
+func runPing(ctx context.Context, opts pingOptions, emit func(types.Event)) (*pingResult, error) {
+	addr, err := resolveHost(ctx, opts.Host, opts.IPVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := icmpsock.Listen(addr, icmpsock.Options{Interface: opts.Interface, DontFragment: opts.DontFragment})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	result := &pingResult{
+		Host:         opts.Host,
+		Address:      addr.String(),
+		IPVersion:    4,
+		Size:         opts.Size,
+		Interval:     opts.Interval.Seconds(),
+		DontFragment: opts.DontFragment,
+		Interface:    opts.Interface,
+		Iteration:    opts.Iteration,
+		Probes:       make([]pingProbe, 0, opts.Count),
+	}
+	if addr.Is6() {
+		result.IPVersion = 6
+	}
+	result.Privileged = conn.Privileged()
+
+	headerSize := 28
+	if addr.Is6() {
+		headerSize = 48
+	}
+	emit(types.Event{Type: types.EventLine, Line: fmt.Sprintf("PING %s (%s) %d(%d) bytes of data.", opts.Host, addr, opts.Size, opts.Size+headerSize)})
+
+	// Replies are read in the background until the socket is closed
+	packets := make(chan icmpsock.Packet, 16)
+	done := make(chan struct{})
+	defer close(done)
+	go conn.ReadLoop(packets, done)
+
+	id := echoID()
+	payload := make([]byte, opts.Size)
+	for i := range payload {
+		payload[i] = byte(i)
+	}
+
+	firstSeq := opts.Iteration * opts.Count
+	seqIndex := make(map[int]int, opts.Count) // sequence number to probe
+	sentAt := make([]time.Time, 0, opts.Count)
+	resolved := make([]bool, 0, opts.Count)
+	pending := 0
+	lastReply := -1
+	var rtts []float64
+
+	finish := func(i int, status string) {
+		resolved[i] = true
+		pending--
+		result.Probes[i].Status = status
+		emit(types.Event{Type: types.EventPartial, Data: result.Probes[i]})
+		emit(types.Event{Type: types.EventProgress, Progress: float64(len(result.Probes)-pending) * 100 / float64(opts.Count)})
+	}
+
+	send := func() {
+		i := len(result.Probes)
+		seq := (firstSeq + i) & 0xffff
+		seqIndex[seq] = i
+		result.Probes = append(result.Probes, pingProbe{Seq: seq, Status: probeTimeout})
+		sentAt = append(sentAt, time.Now())
+		resolved = append(resolved, false)
+		pending++
+
+		if err := conn.WriteEcho(addr, id, seq, payload); err != nil {
+			status := probeError
+			if errors.Is(err, syscall.EMSGSIZE) {
+				// Larger than the MTU of the route, with DF set
+				status = probeTooBig
+			}
+			result.Probes[i].Error = err.Error()
+			emit(types.Event{Type: types.EventLine, Line: fmt.Sprintf("icmp_seq=%d %v", seq, err)})
+			finish(i, status)
+		}
+	}
+
+	// expire gives up on the probes whose timeout passed and returns when the
+	// next one times out
+	expire := func(now time.Time) time.Duration {
+		next := opts.Timeout
+		for i := range result.Probes {
+			if resolved[i] {
+				continue
+			}
+			left := sentAt[i].Add(opts.Timeout).Sub(now)
+			if left <= 0 {
+				emit(types.Event{Type: types.EventLine, Line: fmt.Sprintf("Request timeout for icmp_seq %d", result.Probes[i].Seq)})
+				finish(i, probeTimeout)
+				continue
+			}
+			next = min(next, left)
+		}
+		return next
+	}
+
+	handle := func(packet icmpsock.Packet) {
+		switch packet.Message.Type {
+		case ipv4.ICMPTypeEchoReply, ipv6.ICMPTypeEchoReply:
+			echo, ok := packet.Message.Body.(*icmp.Echo)
+			if !ok || !conn.OwnsEcho(echo, id) || packet.From.WithZone("") != addr.WithZone("") {
+				return
+			}
+			i, ok := seqIndex[echo.Seq]
+			if !ok {
+				return
+			}
+			probe := &result.Probes[i]
+			if probe.Status == probeReply {
+				probe.Duplicates++
+				result.Duplicates++
+				emit(types.Event{Type: types.EventLine, Line: fmt.Sprintf("%d bytes from %s: icmp_seq=%d (DUP!)", packet.Size, packet.From, echo.Seq)})
+				return
+			}
+			if resolved[i] {
+				// Arrived after its timeout
+				return
+			}
+
+			rtt := float64(packet.Received.Sub(sentAt[i]).Microseconds()) / 1000
+			probe.RTT = rtt
+			probe.TTL = packet.TTL
+			probe.Bytes = packet.Size
+			probe.From = packet.From.String()
+			if i < lastReply {
+				probe.OutOfOrder = true
+				result.OutOfOrder++
+			} else {
+				lastReply = i
+			}
+			rtts = append(rtts, rtt)
+
+			line := fmt.Sprintf("%d bytes from %s: icmp_seq=%d", packet.Size, packet.From, echo.Seq)
+			if packet.TTL > 0 {
+				line += fmt.Sprintf(" ttl=%d", packet.TTL)
+			}
+			emit(types.Event{Type: types.EventLine, Line: line + fmt.Sprintf(" time=%.3f ms", rtt)})
+			finish(i, probeReply)
+
+		default:
+			// Raw sockets also receive the errors caused by our requests
+			quoted, ok := icmpsock.ParseQuoted(packet.Message, addr.Is6())
+			if !ok || !conn.Privileged() || !quoted.Echo || quoted.EchoID != id {
+				return
+			}
+			seq := quoted.EchoSeq
+			i, ok := seqIndex[seq]
+			if !ok || resolved[i] {
+				return
+			}
+
+			status, text := icmpErrorStatus(packet.Message)
+			result.Probes[i].From = packet.From.String()
+			result.Probes[i].Error = text
+			emit(types.Event{Type: types.EventLine, Line: fmt.Sprintf("From %s icmp_seq=%d %s", packet.From, seq, text)})
+			finish(i, status)
+		}
+	}
+
+	started := time.Now()
+	ticker := time.NewTicker(opts.Interval)
+	defer ticker.Stop()
+	timer := time.NewTimer(opts.Timeout)
+	defer timer.Stop()
+
+	send()
+	for len(result.Probes) < opts.Count || pending > 0 {
+		tick := ticker.C
+		if len(result.Probes) == opts.Count {
+			tick = nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-tick:
+			send()
+		case packet := <-packets:
+			handle(packet)
+		case <-timer.C:
+		}
+
+		timer.Reset(expire(time.Now()))
+	}
+
+	summarizePing(result, rtts)
+	result.Duration = float64(time.Since(started).Microseconds()) / 1000
+	emit(types.Event{Type: types.EventLine, Line: fmt.Sprintf("--- %s ping statistics ---", opts.Host)})
+	emit(types.Event{Type: types.EventLine, Line: fmt.Sprintf("%d packets transmitted, %d received, %g%% packet loss", result.Sent, result.Received, result.Loss)})
+	if result.Received > 0 {
+		emit(types.Event{Type: types.EventLine, Line: fmt.Sprintf("rtt min/avg/max/mdev = %.3f/%.3f/%.3f/%.3f ms", result.Min, result.Avg, result.Max, result.Mdev)})
+	}
+	return result, nil
+}
+
+// icmpErrorStatus returns the probe status and description of an ICMP error
+func icmpErrorStatus(msg *icmp.Message) (string, string) {
+	switch msg.Type {
+	case ipv4.ICMPTypeTimeExceeded, ipv6.ICMPTypeTimeExceeded:
+		return probeTTLExceeded, "Time to live exceeded"
+	case ipv6.ICMPTypePacketTooBig:
+		return probeTooBig, fmt.Sprintf("Packet too big: mtu=%d", msg.Body.(*icmp.PacketTooBig).MTU)
+	case ipv4.ICMPTypeDestinationUnreachable:
+		switch msg.Code {
+		case 0:
+			return probeUnreachable, "Destination Net Unreachable"
+		case 1:
+			return probeUnreachable, "Destination Host Unreachable"
+		case 3:
+			return probeUnreachable, "Destination Port Unreachable"
+		case 4:
+			return probeTooBig, "Frag needed and DF set"
+		case 13:
+			return probeUnreachable, "Communication administratively prohibited"
+		}
+	case ipv6.ICMPTypeDestinationUnreachable:
+		switch msg.Code {
+		case 0:
+			return probeUnreachable, "Destination unreachable: No route"
+		case 1:
+			return probeUnreachable, "Destination unreachable: Administratively prohibited"
+		case 3:
+			return probeUnreachable, "Destination unreachable: Address unreachable"
+		}
+	}
+	return probeUnreachable, fmt.Sprintf("Destination unreachable (code %d)", msg.Code)
+}
+
+// summarizePing computes the statistics of a run from its probes and the
+// round-trip times of the replies, in the order they arrived
+func summarizePing(result *pingResult, rtts []float64) {
+	result.Sent = len(result.Probes)
+	result.Received = len(rtts)
+	if result.Sent > 0 {
+		result.Loss = math.Round(float64(result.Sent-result.Received)*10000/float64(result.Sent)) / 100
+	}
+	if len(rtts) == 0 {
+		return
+	}
+
+	var sum, sumSquares float64
+	result.Min, result.Max = rtts[0], rtts[0]
+	for _, rtt := range rtts {
+		sum += rtt
+		sumSquares += rtt * rtt
+		result.Min = math.Min(result.Min, rtt)
+		result.Max = math.Max(result.Max, rtt)
+	}
+	avg := sum / float64(len(rtts))
+	result.Avg = roundMs(avg)
+	// Mean deviation as ping computes it: the standard deviation of the RTTs
+	result.Mdev = roundMs(math.Sqrt(math.Max(0, sumSquares/float64(len(rtts))-avg*avg)))
+}
+
+// roundMs rounds to microseconds, the precision of measured times in milliseconds
+func roundMs(ms float64) float64 {
+	return math.Round(ms*1000) / 1000
+}
@@ -13,6 +13,8 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+
+	"github.com/NetScout-Go/NetTool/app/plugins/types"
 )
 
 // PluginMetadata represents the metadata of a plugin
@@ -30,6 +32,7 @@ type PluginMetadata struct {
 	Path            string         `json:"path,omitempty"`
 	Dependencies    []Dependency   `json:"dependencies,omitempty"`
 	GitInfo         GitVersionInfo `json:"gitInfo,omitempty"`
+	Runtime         *types.Runtime `json:"runtime,omitempty"` // set for out-of-process plugins
 }
 
 // GitVersionInfo represents Git version information for a plugin
@@ -1463,18 +1466,27 @@ func (pi *PluginInstaller) validatePlugin(dir string) (PluginMetadata, error) {
 		return PluginMetadata{}, fmt.Errorf("plugin.json not found, not a valid plugin")
 	}
 
-	// Check if plugin.go exists
-	goPath := filepath.Join(dir, "plugin.go")
-	if _, err := os.Stat(goPath); os.IsNotExist(err) {
-		return PluginMetadata{}, fmt.Errorf("plugin.go not found, not a valid plugin")
-	}
-
 	// Read plugin.json
 	metadata, err := pi.readPluginMetadata(dir)
 	if err != nil {
 		return PluginMetadata{}, err
 	}
 
+	// Check if plugin.go exists, unless the plugin runs out of process
+	if metadata.Runtime != nil {
+		if metadata.Runtime.Protocol != RPCProtocol {
+			return PluginMetadata{}, fmt.Errorf("unsupported plugin protocol %q, expected %q", metadata.Runtime.Protocol, RPCProtocol)
+		}
+		if metadata.Runtime.Command == "" {
+			return PluginMetadata{}, fmt.Errorf("runtime command is missing in plugin.json")
+		}
+	} else {
+		goPath := filepath.Join(dir, "plugin.go")
+		if _, err := os.Stat(goPath); os.IsNotExist(err) {
+			return PluginMetadata{}, fmt.Errorf("plugin.go not found, not a valid plugin")
+		}
+	}
+
 	// Validate required fields
 	if metadata.ID == "" {
 		return PluginMetadata{}, fmt.Errorf("plugin ID is missing in plugin.json")
@@ -1,7 +1,6 @@
 package plugins
 
 import (
-	"bytes"
 	"context"
 	"crypto/rand"
 	"encoding/json"
@@ -19,207 +18,66 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/NetScout-Go/NetTool/app/plugins/types"
 )
 
-// LoadPluginFunc loads the plugin function from a Go plugin file
-func LoadPluginFunc(pluginDir, pluginID string) (func(map[string]interface{}) (interface{}, error), error) {
+// LoadPluginFunc loads the execute function of an installed plugin without a
+// main function, building it as a Go plugin first if needed. The plugin must
+// have a Plugin() function that returns a map with an "execute" key.
+func LoadPluginFunc(pluginDir, pluginID string) (ExecuteContextFunc, error) {
 	// Check if the plugin.go file exists
 	pluginGoPath := filepath.Join(pluginDir, "plugin.go")
 	if _, err := os.Stat(pluginGoPath); err != nil {
 		return nil, fmt.Errorf("plugin.go file not found for %s: %v", pluginID, err)
 	}
 
-	// Try to use direct Go function
-	// First, try to find the plugin package
-	goFiles, err := filepath.Glob(filepath.Join(pluginDir, "*.go"))
-	if err != nil {
-		return nil, fmt.Errorf("error finding Go files for plugin %s: %v", pluginID, err)
-	}
-
-	if len(goFiles) == 0 {
-		return nil, fmt.Errorf("no Go files found for plugin %s", pluginID)
-	}
-
-	// Use an adapter-like approach to call the plugin's Execute function
-	// This is a special case for our subnet_calculator plugin that uses executeAdapter
-	if pluginID == "subnet_calculator" {
-		registry := GetRegistry()
-		execFunc, err := registry.GetPluginFunc(pluginID)
-		if err == nil {
-			return execFunc, nil
-		}
-	}
-
-	// Dynamic import based on plugin directory
-	// The plugin must have a Plugin() function that returns a map with an "execute" key
-	return func(params map[string]interface{}) (interface{}, error) {
-		// Import the plugin using direct code execution
-		//pluginName := filepath.Base(pluginDir)
-
-		// Handle specific plugins based on their IDs
-		switch pluginID {
-		case "subnet_calculator":
-			// Use the ExecuteAdapter function from the subnet_calculator package
-			return executeSubnetCalculator(params)
-		case "network_latency_heatmap":
-			return executeNetworkLatencyHeatmap(params)
-		case "ping":
-			return executePing(params)
-		case "traceroute":
-			return executeTraceroute(params)
-		case "dns_lookup":
-			return executeDNSLookup(params)
-		case "port_scanner":
-			return executePortScanner(params)
-		case "bandwidth_test":
-			return executeBandwidthTest(params)
-		case "packet_capture":
-			return executePacketCapture(params)
-		case "tc_controller":
-			return executeTCController(params)
-		case "arp_manager":
-			return executeARPManager(params)
-		case "device_discovery":
-			return executeDeviceDiscovery(params)
-		case "network_quality":
-			return executeNetworkQuality(params)
-		case "dns_propagation":
-			return executeDNSPropagation(params)
-		case "ssl_checker":
-			return executeSSLChecker(params)
-		case "reverse_dns_lookup":
-			return executeReverseDNSLookup(params)
-		case "mtu_tester":
-			return executeMTUTester(params)
-		case "wifi_scanner":
-			return executeWifiScanner(params)
-		default:
-			// For other plugins, try to use dynamically loaded plugin
-			pluginPath := filepath.Join(pluginDir, pluginID+".so")
-			if _, err := os.Stat(pluginPath); os.IsNotExist(err) {
-				// No .so file, try to build it
-				buildCmd := fmt.Sprintf("cd %s && go build -buildmode=plugin -o %s.so .", pluginDir, pluginID)
-				_, err := executeCommand(buildCmd)
-				if err != nil {
-					return nil, fmt.Errorf("failed to build plugin %s: %v", pluginID, err)
-				}
-			}
-
-			// Try to load the plugin
-			p, err := plugin.Open(pluginPath)
-			if err != nil {
-				return nil, fmt.Errorf("failed to load plugin %s: %v", pluginID, err)
-			}
-
-			// Look up the Plugin symbol
-			pluginSymbol, err := p.Lookup("Plugin")
-			if err != nil {
-				return nil, fmt.Errorf("plugin %s does not export Plugin symbol: %v", pluginID, err)
+	return func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		pluginPath := filepath.Join(pluginDir, pluginID+".so")
+		if _, err := os.Stat(pluginPath); os.IsNotExist(err) {
+			// No .so file, try to build it
+			cmd := commandContext(ctx, "go", "build", "-buildmode=plugin", "-o", pluginID+".so", ".")
+			cmd.Dir = pluginDir
+			if output, err := cmd.CombinedOutput(); err != nil {
+				return nil, fmt.Errorf("failed to build plugin %s: %v\nOutput: %s", pluginID, err, output)
 			}
-
-			// Call the Plugin function
-			pluginFunc := reflect.ValueOf(pluginSymbol).Call(nil)[0].Interface()
-
-			// Extract the execute function
-			pluginMap, ok := pluginFunc.(map[string]interface{})
-			if !ok {
-				return nil, fmt.Errorf("plugin %s Plugin() did not return a map", pluginID)
-			}
-
-			execFunc, ok := pluginMap["execute"].(func(map[string]interface{}) (interface{}, error))
-			if !ok {
-				return nil, fmt.Errorf("plugin %s does not provide a valid execute function", pluginID)
-			}
-
-			// Call the execute function with the provided parameters
-			return execFunc(params)
 		}
-	}, nil
-}
-
-// Helper function to execute a shell command
-func executeCommand(command string) (string, error) {
-	cmd := NewCommand(command)
-	output, err := cmd.Run()
-	return output, err
-}
-
-// Specific implementations for each plugin
-// These functions would typically be replaced by properly loading the plugin modules
-// but for now, we'll implement them with direct imports or simple placeholder functionality
 
-func executeSubnetCalculator(params map[string]interface{}) (interface{}, error) {
-	// Try to use the plugin's Plugin function from the dynamically loaded library
-	pluginDir := filepath.Join("app", "plugins", "plugins", "subnet_calculator")
-	pluginPath := filepath.Join(pluginDir, "subnet_calculator.so")
-
-	// Build the plugin if it doesn't exist
-	if _, err := os.Stat(pluginPath); os.IsNotExist(err) {
-		buildCmd := fmt.Sprintf("cd %s && go build -buildmode=plugin -o subnet_calculator.so .", pluginDir)
-		_, err := executeCommand(buildCmd)
-		if err != nil {
-			// If dynamic loading fails, use the registry as a fallback
-			registry := GetRegistry()
-			execFunc, err := registry.GetPluginFunc("subnet_calculator")
-			if err != nil {
-				return nil, fmt.Errorf("subnet_calculator plugin not registered and couldn't build dynamic plugin: %v", err)
-			}
-			return execFunc(params)
-		}
-	}
-
-	// Try to load the plugin
-	p, err := plugin.Open(pluginPath)
-	if err != nil {
-		// If dynamic loading fails, use the registry as a fallback
-		registry := GetRegistry()
-		execFunc, err := registry.GetPluginFunc("subnet_calculator")
+		// Try to load the plugin
+		p, err := plugin.Open(pluginPath)
 		if err != nil {
-			return nil, fmt.Errorf("subnet_calculator plugin not registered and couldn't load dynamic plugin: %v", err)
+			return nil, fmt.Errorf("failed to load plugin %s: %v", pluginID, err)
 		}
-		return execFunc(params)
-	}
 
-	// Look up the Plugin symbol
-	pluginSymbol, err := p.Lookup("Plugin")
-	if err != nil {
-		// If dynamic loading fails, use the registry as a fallback
-		registry := GetRegistry()
-		execFunc, err := registry.GetPluginFunc("subnet_calculator")
+		// Look up the Plugin symbol
+		pluginSymbol, err := p.Lookup("Plugin")
 		if err != nil {
-			return nil, fmt.Errorf("subnet_calculator plugin not registered and couldn't find Plugin symbol: %v", err)
+			return nil, fmt.Errorf("plugin %s does not export Plugin symbol: %v", pluginID, err)
 		}
-		return execFunc(params)
-	}
 
-	// Call the Plugin function
-	pluginFunc := reflect.ValueOf(pluginSymbol).Call(nil)[0].Interface()
+		// Call the Plugin function
+		pluginFunc := reflect.ValueOf(pluginSymbol).Call(nil)[0].Interface()
 
-	// Extract the execute function
-	pluginMap, ok := pluginFunc.(map[string]interface{})
-	if !ok {
-		registry := GetRegistry()
-		execFunc, err := registry.GetPluginFunc("subnet_calculator")
-		if err != nil {
-			return nil, fmt.Errorf("subnet_calculator Plugin() did not return a map")
+		// Extract the execute function
+		pluginMap, ok := pluginFunc.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("plugin %s Plugin() did not return a map", pluginID)
 		}
-		return execFunc(params)
-	}
 
-	execFunc, ok := pluginMap["execute"].(func(map[string]interface{}) (interface{}, error))
-	if !ok {
-		registry := GetRegistry()
-		execFunc, err := registry.GetPluginFunc("subnet_calculator")
-		if err != nil {
-			return nil, fmt.Errorf("subnet_calculator does not provide a valid execute function")
+		execFunc, ok := pluginMap["execute"].(func(map[string]interface{}) (interface{}, error))
+		if !ok {
+			return nil, fmt.Errorf("plugin %s does not provide a valid execute function", pluginID)
 		}
-		return execFunc(params)
-	}
 
-	// Call the execute function with the provided parameters
-	return execFunc(params)
+		// Call the execute function with the provided parameters
+		return types.RunWithContext(ctx, execFunc, params)
+	}, nil
 }
 
+// Specific implementations for each plugin
+// These functions would typically be replaced by properly loading the plugin modules
+// but for now, we'll implement them with direct imports or simple placeholder functionality
+
 func executeNetworkLatencyHeatmap(params map[string]interface{}) (interface{}, error) {
 	// To avoid infinite recursion, we'll implement a simplified version
 	// of the heatmap functionality directly here
@@ -258,122 +116,83 @@ func executeNetworkLatencyHeatmap(params map[string]interface{}) (interface{}, e
 	return result, nil
 }
 
-func executePing(params map[string]interface{}) (interface{}, error) {
-	// Direct implementation without recursion
+func executePortScanner(ctx context.Context, params map[string]interface{}) (interface{}, error) {
 	host, _ := params["host"].(string)
-	countParam, _ := params["count"].(float64)
-	if countParam == 0 {
-		countParam = 4 // Default count
-	}
-
 	if host == "" {
 		return nil, fmt.Errorf("host parameter is required")
 	}
-
-	cmd := fmt.Sprintf("ping -c %d %s", int(countParam), host)
-	output, err := executeCommand(cmd)
-	if err != nil {
-		return nil, fmt.Errorf("ping failed: %v", err)
+	ports, _ := params["ports"].(string)
+	if strings.TrimSpace(ports) == "" {
+		ports = "1-1000"
 	}
 
-	return map[string]interface{}{
-		"command": cmd,
-		"output":  output,
-		"success": err == nil,
-	}, nil
-}
-
-func executeTraceroute(params map[string]interface{}) (interface{}, error) {
-	// Similar implementation to ping
-	host, _ := params["host"].(string)
-	if host == "" {
-		return nil, fmt.Errorf("host parameter is required")
+	cmd := NewSafeCommand("nmap", Flag("-p"), PortsArg(ports), HostArg(host))
+	result, err := cmd.Run(ctx)
+	if err != nil && result.ExitCode < 0 {
+		return nil, fmt.Errorf("port scan failed: %v", err)
 	}
 
-	cmd := fmt.Sprintf("traceroute %s", host)
-	output, err := executeCommand(cmd)
-
-	return map[string]interface{}{
-		"command": cmd,
-		"output":  output,
-		"success": err == nil,
-	}, nil
+	return commandOutput(result, err), nil
 }
 
-func executeDNSLookup(params map[string]interface{}) (interface{}, error) {
-	domain, _ := params["domain"].(string)
-	if domain == "" {
-		return nil, fmt.Errorf("domain parameter is required")
-	}
-
-	cmd := fmt.Sprintf("dig %s", domain)
-	output, err := executeCommand(cmd)
-
-	return map[string]interface{}{
-		"command": cmd,
-		"output":  output,
+// commandOutput is the result of a diagnostic that shows a command's output.
+// Commands exiting with an error, e.g. ping to an unreachable host, still
+// have their output shown.
+func commandOutput(result CommandResult, err error) map[string]interface{} {
+	output := map[string]interface{}{
+		"command": result.Command,
+		"output":  result.Stdout,
 		"success": err == nil,
-	}, nil
-}
-
-func executePortScanner(params map[string]interface{}) (interface{}, error) {
-	host, _ := params["host"].(string)
-	if host == "" {
-		return nil, fmt.Errorf("host parameter is required")
 	}
-
-	cmd := fmt.Sprintf("nmap -p 1-1000 %s", host)
-	output, err := executeCommand(cmd)
-
-	return map[string]interface{}{
-		"command": cmd,
-		"output":  output,
-		"success": err == nil,
-	}, nil
+	if result.Stderr != "" {
+		output["stderr"] = result.Stderr
+	}
+	if result.Truncated {
+		output["truncated"] = true
+	}
+	if err != nil {
+		output["error"] = err.Error()
+	}
+	return output
 }
 
-func executeBandwidthTest(_ map[string]interface{}) (interface{}, error) {
+func executeBandwidthTest(ctx context.Context, _ map[string]interface{}) (interface{}, error) {
 	var attemptNotes []string
 
-	result, err := runLibreSpeedCLI()
+	result, err := runLibreSpeedCLI(ctx)
 	if err == nil {
 		return result, nil
 	}
 	attemptNotes = append(attemptNotes, fmt.Sprintf("librespeed-cli: %v", err))
 
-	result, err = runOoklaSpeedtest()
+	result, err = runOoklaSpeedtest(ctx)
 	if err == nil {
 		return result, nil
 	}
 	attemptNotes = append(attemptNotes, fmt.Sprintf("speedtest binary: %v", err))
 
-	result, err = runLegacySpeedtest()
+	result, err = runLegacySpeedtest(ctx)
 	if err == nil {
 		return result, nil
 	}
 	attemptNotes = append(attemptNotes, fmt.Sprintf("speedtest-cli: %v", err))
 
+	// Do not fake results for a cancelled or timed out test
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
 	return simulateBandwidthTest(attemptNotes), nil
 }
 
-func runLibreSpeedCLI() (map[string]interface{}, error) {
-	binary, err := exec.LookPath("librespeed-cli")
+func runLibreSpeedCLI(ctx context.Context) (map[string]interface{}, error) {
+	cmd := NewSafeCommand("librespeed-cli", Flag("--json"))
+	cmd.Timeout = 60 * time.Second
+	result, err := cmd.Run(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
-	defer cancel()
-
-	cmd := exec.CommandContext(ctx, binary, "--json")
-	var combined bytes.Buffer
-	cmd.Stdout = &combined
-	cmd.Stderr = &combined
-
-	if err := cmd.Run(); err != nil {
-		return nil, formatCommandError("librespeed-cli", err, combined.String())
-	}
-
 	var payload struct {
 		Timestamp string  `json:"timestamp"`
 		Download  float64 `json:"download"`
@@ -388,7 +207,7 @@ func runLibreSpeedCLI() (map[string]interface{}, error) {
 		} `json:"server"`
 	}
 
-	if err := json.Unmarshal(combined.Bytes(), &payload); err != nil {
+	if err := json.Unmarshal([]byte(result.Stdout), &payload); err != nil {
 		return nil, fmt.Errorf("parse librespeed-cli json: %w", err)
 	}
 
@@ -421,24 +240,14 @@ func runLibreSpeedCLI() (map[string]interface{}, error) {
 	}, nil
 }
 
-func runOoklaSpeedtest() (map[string]interface{}, error) {
-	binary, err := exec.LookPath("speedtest")
+func runOoklaSpeedtest(ctx context.Context) (map[string]interface{}, error) {
+	cmd := NewSafeCommand("speedtest", Flag("--accept-license"), Flag("--accept-gdpr"), Flag("--format=json"))
+	cmd.Timeout = 60 * time.Second
+	result, err := cmd.Run(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
-	defer cancel()
-
-	cmd := exec.CommandContext(ctx, binary, "--accept-license", "--accept-gdpr", "--format=json")
-	var combined bytes.Buffer
-	cmd.Stdout = &combined
-	cmd.Stderr = &combined
-
-	if err := cmd.Run(); err != nil {
-		return nil, formatCommandError("speedtest", err, combined.String())
-	}
-
 	var payload struct {
 		Type      string `json:"type"`
 		Timestamp string `json:"timestamp"`
@@ -460,7 +269,7 @@ func runOoklaSpeedtest() (map[string]interface{}, error) {
 		} `json:"interface"`
 	}
 
-	if err := json.Unmarshal(combined.Bytes(), &payload); err != nil {
+	if err := json.Unmarshal([]byte(result.Stdout), &payload); err != nil {
 		return nil, fmt.Errorf("parse speedtest json: %w", err)
 	}
 
@@ -489,24 +298,14 @@ func runOoklaSpeedtest() (map[string]interface{}, error) {
 	}, nil
 }
 
-func runLegacySpeedtest() (map[string]interface{}, error) {
-	binary, err := exec.LookPath("speedtest-cli")
+func runLegacySpeedtest(ctx context.Context) (map[string]interface{}, error) {
+	cmd := NewSafeCommand("speedtest-cli", Flag("--json"))
+	cmd.Timeout = 60 * time.Second
+	result, err := cmd.Run(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
-	defer cancel()
-
-	cmd := exec.CommandContext(ctx, binary, "--json")
-	var combined bytes.Buffer
-	cmd.Stdout = &combined
-	cmd.Stderr = &combined
-
-	if err := cmd.Run(); err != nil {
-		return nil, formatCommandError("speedtest-cli", err, combined.String())
-	}
-
 	var payload struct {
 		Download   float64 `json:"download"`
 		Upload     float64 `json:"upload"`
@@ -520,7 +319,7 @@ func runLegacySpeedtest() (map[string]interface{}, error) {
 		} `json:"server"`
 	}
 
-	if err := json.Unmarshal(combined.Bytes(), &payload); err != nil {
+	if err := json.Unmarshal([]byte(result.Stdout), &payload); err != nil {
 		return nil, fmt.Errorf("parse speedtest-cli json: %w", err)
 	}
 
@@ -632,26 +431,14 @@ func executeNetworkQuality(_ map[string]interface{}) (interface{}, error) {
 	return map[string]interface{}{"message": "Network Quality plugin execution simulation"}, nil
 }
 
-func executeDNSPropagation(_ map[string]interface{}) (interface{}, error) {
-	return map[string]interface{}{"message": "DNS Propagation plugin execution simulation"}, nil
-}
-
-func executeSSLChecker(_ map[string]interface{}) (interface{}, error) {
-	return map[string]interface{}{"message": "SSL Checker plugin execution simulation"}, nil
-}
-
-func executeReverseDNSLookup(_ map[string]interface{}) (interface{}, error) {
-	return map[string]interface{}{"message": "Reverse DNS Lookup plugin execution simulation"}, nil
-}
-
 func executeMTUTester(_ map[string]interface{}) (interface{}, error) {
 	return map[string]interface{}{"message": "MTU Tester plugin execution simulation"}, nil
 }
 
-func executeWifiScanner(params map[string]interface{}) (interface{}, error) {
+func executeWifiScanner(ctx context.Context, params map[string]interface{}) (interface{}, error) {
 	iface, scanTime, showHidden := wifiParseParameters(params)
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(scanTime+10)*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(scanTime+10)*time.Second)
 	defer cancel()
 
 	if err := wifiEnsureInterface(ctx, iface); err != nil {
@@ -726,8 +513,8 @@ func wifiParseParameters(params map[string]interface{}) (string, int, bool) {
 }
 
 func wifiEnsureInterface(ctx context.Context, iface string) error {
-	cmd := exec.CommandContext(ctx, "ip", "link", "show", iface)
-	if err := cmd.Run(); err != nil {
+	cmd := NewSafeCommand("ip", Flag("link"), Flag("show"), InterfaceArg(iface))
+	if _, err := cmd.Run(ctx); err != nil {
 		return fmt.Errorf("interface %s not found or inaccessible", iface)
 	}
 	return nil
@@ -776,29 +563,25 @@ func wifiCommandExists(name string) bool {
 }
 
 func wifiScanWithIw(ctx context.Context, iface string, showHidden bool) ([]map[string]interface{}, error) {
-	cmd := exec.CommandContext(ctx, "sudo", "iw", "dev", iface, "scan")
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("iw scan failed: %w: %s", err, stderr.String())
+	cmd := NewSafeCommand("iw", Flag("dev"), InterfaceArg(iface), Flag("scan"))
+	cmd.Sudo = true
+	result, err := cmd.Run(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("iw scan failed: %w", err)
 	}
 
-	return wifiParseIwScan(stdout.String(), showHidden), nil
+	return wifiParseIwScan(result.Stdout, showHidden), nil
 }
 
 func wifiScanWithIwlist(ctx context.Context, iface string, showHidden bool) ([]map[string]interface{}, error) {
-	cmd := exec.CommandContext(ctx, "sudo", "iwlist", iface, "scanning")
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("iwlist scan failed: %w: %s", err, stderr.String())
+	cmd := NewSafeCommand("iwlist", InterfaceArg(iface), Flag("scanning"))
+	cmd.Sudo = true
+	result, err := cmd.Run(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("iwlist scan failed: %w", err)
 	}
 
-	return wifiParseIwlistScan(stdout.String(), showHidden), nil
+	return wifiParseIwlistScan(result.Stdout, showHidden), nil
 }
 
 func wifiParseIwScan(output string, showHidden bool) []map[string]interface{} {
@@ -1,11 +1,13 @@
 package plugins
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 
 	"github.com/NetScout-Go/NetTool/app/plugins/types"
 )
@@ -24,6 +26,24 @@ const (
 	TypeSelect ParameterType = "select"
 	// TypeRange is the range type identifier.
 	TypeRange ParameterType = "range"
+	// TypeHostname is a host name or IP address.
+	TypeHostname ParameterType = "hostname"
+	// TypeIP is an IPv4 or IPv6 address.
+	TypeIP ParameterType = "ip"
+	// TypeCIDR is a network prefix such as 192.168.1.0/24.
+	TypeCIDR ParameterType = "cidr"
+	// TypePort is a TCP or UDP port number.
+	TypePort ParameterType = "port"
+	// TypePortList is a list of ports and port ranges such as 22,80,8000-8100.
+	TypePortList ParameterType = "ports"
+	// TypeHostList is a list of host names or IP addresses.
+	TypeHostList ParameterType = "hosts"
+	// TypeDuration is a duration such as 500ms or 1m30s, or a number of seconds.
+	TypeDuration ParameterType = "duration"
+	// TypeInterface is the name of one of the system's network interfaces.
+	TypeInterface ParameterType = "interface"
+	// TypeFile is an uploaded file.
+	TypeFile ParameterType = "file"
 )
 
 // Parameter defines a plugin parameter
@@ -34,11 +54,12 @@ type Parameter struct {
 	Type        ParameterType `json:"type"`
 	Required    bool          `json:"required"`
 	Default     interface{}   `json:"default,omitempty"`
-	Options     []Option      `json:"options,omitempty"`    // For select type
-	Min         *float64      `json:"min,omitempty"`        // For number/range type
-	Max         *float64      `json:"max,omitempty"`        // For number/range type
-	Step        *float64      `json:"step,omitempty"`       // For number/range type
-	CanIterate  bool          `json:"canIterate,omitempty"` // Whether this parameter supports iteration
+	Options     []Option      `json:"options,omitempty"`     // For select type
+	Min         *float64      `json:"min,omitempty"`         // For number/range type
+	Max         *float64      `json:"max,omitempty"`         // For number/range type
+	Step        *float64      `json:"step,omitempty"`        // For number/range type
+	CanIterate  bool          `json:"canIterate,omitempty"`  // Whether this parameter supports iteration
+	Placeholder string        `json:"placeholder,omitempty"` // Example input shown in empty fields
 }
 
 // Option defines an option for a select parameter
@@ -47,19 +68,64 @@ type Option struct {
 	Label string      `json:"label"`
 }
 
-// Plugin represents a NetTool plugin
+// Plugin represents a NetTool plugin. Of its execute functions, the first set
+// of ExecuteStream, ExecuteContext and Execute is used.
 type Plugin struct {
-	ID          string                                            `json:"id"`
-	Name        string                                            `json:"name"`
-	Description string                                            `json:"description"`
-	Version     string                                            `json:"version"`
-	Author      string                                            `json:"author"`
-	License     string                                            `json:"license"`
-	Icon        string                                            `json:"icon"`
-	Parameters  []Parameter                                       `json:"parameters"`
-	Execute     func(map[string]interface{}) (interface{}, error) `json:"-"`
+	ID             string                                            `json:"id"`
+	Name           string                                            `json:"name"`
+	Description    string                                            `json:"description"`
+	Version        string                                            `json:"version"`
+	Author         string                                            `json:"author"`
+	License        string                                            `json:"license"`
+	Icon           string                                            `json:"icon"`
+	Parameters     []Parameter                                       `json:"parameters"`
+	Timeout        int                                               `json:"timeout,omitempty"` // seconds, 0 for the default
+	Execute        func(map[string]interface{}) (interface{}, error) `json:"-"`
+	ExecuteContext ExecuteContextFunc                                `json:"-"`
+	ExecuteStream  ExecuteStreamFunc                                 `json:"-"`
+	Provenance     Provenance                                        `json:"provenance,omitempty"` // where the code that runs comes from
+	Shadowed       Provenance                                        `json:"shadowed,omitempty"`   // provenance of another implementation of the ID that does not run
+	Health         func(context.Context) error                       `json:"-"`                    // set for out-of-process plugins
 }
 
+// run executes the plugin under parent, bounded by the plugin's timeout,
+// passing the events of streaming plugins to emit
+func (p *Plugin) run(parent context.Context, params map[string]interface{}, emit func(types.Event)) (interface{}, error) {
+	timeout := defaultPluginTimeout
+	if p.Timeout > 0 {
+		timeout = time.Duration(p.Timeout) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+
+	var result interface{}
+	var err error
+	switch {
+	case p.ExecuteStream != nil:
+		result, err = p.ExecuteStream(ctx, params, emit)
+	case p.ExecuteContext != nil:
+		result, err = p.ExecuteContext(ctx, params)
+	default:
+		result, err = WithContext(p.Execute)(ctx, params)
+	}
+
+	if ctx.Err() != nil {
+		// Only the plugin's own timer is reported as its timeout; the
+		// caller's deadline or cancellation is passed on as is
+		if parent.Err() == nil {
+			return nil, fmt.Errorf("plugin %s timed out after %v: %w", p.ID, timeout, ctx.Err())
+		}
+		return nil, fmt.Errorf("plugin %s stopped: %w", p.ID, parent.Err())
+	}
+	return result, err
+}
+
+// Plugin errors
+var (
+	ErrPluginNotFound   = errors.New("plugin not found")
+	ErrMissingParameter = errors.New("missing required parameter")
+)
+
 // PluginManager manages the plugins in NetTool
 type PluginManager struct {
 	plugins map[string]*Plugin
@@ -87,7 +153,7 @@ func (pm *PluginManager) GetPlugins() []*Plugin {
 
 	plugins := make([]*Plugin, 0, len(pm.plugins))
 	for _, plugin := range pm.plugins {
-		plugins = append(plugins, plugin)
+		plugins = append(plugins, withSystemOptions(plugin))
 	}
 	return plugins
 }
@@ -99,29 +165,64 @@ func (pm *PluginManager) GetPlugin(id string) (*Plugin, error) {
 
 	plugin, ok := pm.plugins[id]
 	if !ok {
-		return nil, errors.New("plugin not found")
+		return nil, ErrPluginNotFound
 	}
-	return plugin, nil
+	return withSystemOptions(plugin), nil
+}
+
+// withSystemOptions returns plugin with its interface parameters offering the
+// system's current network interfaces
+func withSystemOptions(plugin *Plugin) *Plugin {
+	var options []Option
+	for i, param := range plugin.Parameters {
+		if param.Type != TypeInterface || len(param.Options) > 0 {
+			continue
+		}
+		if options == nil {
+			copied := *plugin
+			copied.Parameters = append([]Parameter(nil), plugin.Parameters...)
+			plugin = &copied
+			options = interfaceOptions()
+		}
+		plugin.Parameters[i].Options = options
+	}
+	return plugin
 }
 
 // RunPlugin runs a plugin with the given parameters
 func (pm *PluginManager) RunPlugin(id string, params map[string]interface{}) (interface{}, error) {
+	return pm.RunPluginContext(context.Background(), id, params)
+}
+
+// RunPluginContext runs a plugin with the given parameters until ctx is done
+// or the plugin's timeout passes
+func (pm *PluginManager) RunPluginContext(ctx context.Context, id string, params map[string]interface{}) (interface{}, error) {
 	plugin, err := pm.GetPlugin(id)
 	if err != nil {
 		return nil, err
 	}
 
-	// Validate parameters
-	for _, param := range plugin.Parameters {
-		if param.Required {
-			if _, ok := params[param.ID]; !ok {
-				return nil, errors.New("missing required parameter: " + param.ID)
-			}
-		}
+	// Validate parameters and give them their declared types
+	params, err = validateParams(plugin, params)
+	if err != nil {
+		return nil, err
 	}
 
 	// Execute plugin
-	return plugin.Execute(params)
+	return plugin.run(ctx, params, func(types.Event) {})
+}
+
+// CheckHealth checks that a plugin is able to run. Only out-of-process
+// plugins are asked; other plugins are healthy once loaded.
+func (pm *PluginManager) CheckHealth(ctx context.Context, id string) error {
+	plugin, err := pm.GetPlugin(id)
+	if err != nil {
+		return err
+	}
+	if plugin.Health == nil {
+		return nil
+	}
+	return plugin.Health(ctx)
 }
 
 // RegisterPlugins refreshes and registers all plugins
@@ -166,8 +267,9 @@ func (pm *PluginManager) RefreshPlugins() error {
 		pluginDir := filepath.Join("app/plugins/plugins", entry.Name())
 		pluginID := entry.Name()
 
-		// Get the plugin execution function from the registry
-		executeFunc, err := registry.GetPluginFunc(pluginID)
+		// Get the plugin execution function from the registry, which decides
+		// between the plugin's own code and a builtin of the same ID
+		resolution, err := registry.Resolve(pluginID)
 		if err != nil {
 			fmt.Printf("Warning: Plugin %s not registered in registry: %v\n", pluginID, err)
 			continue
@@ -184,48 +286,49 @@ func (pm *PluginManager) RefreshPlugins() error {
 		definition := plugin.GetDefinition()
 
 		// Register the plugin
-		pm.plugins[pluginID] = &Plugin{
-			ID:          definition.ID,
-			Name:        definition.Name,
-			Description: definition.Description,
-			Version:     definition.Version,
-			Author:      definition.Author,
-			License:     definition.License,
-			Icon:        definition.Icon,
-			Parameters:  convertParameters(definition.Parameters),
-			Execute:     executeFunc,
+		registered := newPlugin(definition, resolution)
+		if rpcPlugin, ok := plugin.(*RPCPlugin); ok && resolution.Provenance == ProvenanceExternal {
+			registered.Health = rpcPlugin.Health
 		}
+		pm.plugins[pluginID] = registered
 
-		fmt.Printf("Registered plugin: %s (%s)\n", definition.Name, definition.ID)
+		if resolution.Shadowed != "" {
+			fmt.Printf("Registered plugin: %s (%s), running %s code over %s code\n", definition.Name, definition.ID, resolution.Provenance, resolution.Shadowed)
+		} else {
+			fmt.Printf("Registered plugin: %s (%s)\n", definition.Name, definition.ID)
+		}
 	}
 
-	// Register hardcoded plugins if they don't already exist
-	registerIfNotExists := func(plugin *Plugin) {
-		if _, exists := pm.plugins[plugin.ID]; !exists {
-			pm.plugins[plugin.ID] = plugin
-			fmt.Printf("Registering hardcoded plugin: %s\n", plugin.ID)
+	// Register the builtins that describe themselves and were not installed
+	for id, builtin := range registry.Builtins() {
+		if _, exists := pm.plugins[id]; exists || builtin.Definition == nil {
+			continue
 		}
+		pm.plugins[id] = newPlugin(*builtin.Definition, Resolution{Registration: builtin})
+		fmt.Printf("Registering builtin plugin: %s\n", id)
 	}
 
-	// Register network_info plugin
-	registerIfNotExists(&Plugin{
-		ID:          "network_info",
-		Name:        "Network Information",
-		Description: "Displays detailed information about the device's network connections",
-		Version:     "1.0.0",
-		Author:      "NetTool Team",
-		License:     "MIT",
-		Icon:        "network",
-		Parameters:  []Parameter{}, // No parameters needed
-		Execute: func(_ map[string]interface{}) (interface{}, error) {
-			// This plugin is handled directly by the main dashboard
-			return map[string]interface{}{"message": "Network info plugin is handled by the dashboard"}, nil
-		},
-	})
-
 	return nil
 }
 
+// newPlugin creates a plugin from its definition and the registration that runs it
+func newPlugin(definition types.PluginDefinition, resolution Resolution) *Plugin {
+	return &Plugin{
+		ID:            definition.ID,
+		Name:          definition.Name,
+		Description:   definition.Description,
+		Version:       definition.Version,
+		Author:        definition.Author,
+		License:       definition.License,
+		Icon:          definition.Icon,
+		Parameters:    convertParameters(definition.Parameters),
+		Timeout:       definition.Timeout,
+		ExecuteStream: resolution.Execute,
+		Provenance:    resolution.Provenance,
+		Shadowed:      resolution.Shadowed,
+	}
+}
+
 // Helper function to convert parameter types
 func convertParameters(pluginParams []types.PluginParam) []Parameter {
 	parameters := make([]Parameter, len(pluginParams))
@@ -242,6 +345,10 @@ func convertParameters(pluginParams []types.PluginParam) []Parameter {
 			Max:         param.Max,
 			Step:        param.Step,
 			CanIterate:  param.CanIterate,
+			Placeholder: param.Placeholder,
+		}
+		if parameters[i].Placeholder == "" {
+			parameters[i].Placeholder = typePlaceholders[parameters[i].Type]
 		}
 	}
 	return parameters
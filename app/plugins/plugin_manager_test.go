@@ -0,0 +1,59 @@
+package plugins
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPluginRunTimeouts(t *testing.T) {
+	plugin := &Plugin{ID: "slow", Timeout: 1, ExecuteContext: func(ctx context.Context, _ map[string]interface{}) (interface{}, error) {
+		<-ctx.Done()
+		return nil, errors.New("interrupted")
+	}}
+
+	tests := []struct {
+		name    string
+		ctx     func() (context.Context, context.CancelFunc)
+		message string
+		target  error
+	}{
+		{
+			name:    "plugin timeout",
+			ctx:     func() (context.Context, context.CancelFunc) { return context.WithCancel(context.Background()) },
+			message: "plugin slow timed out after 1s",
+			target:  context.DeadlineExceeded,
+		},
+		{
+			name: "caller deadline",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 50*time.Millisecond)
+			},
+			message: "plugin slow stopped",
+			target:  context.DeadlineExceeded,
+		},
+		{
+			name: "caller cancellation",
+			ctx: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				time.AfterFunc(50*time.Millisecond, cancel)
+				return ctx, cancel
+			},
+			message: "plugin slow stopped",
+			target:  context.Canceled,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := tt.ctx()
+			defer cancel()
+
+			_, err := plugin.run(ctx, nil, nil)
+			if err == nil || !strings.HasPrefix(err.Error(), tt.message) || !errors.Is(err, tt.target) {
+				t.Errorf("error %v, want %q matching %v", err, tt.message, tt.target)
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package plugins
+
+import (
+	"context"
+	"os/exec"
+	"syscall"
+)
+
+// commandContext creates a command that runs in its own process group, so
+// that cancelling ctx kills the command together with every process it
+// started, e.g. the binary built by "go run" or the probes of nmap
+func commandContext(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		// A negative PID signals the whole process group
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = commandWaitDelay
+	return cmd
+}
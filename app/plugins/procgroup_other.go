@@ -0,0 +1,17 @@
+//go:build !linux
+
+package plugins
+
+import (
+	"context"
+	"os/exec"
+)
+
+// commandContext creates a command that is killed when ctx is done. Process
+// groups are only used on Linux; elsewhere children of the command may
+// outlive it.
+func commandContext(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.WaitDelay = commandWaitDelay
+	return cmd
+}
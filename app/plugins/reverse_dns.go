@@ -0,0 +1,374 @@
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NetScout-Go/NetTool/app/core"
+	"github.com/NetScout-Go/NetTool/app/plugins/types"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	// maxReverseAddresses limits the addresses of a sweep, a /16 of IPv4
+	maxReverseAddresses = 1 << 16
+	// defaultReverseConcurrency is the number of lookups made at once
+	defaultReverseConcurrency = 32
+	// maxReverseNames limits the PTR names confirmed for an address
+	maxReverseNames = 8
+)
+
+// reverseDNSDefinition describes the reverse DNS lookup, so it is listed without a plugin.json
+var reverseDNSDefinition = types.PluginDefinition{
+	ID:          "reverse_dns_lookup",
+	Name:        "Reverse DNS Lookup",
+	Description: "Looks up the PTR names of addresses or whole networks and checks that the names resolve back to the addresses",
+	Version:     "2.0.0",
+	Author:      "NetTool Team",
+	License:     "MIT",
+	Icon:        "dns",
+	Timeout:     3600, // sweeps of large networks
+	Parameters: []types.PluginParam{
+		{ID: "targets", Name: "Addresses", Description: "Addresses or networks such as 192.168.1.0/24, separated by commas; the addresses in the ARP table when empty", Type: types.TypeString},
+		{ID: "resolver", Name: "Resolver", Description: "Address of the resolver, a host for DoT, or a URL for DoH; the system's resolvers when empty", Type: types.TypeString},
+		{ID: "transport", Name: "Transport", Description: "How the queries are sent", Type: types.TypeSelect, Default: dnsUDP, Options: []types.Option{
+			{Value: dnsUDP, Label: "UDP"},
+			{Value: dnsTCP, Label: "TCP"},
+			{Value: dnsDoT, Label: "DNS over TLS"},
+			{Value: dnsDoH, Label: "DNS over HTTPS"},
+		}},
+		{ID: "verify", Name: "Forward-Confirm", Description: "Check that each name resolves back to the address", Type: types.TypeBoolean, Default: true},
+		{ID: "concurrency", Name: "Concurrency", Description: "Number of addresses looked up at once", Type: types.TypeNumber, Default: defaultReverseConcurrency, Min: types.FloatPtr(1), Max: types.FloatPtr(256), Step: types.FloatPtr(1)},
+		{ID: "includeUnnamed", Name: "Include Unnamed", Description: "Also list the addresses that have no PTR record", Type: types.TypeBoolean, Default: false},
+		{ID: "timeout", Name: "Timeout", Description: "Time to wait for each resolver", Type: types.TypeDuration, Default: "2s", Min: types.FloatPtr(0.1), Max: types.FloatPtr(60)},
+	},
+}
+
+// reversePTRName is a PTR name of an address
+type reversePTRName struct {
+	Name     string   `json:"name"`
+	Forward  []string `json:"forwardAddresses,omitempty"` // addresses the name resolves to
+	Verified bool     `json:"verified"`                   // the name resolves back to the address
+}
+
+// reverseDNSEntry is the reverse lookup of one address. It shares ipAddress,
+// macAddress and device with the entries of core.GetARPTable.
+type reverseDNSEntry struct {
+	IPAddress  string           `json:"ipAddress"`
+	Hostname   string           `json:"hostname,omitempty"` // first verified name, or first name without verification
+	Names      []reversePTRName `json:"names,omitempty"`
+	Verified   bool             `json:"verified"` // forward-confirmed reverse DNS
+	Rcode      string           `json:"rcode,omitempty"`
+	Error      string           `json:"error,omitempty"`
+	MACAddress string           `json:"macAddress,omitempty"`
+	Device     string           `json:"device,omitempty"`
+}
+
+// reverseDNSResult is the result of a reverse lookup
+type reverseDNSResult struct {
+	Targets   []string          `json:"targets"`
+	ARPTable  bool              `json:"arpTable,omitempty"` // the addresses of the ARP table were looked up
+	Resolvers []string          `json:"resolvers"`
+	Transport string            `json:"transport"`
+	Addresses int               `json:"addresses"` // addresses looked up
+	Named     int               `json:"named"`
+	Verified  int               `json:"verified"`
+	Failed    int               `json:"failed"` // addresses no resolver answered for
+	Entries   []reverseDNSEntry `json:"results"`
+	// Hostnames maps the addresses with a name to their hostname, for
+	// naming the entries of the ARP table
+	Hostnames map[string]string `json:"hostnames"`
+	Duration  float64           `json:"durationMs"`
+}
+
+// reverseLookup resolves addresses with a set of resolvers, trying them in order
+type reverseLookup struct {
+	client    dnsClient
+	resolvers []string
+	transport string
+	verify    bool
+}
+
+// executeReverseDNSLookup looks up the names of addresses, lists of addresses
+// or networks, a bounded number at a time
+func executeReverseDNSLookup(ctx context.Context, params map[string]interface{}, emit func(types.Event)) (interface{}, error) {
+	lookup := reverseLookup{transport: dnsUDP, verify: true}
+	if transport, ok := params["transport"].(string); ok && transport != "" {
+		lookup.transport = transport
+	}
+	if verify, ok := params["verify"].(bool); ok {
+		lookup.verify = verify
+	}
+	if timeout, ok := params["timeout"].(float64); ok && timeout > 0 {
+		lookup.client.Timeout = time.Duration(timeout * float64(time.Second))
+	}
+	if resolver, ok := params["resolver"].(string); ok && strings.TrimSpace(resolver) != "" {
+		lookup.resolvers = []string{strings.TrimSpace(resolver)}
+	} else {
+		if lookup.transport == dnsDoH {
+			return nil, fmt.Errorf("a DoH resolver URL is required")
+		}
+		if lookup.resolvers = systemDNSServers(); len(lookup.resolvers) == 0 {
+			return nil, ErrNoDNSServers
+		}
+	}
+
+	concurrency := defaultReverseConcurrency
+	if n, ok := params["concurrency"].(float64); ok && n >= 1 {
+		concurrency = int(n)
+	}
+	includeUnnamed, _ := params["includeUnnamed"].(bool)
+
+	// Entries are joined with the ARP table; without one there is nothing
+	// to add
+	neighbors := make(map[string]core.ARPEntry)
+	if table, err := core.GetARPTable(); err == nil {
+		for _, entry := range table {
+			neighbors[entry.IPAddress] = entry
+		}
+	}
+
+	targets, _ := params["targets"].(string)
+	specs := []string{}
+	var addrs []netip.Addr
+	var err error
+	if strings.TrimSpace(targets) == "" {
+		if len(neighbors) == 0 {
+			return nil, fmt.Errorf("the ARP table is empty; give the addresses to look up")
+		}
+		for ip := range neighbors {
+			if addr, err := netip.ParseAddr(ip); err == nil {
+				addrs = append(addrs, addr)
+			}
+		}
+		sort.Slice(addrs, func(i, j int) bool { return addrs[i].Less(addrs[j]) })
+	} else {
+		specs = splitList(targets)
+		if addrs, err = reverseTargets(specs); err != nil {
+			return nil, err
+		}
+	}
+	// Addresses given one by one are listed even without a name
+	listed := strings.TrimSpace(targets) != "" && !strings.Contains(targets, "/")
+
+	started := time.Now()
+	entries := make([]reverseDNSEntry, len(addrs))
+	jobs := make(chan int)
+	var done int
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for range min(concurrency, len(addrs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				entry := lookup.resolve(ctx, addrs[i])
+				entries[i] = entry
+
+				mu.Lock()
+				done++
+				if entry.Hostname != "" {
+					line := fmt.Sprintf("%-39s %s", entry.IPAddress, entry.Hostname)
+					if entry.Verified {
+						line += " (verified)"
+					} else if lookup.verify {
+						line += " (not forward-confirmed)"
+					}
+					emit(types.Event{Type: types.EventLine, Line: line})
+				}
+				emit(types.Event{Type: types.EventProgress, Progress: float64(done) * 100 / float64(len(addrs))})
+				mu.Unlock()
+			}
+		}()
+	}
+feed:
+	for i := range addrs {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	result := &reverseDNSResult{
+		Targets:   specs,
+		ARPTable:  len(specs) == 0,
+		Resolvers: lookup.resolvers,
+		Transport: lookup.transport,
+		Addresses: len(addrs),
+		Entries:   []reverseDNSEntry{},
+		Hostnames: make(map[string]string),
+	}
+	for _, entry := range entries {
+		if neighbor, ok := neighbors[entry.IPAddress]; ok {
+			entry.MACAddress = neighbor.MACAddress
+			entry.Device = neighbor.Device
+		}
+		switch {
+		case entry.Error != "":
+			result.Failed++
+		case entry.Hostname != "":
+			result.Named++
+			result.Hostnames[entry.IPAddress] = entry.Hostname
+			if entry.Verified {
+				result.Verified++
+			}
+		}
+		if entry.Hostname != "" || entry.Error != "" || includeUnnamed || listed {
+			result.Entries = append(result.Entries, entry)
+		}
+	}
+	result.Duration = float64(time.Since(started).Microseconds()) / 1000
+
+	emit(types.Event{Type: types.EventLine, Line: fmt.Sprintf("%d of %d addresses named, %d forward-confirmed, %d failed",
+		result.Named, result.Addresses, result.Verified, result.Failed)})
+	return result, nil
+}
+
+// reverseTargets returns the addresses of a list of addresses and networks.
+// The network and broadcast addresses of IPv4 networks are left out.
+func reverseTargets(specs []string) ([]netip.Addr, error) {
+	seen := make(map[netip.Addr]bool)
+	var addrs []netip.Addr
+	add := func(addr netip.Addr) error {
+		if seen[addr] {
+			return nil
+		}
+		if len(addrs) == maxReverseAddresses {
+			return fmt.Errorf("at most %d addresses can be looked up at once", maxReverseAddresses)
+		}
+		seen[addr] = true
+		addrs = append(addrs, addr)
+		return nil
+	}
+
+	for _, spec := range specs {
+		if addr, err := netip.ParseAddr(strings.Trim(spec, "[]")); err == nil {
+			if err := add(addr.Unmap()); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(spec)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an IP address or a network such as 192.168.1.0/24", spec)
+		}
+		prefix = prefix.Masked()
+		hostBits := prefix.Addr().BitLen() - prefix.Bits()
+		if hostBits > 16 {
+			return nil, fmt.Errorf("network %s is larger than the %d addresses that can be looked up at once", prefix, maxReverseAddresses)
+		}
+
+		first, last := prefix.Addr(), lastAddr(prefix)
+		if prefix.Addr().Is4() && hostBits > 1 {
+			first, last = first.Next(), last.Prev()
+		}
+		for addr := first; addr.IsValid() && !last.Less(addr); addr = addr.Next() {
+			if err := add(addr); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no addresses to look up")
+	}
+	return addrs, nil
+}
+
+// lastAddr returns the last address of a masked prefix
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	b := prefix.Addr().AsSlice()
+	for i := prefix.Bits(); i < len(b)*8; i++ {
+		b[i/8] |= 0x80 >> (i % 8)
+	}
+	addr, _ := netip.AddrFromSlice(b)
+	return addr
+}
+
+// exchange sends a query to the resolvers in order until one answers
+func (l *reverseLookup) exchange(ctx context.Context, name string, typ dnsmessage.Type) (*dnsResponse, error) {
+	var err error
+	for _, resolver := range l.resolvers {
+		var resp *dnsResponse
+		resp, err = l.client.Exchange(ctx, dnsQuery{Name: name, Type: typ, Resolver: resolver, Transport: l.transport})
+		if err == nil {
+			return resp, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+	return nil, err
+}
+
+// resolve looks up the names of an address and confirms them
+func (l *reverseLookup) resolve(ctx context.Context, addr netip.Addr) reverseDNSEntry {
+	entry := reverseDNSEntry{IPAddress: addr.String()}
+	resp, err := l.exchange(ctx, reverseDNSName(addr), dnsmessage.TypePTR)
+	if err != nil {
+		entry.Error = err.Error()
+		return entry
+	}
+	entry.Rcode = resp.Rcode
+	// Only NXDOMAIN and NOERROR tell whether the address has a name
+	if resp.Rcode != "NOERROR" && resp.Rcode != "NXDOMAIN" {
+		entry.Error = fmt.Sprintf("%s answered %s", resp.Resolver, resp.Rcode)
+		return entry
+	}
+
+	for _, record := range resp.Answers {
+		if record.Type != "PTR" || len(entry.Names) == maxReverseNames {
+			continue
+		}
+		name := reversePTRName{Name: strings.TrimSuffix(record.Data, ".")}
+		if l.verify {
+			l.confirm(ctx, addr, &name)
+			if name.Verified && !entry.Verified {
+				entry.Verified = true
+				entry.Hostname = name.Name
+			}
+		}
+		entry.Names = append(entry.Names, name)
+	}
+	if entry.Hostname == "" && len(entry.Names) > 0 {
+		entry.Hostname = entry.Names[0].Name
+	}
+	return entry
+}
+
+// confirm looks up the addresses of a PTR name, of the family of addr, and
+// whether addr is one of them
+func (l *reverseLookup) confirm(ctx context.Context, addr netip.Addr, name *reversePTRName) {
+	typ, recordType := dnsmessage.TypeA, "A"
+	if addr.Is6() {
+		typ, recordType = dnsmessage.TypeAAAA, "AAAA"
+	}
+	resp, err := l.exchange(ctx, name.Name, typ)
+	if err != nil {
+		return
+	}
+	for _, record := range resp.Answers {
+		if record.Type != recordType {
+			continue
+		}
+		forward, err := netip.ParseAddr(record.Data)
+		if err != nil {
+			continue
+		}
+		name.Forward = append(name.Forward, forward.String())
+		if forward == addr {
+			name.Verified = true
+		}
+	}
+}
@@ -0,0 +1,620 @@
+package plugins
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NetScout-Go/NetTool/app/plugins/types"
+)
+
+// RPCProtocol is the protocol of out-of-process plugins: JSON-RPC 2.0
+// messages, one per line, on the plugin's stdin and stdout.
+//
+// The host sends the requests
+//
+//	initialize {"protocolVersion": 1}          -> {"protocolVersion": 1}
+//	definition                                 -> a plugin.json style definition
+//	execute    {"params": {...}}               -> the plugin's result
+//	health                                     -> {"status": "ok"}
+//
+// and the notifications "cancel" {"id": <id of an execute request>} and
+// "shutdown". While executing, the plugin may send the notification
+// "event" {"id": <id of the execute request>, "event": {"type": "line", ...}}.
+const RPCProtocol = "jsonrpc-stdio/1"
+
+// rpcProtocolVersion is the version negotiated by initialize
+const rpcProtocolVersion = 1
+
+// Lifetimes of plugin worker processes
+const (
+	rpcStartTimeout   = 10 * time.Second // to answer initialize
+	rpcCancelGrace    = 5 * time.Second  // to answer a cancelled request before the worker is killed
+	rpcShutdownGrace  = 2 * time.Second  // to exit after shutdown
+	rpcIdleTimeout    = 5 * time.Minute  // idle workers are stopped after this long
+	rpcStderrTailSize = 4096             // bytes of stderr kept for error messages
+)
+
+// ErrWorkerExited is returned for requests to a plugin process that exited
+var ErrWorkerExited = errors.New("plugin process exited")
+
+// rpcMessage is a JSON-RPC 2.0 request, response or notification
+type rpcMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *int64          `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+// RPCError is an error returned by an out-of-process plugin
+type RPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *RPCError) Error() string {
+	return e.Message
+}
+
+// rpcEventParams are the params of an event notification
+type rpcEventParams struct {
+	ID    int64       `json:"id"`
+	Event types.Event `json:"event"`
+}
+
+// rpcCall is a request waiting for its response
+type rpcCall struct {
+	response chan rpcMessage
+	emit     func(types.Event)
+}
+
+// rpcWorker is one running plugin process
+type rpcWorker struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	kill  context.CancelFunc
+
+	writeMu sync.Mutex
+
+	mu       sync.Mutex
+	pending  map[int64]*rpcCall
+	nextID   int64
+	stderr   []byte // tail
+	exitErr  error
+	lastUsed time.Time
+
+	exited chan struct{}
+}
+
+// startRPCWorker starts a plugin process and negotiates the protocol version
+func startRPCWorker(dir string, runtime types.Runtime) (*rpcWorker, error) {
+	command := runtime.Command
+	if strings.Contains(command, "/") && !filepath.IsAbs(command) {
+		command = filepath.Join(dir, command)
+	}
+
+	ctx, kill := context.WithCancel(context.Background())
+	cmd := commandContext(ctx, command, runtime.Args...)
+	cmd.Dir = dir
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		kill()
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		kill()
+		return nil, err
+	}
+
+	w := &rpcWorker{
+		cmd:      cmd,
+		stdin:    stdin,
+		kill:     kill,
+		pending:  make(map[int64]*rpcCall),
+		lastUsed: time.Now(),
+		exited:   make(chan struct{}),
+	}
+	cmd.Stderr = stderrTail{w}
+
+	if err := cmd.Start(); err != nil {
+		kill()
+		return nil, fmt.Errorf("failed to start %s: %v", runtime.Command, err)
+	}
+	go w.readLoop(stdout)
+
+	startCtx, cancel := context.WithTimeout(context.Background(), rpcStartTimeout)
+	defer cancel()
+
+	var init struct {
+		ProtocolVersion int `json:"protocolVersion"`
+	}
+	raw, err := w.call(startCtx, "initialize", map[string]int{"protocolVersion": rpcProtocolVersion}, nil)
+	if err == nil {
+		err = json.Unmarshal(raw, &init)
+	}
+	if err == nil && init.ProtocolVersion != rpcProtocolVersion {
+		err = fmt.Errorf("unsupported protocol version %d", init.ProtocolVersion)
+	}
+	if err != nil {
+		w.stop()
+		return nil, fmt.Errorf("failed to initialize %s: %v", runtime.Command, err)
+	}
+
+	return w, nil
+}
+
+// stderrTail keeps the end of a worker's stderr for error messages
+type stderrTail struct {
+	w *rpcWorker
+}
+
+func (t stderrTail) Write(p []byte) (int, error) {
+	t.w.mu.Lock()
+	defer t.w.mu.Unlock()
+
+	t.w.stderr = append(t.w.stderr, p...)
+	if len(t.w.stderr) > rpcStderrTailSize {
+		t.w.stderr = t.w.stderr[len(t.w.stderr)-rpcStderrTailSize:]
+	}
+	return len(p), nil
+}
+
+// readLoop dispatches responses and events until the process closes stdout
+func (w *rpcWorker) readLoop(stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), maxOutputLine)
+
+	for scanner.Scan() {
+		var msg rpcMessage
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			// Stray output, e.g. from a library printing to stdout
+			continue
+		}
+
+		switch {
+		case msg.Method == "event":
+			var params rpcEventParams
+			if err := json.Unmarshal(msg.Params, &params); err != nil {
+				continue
+			}
+			w.mu.Lock()
+			call := w.pending[params.ID]
+			w.mu.Unlock()
+			if call != nil && call.emit != nil {
+				call.emit(params.Event)
+			}
+
+		case msg.ID != nil && msg.Method == "":
+			w.mu.Lock()
+			call := w.pending[*msg.ID]
+			delete(w.pending, *msg.ID)
+			w.mu.Unlock()
+			if call != nil {
+				call.response <- msg
+			}
+		}
+	}
+
+	// A line longer than maxOutputLine stops the scanner, and the plugin
+	// would block on a full pipe; stop it and drain the pipe so Wait returns
+	scanErr := scanner.Err()
+	if scanErr != nil {
+		w.kill()
+		io.Copy(io.Discard, stdout)
+	}
+
+	err := w.cmd.Wait()
+
+	w.mu.Lock()
+	switch {
+	case scanErr != nil:
+		w.exitErr = fmt.Errorf("%w: failed to read its output: %v", ErrWorkerExited, scanErr)
+	case err != nil:
+		w.exitErr = fmt.Errorf("%w: %v", ErrWorkerExited, err)
+	default:
+		w.exitErr = ErrWorkerExited
+	}
+	if tail := strings.TrimSpace(string(w.stderr)); tail != "" {
+		w.exitErr = fmt.Errorf("%w\nOutput: %s", w.exitErr, tail)
+	}
+	w.mu.Unlock()
+
+	close(w.exited)
+}
+
+// alive reports whether the process is still running
+func (w *rpcWorker) alive() bool {
+	select {
+	case <-w.exited:
+		return false
+	default:
+		return true
+	}
+}
+
+func (w *rpcWorker) send(msg rpcMessage) error {
+	msg.JSONRPC = "2.0"
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	w.writeMu.Lock()
+	defer w.writeMu.Unlock()
+	_, err = w.stdin.Write(append(data, '\n'))
+	return err
+}
+
+func (w *rpcWorker) notify(method string, params interface{}) error {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return w.send(rpcMessage{Method: method, Params: raw})
+}
+
+// call sends a request and waits for its response. When ctx is done first,
+// the plugin is asked to cancel the request and killed if it does not answer
+// within rpcCancelGrace.
+func (w *rpcWorker) call(ctx context.Context, method string, params interface{}, emit func(types.Event)) (json.RawMessage, error) {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal parameters: %v", err)
+	}
+
+	call := &rpcCall{response: make(chan rpcMessage, 1), emit: emit}
+	w.mu.Lock()
+	w.nextID++
+	id := w.nextID
+	w.pending[id] = call
+	w.mu.Unlock()
+
+	forget := func() {
+		w.mu.Lock()
+		delete(w.pending, id)
+		w.mu.Unlock()
+	}
+
+	if err := w.send(rpcMessage{ID: &id, Method: method, Params: raw}); err != nil {
+		forget()
+		return nil, w.failure(err)
+	}
+
+	select {
+	case msg := <-call.response:
+		if msg.Error != nil {
+			return nil, msg.Error
+		}
+		return msg.Result, nil
+
+	case <-w.exited:
+		forget()
+		return nil, w.failure(nil)
+
+	case <-ctx.Done():
+	}
+
+	// Give the plugin a chance to stop cleanly before killing it
+	w.notify("cancel", map[string]int64{"id": id})
+	select {
+	case <-call.response:
+	case <-w.exited:
+	case <-time.After(rpcCancelGrace):
+		w.kill()
+	}
+	forget()
+	return nil, ctx.Err()
+}
+
+// failure returns the exit error of the worker, if it exited, or err
+func (w *rpcWorker) failure(err error) error {
+	select {
+	case <-w.exited:
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		return w.exitErr
+	default:
+		return err
+	}
+}
+
+// stop asks the plugin to shut down and kills it if it does not exit in time
+func (w *rpcWorker) stop() {
+	w.notify("shutdown", struct{}{})
+	w.stdin.Close()
+
+	select {
+	case <-w.exited:
+	case <-time.After(rpcShutdownGrace):
+		w.kill()
+		<-w.exited
+	}
+	w.kill()
+}
+
+// RPCPlugin is an out-of-process plugin speaking RPCProtocol. Its processes
+// are started on demand, reused across runs and stopped when idle.
+type RPCPlugin struct {
+	id      string
+	dir     string
+	runtime types.Runtime
+	slots   chan struct{}
+
+	mu         sync.Mutex
+	definition *types.PluginDefinition
+	idle       []*rpcWorker
+	closed     bool
+}
+
+// NewRPCPlugin creates an out-of-process plugin from its definition
+func NewRPCPlugin(dir string, definition types.PluginDefinition) (*RPCPlugin, error) {
+	if definition.Runtime == nil {
+		return nil, fmt.Errorf("plugin %s has no runtime", definition.ID)
+	}
+	runtime := *definition.Runtime
+	if runtime.Protocol != RPCProtocol {
+		return nil, fmt.Errorf("plugin %s: unsupported protocol %q, expected %q", definition.ID, runtime.Protocol, RPCProtocol)
+	}
+	if runtime.Command == "" {
+		return nil, fmt.Errorf("plugin %s: runtime command is missing", definition.ID)
+	}
+	if runtime.MaxWorkers <= 0 {
+		runtime.MaxWorkers = 1
+	}
+
+	return &RPCPlugin{
+		id:         definition.ID,
+		dir:        dir,
+		runtime:    runtime,
+		slots:      make(chan struct{}, runtime.MaxWorkers),
+		definition: &definition,
+	}, nil
+}
+
+// GetDefinition returns the plugin definition from plugin.json. When that
+// does not name the plugin, the definition is requested from the process.
+func (p *RPCPlugin) GetDefinition() types.PluginDefinition {
+	p.mu.Lock()
+	definition := *p.definition
+	p.mu.Unlock()
+
+	if definition.Name != "" {
+		return definition
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), rpcStartTimeout)
+	defer cancel()
+
+	var fetched types.PluginDefinition
+	raw, err := p.call(ctx, "definition", struct{}{}, nil)
+	if err == nil {
+		err = json.Unmarshal(raw, &fetched)
+	}
+	if err != nil {
+		fmt.Printf("Error getting plugin definition for %s: %v\n", p.id, err)
+		definition.Name = p.id
+		return definition
+	}
+
+	// plugin.json decides how the plugin is identified and started
+	fetched.ID = definition.ID
+	fetched.Runtime = definition.Runtime
+	if definition.Timeout > 0 {
+		fetched.Timeout = definition.Timeout
+	}
+
+	p.mu.Lock()
+	p.definition = &fetched
+	p.mu.Unlock()
+	return fetched
+}
+
+// Execute runs the plugin with the given parameters
+func (p *RPCPlugin) Execute(params map[string]interface{}) (interface{}, error) {
+	return p.ExecuteContext(context.Background(), params)
+}
+
+// ExecuteContext runs the plugin with the given parameters until ctx is done
+func (p *RPCPlugin) ExecuteContext(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	return p.ExecuteStream(ctx, params, func(types.Event) {})
+}
+
+// ExecuteStream runs the plugin until ctx is done, passing its event
+// notifications to emit
+func (p *RPCPlugin) ExecuteStream(ctx context.Context, params map[string]interface{}, emit func(types.Event)) (interface{}, error) {
+	raw, err := p.call(ctx, "execute", map[string]interface{}{"params": params}, emit)
+	if err != nil {
+		return nil, err
+	}
+
+	var result interface{}
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return nil, fmt.Errorf("plugin %s returned an invalid result: %v", p.id, err)
+	}
+	return result, nil
+}
+
+// Health asks a plugin process whether it is able to serve requests
+func (p *RPCPlugin) Health(ctx context.Context) error {
+	raw, err := p.call(ctx, "health", struct{}{}, nil)
+	if err != nil {
+		return err
+	}
+
+	var health struct {
+		Status  string `json:"status"`
+		Message string `json:"message,omitempty"`
+	}
+	if err := json.Unmarshal(raw, &health); err != nil {
+		return fmt.Errorf("invalid health response: %v", err)
+	}
+	if health.Status != "ok" {
+		return fmt.Errorf("plugin %s is %s: %s", p.id, health.Status, health.Message)
+	}
+	return nil
+}
+
+// call runs a request on an idle or newly started worker
+func (p *RPCPlugin) call(ctx context.Context, method string, params interface{}, emit func(types.Event)) (json.RawMessage, error) {
+	select {
+	case p.slots <- struct{}{}:
+		defer func() { <-p.slots }()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	w, err := p.acquire()
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := w.call(ctx, method, params, emit)
+	p.release(w)
+	return result, err
+}
+
+// acquire returns an idle worker or starts a new one
+func (p *RPCPlugin) acquire() (*rpcWorker, error) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, fmt.Errorf("plugin %s was unloaded", p.id)
+	}
+	for len(p.idle) > 0 {
+		w := p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+		if w.alive() {
+			p.mu.Unlock()
+			return w, nil
+		}
+	}
+	p.mu.Unlock()
+
+	return startRPCWorker(p.dir, p.runtime)
+}
+
+// release returns a worker to the idle list, or stops it when the plugin was
+// unloaded in the meantime
+func (p *RPCPlugin) release(w *rpcWorker) {
+	if !w.alive() {
+		return
+	}
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		go w.stop()
+		return
+	}
+	w.lastUsed = time.Now()
+	p.idle = append(p.idle, w)
+	p.mu.Unlock()
+
+	time.AfterFunc(rpcIdleTimeout, p.stopIdle)
+}
+
+// stopIdle stops workers that have not been used for rpcIdleTimeout
+func (p *RPCPlugin) stopIdle() {
+	p.mu.Lock()
+	var keep, stale []*rpcWorker
+	for _, w := range p.idle {
+		if time.Since(w.lastUsed) >= rpcIdleTimeout {
+			stale = append(stale, w)
+		} else {
+			keep = append(keep, w)
+		}
+	}
+	p.idle = keep
+	p.mu.Unlock()
+
+	for _, w := range stale {
+		w.stop()
+	}
+}
+
+// Close stops the idle workers; workers serving a run are stopped once it ends
+func (p *RPCPlugin) Close() {
+	p.mu.Lock()
+	p.closed = true
+	idle := p.idle
+	p.idle = nil
+	p.mu.Unlock()
+
+	for _, w := range idle {
+		w.stop()
+	}
+}
+
+// The out-of-process plugins loaded so far, by directory, so that reloading
+// the plugin list keeps their running workers
+var (
+	rpcPluginsMu sync.Mutex
+	rpcPlugins   = make(map[string]*RPCPlugin)
+)
+
+// loadRPCPlugin returns the out-of-process plugin in dir, reusing the loaded
+// one unless its runtime changed
+func loadRPCPlugin(dir string, definition types.PluginDefinition) (*RPCPlugin, error) {
+	rpcPluginsMu.Lock()
+	defer rpcPluginsMu.Unlock()
+
+	if existing, ok := rpcPlugins[dir]; ok {
+		if definition.Runtime != nil && sameRuntime(existing.runtime, *definition.Runtime) {
+			existing.mu.Lock()
+			existing.definition = &definition
+			existing.mu.Unlock()
+			return existing, nil
+		}
+		existing.Close()
+		delete(rpcPlugins, dir)
+	}
+
+	p, err := NewRPCPlugin(dir, definition)
+	if err != nil {
+		return nil, err
+	}
+	rpcPlugins[dir] = p
+	return p, nil
+}
+
+// loadedRPCPlugin returns the out-of-process plugin loaded from dir, if any
+func loadedRPCPlugin(dir string) *RPCPlugin {
+	rpcPluginsMu.Lock()
+	defer rpcPluginsMu.Unlock()
+	return rpcPlugins[dir]
+}
+
+// closeRPCPluginsExcept unloads the out-of-process plugins in pluginsDir
+// whose directory is not in keep
+func closeRPCPluginsExcept(pluginsDir string, keep map[string]bool) {
+	rpcPluginsMu.Lock()
+	defer rpcPluginsMu.Unlock()
+
+	for dir, p := range rpcPlugins {
+		if filepath.Dir(dir) == filepath.Clean(pluginsDir) && !keep[dir] {
+			p.Close()
+			delete(rpcPlugins, dir)
+		}
+	}
+}
+
+func sameRuntime(a, b types.Runtime) bool {
+	if b.MaxWorkers <= 0 {
+		b.MaxWorkers = 1
+	}
+	return a.Protocol == b.Protocol && a.Command == b.Command &&
+		a.MaxWorkers == b.MaxWorkers && strings.Join(a.Args, "\x00") == strings.Join(b.Args, "\x00")
+}
@@ -0,0 +1,39 @@
+package plugins
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NetScout-Go/NetTool/app/plugins/types"
+)
+
+func TestRPCWorkerStopsOnOverlongLine(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no shell to run the plugin")
+	}
+
+	// Answers initialize, then writes one line that never ends
+	const script = `read request
+echo '{"jsonrpc":"2.0","id":1,"result":{"protocolVersion":1}}'
+read request
+exec cat /dev/zero`
+	w, err := startRPCWorker(t.TempDir(), types.Runtime{Command: "sh", Args: []string{"-c", script}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.kill()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err = w.call(ctx, "execute", map[string]interface{}{"params": nil}, nil)
+	if !errors.Is(err, ErrWorkerExited) || !strings.Contains(err.Error(), "too long") {
+		t.Errorf("error %v, want the worker to exit on the overlong line", err)
+	}
+	if w.alive() {
+		t.Error("worker still running")
+	}
+}
@@ -0,0 +1,244 @@
+package plugins
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limits of the commands run by the builtin diagnostics
+const (
+	defaultCommandTimeout   = 2 * time.Minute
+	defaultMaxCommandOutput = 1024 * 1024 // bytes kept of stdout and of stderr
+)
+
+// Command execution errors
+var (
+	ErrCommandNotAllowed = errors.New("command not allowed")
+	ErrArgumentRejected  = errors.New("argument rejected")
+	ErrCommandNotFound   = errors.New("command not found")
+)
+
+// commandFlags are the commands the builtins may run and, for each, the
+// literal arguments (flags and subcommands) they may pass. Everything else
+// must be a typed value, see CommandArg.
+var commandFlags = map[string][]string{
+	"nmap":           {"-p", "-Pn", "-sT", "-T4", "--open"},
+	"librespeed-cli": {"--json"},
+	"speedtest":      {"--accept-license", "--accept-gdpr", "--format=json"},
+	"speedtest-cli":  {"--json"},
+	"ip":             {"link", "show"},
+	"iw":             {"dev", "scan"},
+	"iwlist":         {"scanning"},
+}
+
+// argKind is what a CommandArg holds
+type argKind int
+
+const (
+	argLiteral argKind = iota
+	argHost
+	argInt
+	argPorts
+	argInterface
+)
+
+// CommandArg is one argument of a safe command: a literal from the command's
+// allow-list or a value checked for its kind
+type CommandArg struct {
+	kind  argKind
+	value string
+}
+
+// Flag is a literal argument, which must be allow-listed for the command
+func Flag(flag string) CommandArg { return CommandArg{kind: argLiteral, value: flag} }
+
+// HostArg is a host name or IP address
+func HostArg(host string) CommandArg { return CommandArg{kind: argHost, value: host} }
+
+// IntArg is an integer
+func IntArg(n int) CommandArg { return CommandArg{kind: argInt, value: strconv.Itoa(n)} }
+
+// PortsArg is a list of ports and port ranges such as 22,80,8000-8100
+func PortsArg(ports string) CommandArg { return CommandArg{kind: argPorts, value: ports} }
+
+// InterfaceArg is the name of a network interface
+func InterfaceArg(name string) CommandArg { return CommandArg{kind: argInterface, value: name} }
+
+var interfaceNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.:@-]{1,15}$`)
+
+// check validates the argument for command
+func (a CommandArg) check(command string) error {
+	// Values are never taken for options of the command
+	if a.kind != argLiteral && strings.HasPrefix(a.value, "-") {
+		return fmt.Errorf("%w: %q looks like an option", ErrArgumentRejected, a.value)
+	}
+
+	switch a.kind {
+	case argLiteral:
+		for _, allowed := range commandFlags[command] {
+			if a.value == allowed {
+				return nil
+			}
+		}
+		return fmt.Errorf("%w: %s does not allow %q", ErrArgumentRejected, command, a.value)
+	case argHost:
+		if _, err := toHostname(a.value); err != nil {
+			return fmt.Errorf("%w: %v", ErrArgumentRejected, err)
+		}
+	case argInt:
+		// Built by IntArg, always valid
+	case argPorts:
+		if _, err := ParsePortList(a.value); err != nil {
+			return fmt.Errorf("%w: %v", ErrArgumentRejected, err)
+		}
+	case argInterface:
+		if !interfaceNamePattern.MatchString(a.value) {
+			return fmt.Errorf("%w: %q is not an interface name", ErrArgumentRejected, a.value)
+		}
+	}
+	return nil
+}
+
+// SafeCommand runs an allow-listed binary with checked arguments, never
+// through a shell, with a timeout and bounded output
+type SafeCommand struct {
+	Name      string        // allow-listed binary, looked up on PATH
+	Args      []CommandArg  // checked against the allow-list before running
+	Sudo      bool          // run through "sudo -n", failing rather than prompting for a password
+	Timeout   time.Duration // zero for defaultCommandTimeout
+	MaxOutput int           // bytes kept of stdout and of stderr, zero for defaultMaxCommandOutput
+}
+
+// CommandResult is the outcome of a command that ran
+type CommandResult struct {
+	Command   string `json:"command"` // for display only
+	Stdout    string `json:"stdout"`
+	Stderr    string `json:"stderr,omitempty"`
+	ExitCode  int    `json:"exitCode"`
+	Truncated bool   `json:"truncated,omitempty"` // output beyond MaxOutput was dropped
+}
+
+// NewSafeCommand creates a command running name with args
+func NewSafeCommand(name string, args ...CommandArg) *SafeCommand {
+	return &SafeCommand{Name: name, Args: args}
+}
+
+// argv checks the command and returns its arguments
+func (c *SafeCommand) argv() ([]string, error) {
+	if _, ok := commandFlags[c.Name]; !ok {
+		return nil, fmt.Errorf("%w: %s", ErrCommandNotAllowed, c.Name)
+	}
+
+	args := make([]string, len(c.Args))
+	for i, arg := range c.Args {
+		if err := arg.check(c.Name); err != nil {
+			return nil, err
+		}
+		args[i] = arg.value
+	}
+	return args, nil
+}
+
+// String returns the command line for display
+func (c *SafeCommand) String() string {
+	parts := []string{c.Name}
+	if c.Sudo {
+		parts = []string{"sudo", "-n", c.Name}
+	}
+	for _, arg := range c.Args {
+		parts = append(parts, arg.value)
+	}
+	return strings.Join(parts, " ")
+}
+
+// Run runs the command until it exits, ctx is done or its timeout passes.
+// A command that ran and exited with a non-zero status returns its result
+// along with the error.
+func (c *SafeCommand) Run(ctx context.Context) (CommandResult, error) {
+	result := CommandResult{Command: c.String(), ExitCode: -1}
+
+	args, err := c.argv()
+	if err != nil {
+		return result, err
+	}
+
+	binary, err := exec.LookPath(c.Name)
+	if err != nil {
+		return result, fmt.Errorf("%w: %s is not installed", ErrCommandNotFound, c.Name)
+	}
+	if c.Sudo {
+		sudo, err := exec.LookPath("sudo")
+		if err != nil {
+			return result, fmt.Errorf("%w: sudo is not installed", ErrCommandNotFound)
+		}
+		args = append([]string{"-n", binary}, args...)
+		binary = sudo
+	}
+
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = defaultCommandTimeout
+	}
+	maxOutput := c.MaxOutput
+	if maxOutput <= 0 {
+		maxOutput = defaultMaxCommandOutput
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := commandContext(runCtx, binary, args...)
+	stdout := &cappedBuffer{limit: maxOutput}
+	stderr := &cappedBuffer{limit: maxOutput}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	err = cmd.Run()
+	result.Stdout = stdout.String()
+	result.Stderr = stderr.String()
+	result.Truncated = stdout.truncated || stderr.truncated
+	if cmd.ProcessState != nil {
+		result.ExitCode = cmd.ProcessState.ExitCode()
+	}
+
+	switch {
+	case ctx.Err() != nil:
+		return result, ctx.Err()
+	case runCtx.Err() != nil:
+		return result, fmt.Errorf("%s timed out after %v", c.Name, timeout)
+	case err != nil:
+		return result, formatCommandError(c.Name, err, result.Stderr+result.Stdout)
+	}
+	return result, nil
+}
+
+// cappedBuffer keeps the first limit bytes written to it and drops the rest.
+// The buffer is not embedded, so that io.Copy cannot bypass Write through
+// bytes.Buffer's ReadFrom.
+type cappedBuffer struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.buf.Len(); room < len(p) {
+		b.truncated = true
+		if room > 0 {
+			b.buf.Write(p[:room])
+		}
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+func (b *cappedBuffer) String() string {
+	return b.buf.String()
+}
@@ -0,0 +1,846 @@
+package plugins
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NetScout-Go/NetTool/app/plugins/types"
+	"golang.org/x/crypto/ocsp"
+)
+
+// STARTTLS protocols, negotiated in clear text before the handshake
+const (
+	startTLSNone     = "none"
+	startTLSSMTP     = "smtp"
+	startTLSIMAP     = "imap"
+	startTLSPOP3     = "pop3"
+	startTLSFTP      = "ftp"
+	startTLSLDAP     = "ldap"
+	startTLSPostgres = "postgres"
+)
+
+const (
+	defaultTLSTimeout = 10 * time.Second
+	// tlsScanConcurrency is the number of handshakes made at once while
+	// scanning the cipher suites
+	tlsScanConcurrency = 8
+	// certificateExpiryWarning is how long before its expiry a certificate is flagged
+	certificateExpiryWarning = 30 * 24 * time.Hour
+	// maxStartTLSReply limits the replies read before the handshake
+	maxStartTLSReply = 64 * 1024
+)
+
+// startTLSPorts are the usual ports of the protocols
+var startTLSPorts = map[string]int{
+	startTLSNone:     443,
+	startTLSSMTP:     587,
+	startTLSIMAP:     143,
+	startTLSPOP3:     110,
+	startTLSFTP:      21,
+	startTLSLDAP:     389,
+	startTLSPostgres: 5432,
+}
+
+// tlsVersions are the protocol versions checked, oldest first
+var tlsVersions = []uint16{tls.VersionTLS10, tls.VersionTLS11, tls.VersionTLS12, tls.VersionTLS13}
+
+// sslCheckerDefinition describes the TLS checker, so it is listed without a plugin.json
+var sslCheckerDefinition = types.PluginDefinition{
+	ID:          "ssl_checker",
+	Name:        "SSL/TLS Checker",
+	Description: "Inspects a server's certificate chain, its validity and names, and the protocol versions and cipher suites the server accepts",
+	Version:     "2.0.0",
+	Author:      "NetTool Team",
+	License:     "MIT",
+	Icon:        "lock",
+	Timeout:     600, // a handshake per cipher suite
+	Parameters: []types.PluginParam{
+		{ID: "host", Name: "Host", Description: "Server to check", Type: types.TypeHostname, Required: true},
+		{ID: "port", Name: "Port", Description: "Port of the server; the usual port of the protocol when empty", Type: types.TypePort},
+		{ID: "serverName", Name: "Server Name", Description: "Name sent in SNI and checked against the certificate; the host when empty", Type: types.TypeString},
+		{ID: "starttls", Name: "STARTTLS", Description: "Protocol to upgrade to TLS, for servers that start in clear text", Type: types.TypeSelect, Default: startTLSNone, Options: []types.Option{
+			{Value: startTLSNone, Label: "None (TLS from the start)"},
+			{Value: startTLSSMTP, Label: "SMTP"},
+			{Value: startTLSIMAP, Label: "IMAP"},
+			{Value: startTLSPOP3, Label: "POP3"},
+			{Value: startTLSFTP, Label: "FTP"},
+			{Value: startTLSLDAP, Label: "LDAP"},
+			{Value: startTLSPostgres, Label: "PostgreSQL"},
+		}},
+		{ID: "caBundle", Name: "CA Bundle", Description: "PEM certificates to validate the chain against instead of the system's", Type: types.TypeFile},
+		{ID: "scan", Name: "Scan Protocols", Description: "Try every protocol version and cipher suite", Type: types.TypeBoolean, Default: true},
+		{ID: "timeout", Name: "Timeout", Description: "Time to wait for each connection", Type: types.TypeDuration, Default: "10s", Min: types.FloatPtr(0.5), Max: types.FloatPtr(120)},
+	},
+}
+
+// tlsCheckOptions are the parameters of a TLS check
+type tlsCheckOptions struct {
+	Host       string
+	Port       int
+	ServerName string // sent in SNI, empty for addresses
+	VerifyName string // checked against the certificate
+	StartTLS   string
+	Roots      *x509.CertPool // nil for the system's
+	CABundle   string
+	Scan       bool
+	Timeout    time.Duration
+}
+
+// tlsCertificate describes a certificate of the chain
+type tlsCertificate struct {
+	Subject            string    `json:"subject"`
+	Issuer             string    `json:"issuer"`
+	SerialNumber       string    `json:"serialNumber"`
+	NotBefore          time.Time `json:"notBefore"`
+	NotAfter           time.Time `json:"notAfter"`
+	DaysRemaining      int       `json:"daysRemaining"`
+	Expired            bool      `json:"expired"`
+	NotYetValid        bool      `json:"notYetValid,omitempty"`
+	DNSNames           []string  `json:"dnsNames,omitempty"`
+	IPAddresses        []string  `json:"ipAddresses,omitempty"`
+	KeyType            string    `json:"keyType"`
+	KeyBits            int       `json:"keyBits"`
+	Curve              string    `json:"curve,omitempty"`
+	SignatureAlgorithm string    `json:"signatureAlgorithm"`
+	IsCA               bool      `json:"isCA"`
+	SelfSigned         bool      `json:"selfSigned"`
+	OCSPServers        []string  `json:"ocspServers,omitempty"`
+	Fingerprint        string    `json:"sha256Fingerprint"`
+}
+
+// tlsValidation is the result of validating the chain
+type tlsValidation struct {
+	Trusted       bool     `json:"trusted"`       // the chain leads to a trusted root
+	HostnameMatch bool     `json:"hostnameMatch"` // the name is covered by the leaf's SANs
+	Name          string   `json:"name,omitempty"`
+	Roots         string   `json:"roots"`                   // "system" or the name of the CA bundle
+	Chain         []string `json:"verifiedChain,omitempty"` // subjects from the leaf to the trusted root
+	Errors        []string `json:"errors"`
+}
+
+// tlsOCSP describes the stapled OCSP response
+type tlsOCSP struct {
+	Stapled    bool       `json:"stapled"`
+	Status     string     `json:"status,omitempty"` // good, revoked or unknown
+	ProducedAt *time.Time `json:"producedAt,omitempty"`
+	NextUpdate *time.Time `json:"nextUpdate,omitempty"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// tlsCipher is a cipher suite the server accepts
+type tlsCipher struct {
+	Name     string `json:"name"`
+	ID       string `json:"id"`
+	Insecure bool   `json:"insecure"`
+}
+
+// tlsProtocol is a protocol version and the cipher suites the server accepts with it
+type tlsProtocol struct {
+	Version      string      `json:"version"`
+	Supported    bool        `json:"supported"`
+	CipherSuites []tlsCipher `json:"cipherSuites,omitempty"`
+	Error        string      `json:"error,omitempty"`
+}
+
+// tlsCheckResult is the result of a TLS check
+type tlsCheckResult struct {
+	Host         string           `json:"host"`
+	Address      string           `json:"address"`
+	Port         int              `json:"port"`
+	ServerName   string           `json:"serverName,omitempty"`
+	StartTLS     string           `json:"starttls,omitempty"`
+	Protocol     string           `json:"protocol"`
+	CipherSuite  string           `json:"cipherSuite"`
+	Certificates []tlsCertificate `json:"certificates"` // as sent by the server, leaf first
+	Validation   tlsValidation    `json:"validation"`
+	OCSP         tlsOCSP          `json:"ocsp"`
+	Protocols    []tlsProtocol    `json:"protocols,omitempty"`
+	Warnings     []string         `json:"warnings"`
+	Duration     float64          `json:"durationMs"`
+}
+
+// executeSSLChecker connects to a TLS server, inspects its certificates and
+// checks which protocol versions and cipher suites it accepts
+func executeSSLChecker(ctx context.Context, params map[string]interface{}, emit func(types.Event)) (interface{}, error) {
+	opts, err := parseTLSCheckParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	started := time.Now()
+	address := net.JoinHostPort(opts.Host, strconv.Itoa(opts.Port))
+	config := opts.clientConfig()
+	config.MinVersion = tls.VersionTLS10
+	state, remote, err := opts.handshake(ctx, address, config)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("could not connect to %s over TLS: %v", address, err)
+	}
+	if len(state.PeerCertificates) == 0 {
+		return nil, fmt.Errorf("%s sent no certificate", address)
+	}
+
+	result := &tlsCheckResult{
+		Host:        opts.Host,
+		Address:     remote,
+		Port:        opts.Port,
+		ServerName:  opts.ServerName,
+		Protocol:    tls.VersionName(state.Version),
+		CipherSuite: tls.CipherSuiteName(state.CipherSuite),
+		Warnings:    []string{},
+	}
+	if opts.StartTLS != startTLSNone {
+		result.StartTLS = opts.StartTLS
+	}
+	emit(types.Event{Type: types.EventLine, Line: fmt.Sprintf("Connected to %s: %s, %s", remote, result.Protocol, result.CipherSuite)})
+
+	now := time.Now()
+	for i, cert := range state.PeerCertificates {
+		info := describeCertificate(cert, now)
+		result.Certificates = append(result.Certificates, info)
+		emit(types.Event{Type: types.EventLine, Line: fmt.Sprintf("%2d s:%s", i, info.Subject)})
+		emit(types.Event{Type: types.EventLine, Line: fmt.Sprintf("   i:%s", info.Issuer)})
+		emit(types.Event{Type: types.EventLine, Line: fmt.Sprintf("   %s %d bits, %s, expires %s (%d days)",
+			info.KeyType, info.KeyBits, info.SignatureAlgorithm, info.NotAfter.Format(time.DateOnly), info.DaysRemaining)})
+	}
+
+	result.Validation = validateChain(state.PeerCertificates, opts, now)
+	if len(result.Validation.Errors) == 0 {
+		emit(types.Event{Type: types.EventLine, Line: "Chain: valid for " + result.Validation.Name})
+	}
+	for _, e := range result.Validation.Errors {
+		emit(types.Event{Type: types.EventLine, Line: "Chain: " + e})
+	}
+
+	result.OCSP = stapledOCSP(state)
+	if result.OCSP.Stapled {
+		emit(types.Event{Type: types.EventLine, Line: "OCSP: stapled, " + result.OCSP.Status + result.OCSP.Error})
+	} else {
+		emit(types.Event{Type: types.EventLine, Line: "OCSP: not stapled"})
+	}
+
+	if opts.Scan {
+		// Every handshake goes to the same address, rather than to any
+		// address of the host
+		if result.Protocols, err = opts.scan(ctx, remote, emit); err != nil {
+			return nil, err
+		}
+	}
+
+	result.Warnings = tlsWarnings(result, now)
+	for _, warning := range result.Warnings {
+		emit(types.Event{Type: types.EventLine, Line: "Warning: " + warning})
+	}
+	result.Duration = float64(time.Since(started).Microseconds()) / 1000
+	return result, nil
+}
+
+// parseTLSCheckParams reads the parameters of a TLS check
+func parseTLSCheckParams(params map[string]interface{}) (tlsCheckOptions, error) {
+	opts := tlsCheckOptions{StartTLS: startTLSNone, Scan: true, Timeout: defaultTLSTimeout, CABundle: "system"}
+
+	host, _ := params["host"].(string)
+	opts.Host = strings.Trim(strings.TrimSpace(host), "[]")
+	if opts.Host == "" {
+		return opts, fmt.Errorf("host parameter is required")
+	}
+	if starttls, ok := params["starttls"].(string); ok && starttls != "" {
+		opts.StartTLS = starttls
+	}
+	port, ok := startTLSPorts[opts.StartTLS]
+	if !ok {
+		return opts, fmt.Errorf("unknown STARTTLS protocol %q", opts.StartTLS)
+	}
+	opts.Port = port
+	if p, ok := params["port"].(float64); ok && p > 0 {
+		opts.Port = int(p)
+	}
+
+	opts.VerifyName = opts.Host
+	if name, ok := params["serverName"].(string); ok && strings.TrimSpace(name) != "" {
+		opts.VerifyName = strings.TrimSpace(name)
+	}
+	// Addresses are not sent in SNI
+	if _, err := netip.ParseAddr(opts.VerifyName); err != nil {
+		opts.ServerName = opts.VerifyName
+	}
+
+	if bundle, ok := params["caBundle"].(FileParam); ok && len(bundle.Data) > 0 {
+		opts.Roots = x509.NewCertPool()
+		if !opts.Roots.AppendCertsFromPEM(bundle.Data) {
+			return opts, fmt.Errorf("the CA bundle has no PEM certificates")
+		}
+		opts.CABundle = bundle.Name
+		if opts.CABundle == "" {
+			opts.CABundle = "custom"
+		}
+	}
+	if scan, ok := params["scan"].(bool); ok {
+		opts.Scan = scan
+	}
+	if timeout, ok := params["timeout"].(float64); ok && timeout > 0 {
+		opts.Timeout = time.Duration(timeout * float64(time.Second))
+	}
+	return opts, nil
+}
+
+// clientConfig returns the configuration of the handshakes. The chain is
+// validated apart from the handshake, so that servers with invalid chains
+// can be inspected too.
+func (o *tlsCheckOptions) clientConfig() *tls.Config {
+	return &tls.Config{
+		ServerName:         o.ServerName,
+		InsecureSkipVerify: true,
+	}
+}
+
+// handshake connects to address, negotiates STARTTLS and makes a TLS
+// handshake, returning the state of the connection and the address connected to
+func (o *tlsCheckOptions) handshake(ctx context.Context, address string, config *tls.Config) (tls.ConnectionState, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, o.Timeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return tls.ConnectionState{}, "", err
+	}
+	defer conn.Close()
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+	// Closing the connection ends the clear text exchange when ctx is cancelled
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	if err := startTLS(conn, o.StartTLS); err != nil {
+		if ctx.Err() != nil {
+			return tls.ConnectionState{}, "", ctx.Err()
+		}
+		return tls.ConnectionState{}, "", fmt.Errorf("%s STARTTLS failed: %v", strings.ToUpper(o.StartTLS), err)
+	}
+
+	tlsConn := tls.Client(conn, config)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return tls.ConnectionState{}, "", err
+	}
+	return tlsConn.ConnectionState(), conn.RemoteAddr().String(), nil
+}
+
+// startTLS asks the server to start TLS on conn in its clear text protocol
+func startTLS(conn net.Conn, protocol string) error {
+	br := bufio.NewReader(io.LimitReader(conn, maxStartTLSReply))
+	var err error
+	switch protocol {
+	case startTLSNone:
+		return nil
+	case startTLSSMTP:
+		err = startSMTP(conn, br)
+	case startTLSIMAP:
+		err = startIMAP(conn, br)
+	case startTLSPOP3:
+		err = startPOP3(conn, br)
+	case startTLSFTP:
+		err = startFTP(conn, br)
+	case startTLSLDAP:
+		err = startLDAP(conn, br)
+	case startTLSPostgres:
+		err = startPostgres(conn, br)
+	default:
+		err = fmt.Errorf("unknown protocol %q", protocol)
+	}
+	if err == nil && br.Buffered() > 0 {
+		err = fmt.Errorf("server sent data before the handshake")
+	}
+	return err
+}
+
+// readReply reads a reply of SMTP or FTP, whose lines start with a code and
+// continue while the code is followed by a dash
+func readReply(br *bufio.Reader) (int, []string, error) {
+	var lines []string
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return 0, nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if len(line) < 3 {
+			return 0, nil, fmt.Errorf("malformed reply %q", line)
+		}
+		code, err := strconv.Atoi(line[:3])
+		if err != nil {
+			return 0, nil, fmt.Errorf("malformed reply %q", line)
+		}
+		lines = append(lines, line)
+		if len(line) == 3 || line[3] != '-' {
+			return code, lines, nil
+		}
+	}
+}
+
+// expectReply reads a reply and checks its code
+func expectReply(br *bufio.Reader, want int) ([]string, error) {
+	code, lines, err := readReply(br)
+	if err != nil {
+		return nil, err
+	}
+	if code != want {
+		return nil, fmt.Errorf("server replied %q", lines[len(lines)-1])
+	}
+	return lines, nil
+}
+
+// startSMTP sends STARTTLS after the EHLO (RFC 3207)
+func startSMTP(conn net.Conn, br *bufio.Reader) error {
+	if _, err := expectReply(br, 220); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(conn, "EHLO nettool\r\n"); err != nil {
+		return err
+	}
+	lines, err := expectReply(br, 250)
+	if err != nil {
+		return err
+	}
+	offered := false
+	for _, line := range lines[1:] {
+		if len(line) > 4 && strings.EqualFold(strings.TrimSpace(line[4:]), "STARTTLS") {
+			offered = true
+		}
+	}
+	if !offered {
+		return fmt.Errorf("server does not offer STARTTLS")
+	}
+	if _, err := io.WriteString(conn, "STARTTLS\r\n"); err != nil {
+		return err
+	}
+	_, err = expectReply(br, 220)
+	return err
+}
+
+// startFTP sends AUTH TLS (RFC 4217)
+func startFTP(conn net.Conn, br *bufio.Reader) error {
+	if _, err := expectReply(br, 220); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(conn, "AUTH TLS\r\n"); err != nil {
+		return err
+	}
+	_, err := expectReply(br, 234)
+	return err
+}
+
+// startIMAP sends STARTTLS with a tag (RFC 3501)
+func startIMAP(conn net.Conn, br *bufio.Reader) error {
+	greeting, err := br.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(greeting, "* OK") {
+		return fmt.Errorf("server greeted %q", strings.TrimSpace(greeting))
+	}
+	if _, err := io.WriteString(conn, "a1 STARTTLS\r\n"); err != nil {
+		return err
+	}
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		// Untagged lines, such as capabilities, come before the tagged reply
+		if !strings.HasPrefix(line, "a1 ") {
+			continue
+		}
+		if !strings.HasPrefix(strings.ToUpper(line), "A1 OK") {
+			return fmt.Errorf("server replied %q", strings.TrimSpace(line))
+		}
+		return nil
+	}
+}
+
+// startPOP3 sends STLS (RFC 2595)
+func startPOP3(conn net.Conn, br *bufio.Reader) error {
+	for i, command := range []string{"", "STLS\r\n"} {
+		if command != "" {
+			if _, err := io.WriteString(conn, command); err != nil {
+				return err
+			}
+		}
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		if !strings.HasPrefix(line, "+OK") {
+			if i == 0 {
+				return fmt.Errorf("server greeted %q", strings.TrimSpace(line))
+			}
+			return fmt.Errorf("server replied %q", strings.TrimSpace(line))
+		}
+	}
+	return nil
+}
+
+// ldapStartTLSRequest is the extended request 1.3.6.1.4.1.1466.20037 with
+// message ID 1 (RFC 4511)
+var ldapStartTLSRequest = append([]byte{0x30, 0x1d, 0x02, 0x01, 0x01, 0x77, 0x18, 0x80, 0x16}, "1.3.6.1.4.1.1466.20037"...)
+
+// startLDAP sends the StartTLS extended request and checks its result code
+func startLDAP(conn net.Conn, br *bufio.Reader) error {
+	if _, err := conn.Write(ldapStartTLSRequest); err != nil {
+		return err
+	}
+	tag, message, err := readBER(br)
+	if err != nil {
+		return err
+	}
+	if tag != 0x30 {
+		return fmt.Errorf("malformed LDAP response")
+	}
+	// The message ID, then the extended response starting with its result code
+	msg := bufio.NewReader(bytes.NewReader(message))
+	if _, _, err := readBER(msg); err != nil {
+		return err
+	}
+	tag, response, err := readBER(msg)
+	if err != nil || tag != 0x78 {
+		return fmt.Errorf("malformed LDAP response")
+	}
+	tag, code, err := readBER(bufio.NewReader(bytes.NewReader(response)))
+	if err != nil || tag != 0x0a || len(code) != 1 {
+		return fmt.Errorf("malformed LDAP response")
+	}
+	if code[0] != 0 {
+		return fmt.Errorf("server replied with result code %d", code[0])
+	}
+	return nil
+}
+
+// readBER reads the tag and content of a BER element
+func readBER(br *bufio.Reader) (byte, []byte, error) {
+	tag, err := br.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	b, err := br.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	length := int(b)
+	if b&0x80 != 0 {
+		n := int(b & 0x7f)
+		if n == 0 || n > 3 {
+			return 0, nil, fmt.Errorf("unsupported BER length")
+		}
+		length = 0
+		for range n {
+			if b, err = br.ReadByte(); err != nil {
+				return 0, nil, err
+			}
+			length = length<<8 | int(b)
+		}
+	}
+	content := make([]byte, length)
+	if _, err := io.ReadFull(br, content); err != nil {
+		return 0, nil, err
+	}
+	return tag, content, nil
+}
+
+// startPostgres sends the SSLRequest message and reads the server's choice
+func startPostgres(conn net.Conn, br *bufio.Reader) error {
+	// Length 8 and the request code 80877103
+	if _, err := conn.Write([]byte{0, 0, 0, 8, 0x04, 0xd2, 0x16, 0x2f}); err != nil {
+		return err
+	}
+	b, err := br.ReadByte()
+	if err != nil {
+		return err
+	}
+	if b != 'S' {
+		return fmt.Errorf("server does not accept TLS")
+	}
+	return nil
+}
+
+// describeCertificate returns the details of a certificate
+func describeCertificate(cert *x509.Certificate, now time.Time) tlsCertificate {
+	fingerprint := sha256.Sum256(cert.Raw)
+	info := tlsCertificate{
+		Subject:            cert.Subject.String(),
+		Issuer:             cert.Issuer.String(),
+		SerialNumber:       hex.EncodeToString(cert.SerialNumber.Bytes()),
+		NotBefore:          cert.NotBefore,
+		NotAfter:           cert.NotAfter,
+		DaysRemaining:      int(cert.NotAfter.Sub(now).Hours() / 24),
+		Expired:            now.After(cert.NotAfter),
+		NotYetValid:        now.Before(cert.NotBefore),
+		DNSNames:           cert.DNSNames,
+		SignatureAlgorithm: cert.SignatureAlgorithm.String(),
+		IsCA:               cert.IsCA,
+		SelfSigned:         bytes.Equal(cert.RawSubject, cert.RawIssuer) && cert.CheckSignatureFrom(cert) == nil,
+		OCSPServers:        cert.OCSPServer,
+		Fingerprint:        hex.EncodeToString(fingerprint[:]),
+	}
+	for _, ip := range cert.IPAddresses {
+		info.IPAddresses = append(info.IPAddresses, ip.String())
+	}
+
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		info.KeyType, info.KeyBits = "RSA", key.N.BitLen()
+	case *ecdsa.PublicKey:
+		info.KeyType, info.KeyBits, info.Curve = "ECDSA", key.Curve.Params().BitSize, key.Curve.Params().Name
+	case ed25519.PublicKey:
+		info.KeyType, info.KeyBits = "Ed25519", 256
+	default:
+		info.KeyType = cert.PublicKeyAlgorithm.String()
+	}
+	return info
+}
+
+// validateChain validates the chain the server sent against the roots, and
+// the name against the leaf
+func validateChain(certs []*x509.Certificate, opts tlsCheckOptions, now time.Time) tlsValidation {
+	validation := tlsValidation{Name: opts.VerifyName, Roots: opts.CABundle, Errors: []string{}}
+	leaf := certs[0]
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	chains, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         opts.Roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	if err != nil {
+		validation.Errors = append(validation.Errors, err.Error())
+	} else {
+		validation.Trusted = true
+		for _, cert := range chains[0] {
+			validation.Chain = append(validation.Chain, cert.Subject.String())
+		}
+	}
+
+	if err := leaf.VerifyHostname(opts.VerifyName); err != nil {
+		validation.Errors = append(validation.Errors, err.Error())
+	} else {
+		validation.HostnameMatch = true
+	}
+	return validation
+}
+
+// stapledOCSP parses the OCSP response stapled to the handshake
+func stapledOCSP(state tls.ConnectionState) tlsOCSP {
+	if len(state.OCSPResponse) == 0 {
+		return tlsOCSP{}
+	}
+	result := tlsOCSP{Stapled: true}
+
+	var issuer *x509.Certificate
+	if len(state.PeerCertificates) > 1 {
+		issuer = state.PeerCertificates[1]
+	}
+	resp, err := ocsp.ParseResponseForCert(state.OCSPResponse, state.PeerCertificates[0], issuer)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	switch resp.Status {
+	case ocsp.Good:
+		result.Status = "good"
+	case ocsp.Revoked:
+		result.Status = "revoked"
+		result.RevokedAt = &resp.RevokedAt
+	default:
+		result.Status = "unknown"
+	}
+	result.ProducedAt = &resp.ProducedAt
+	if !resp.NextUpdate.IsZero() {
+		result.NextUpdate = &resp.NextUpdate
+	}
+	return result
+}
+
+// tlsScanProbe is a handshake of the scan: a version, with a single cipher
+// suite below TLS 1.3
+type tlsScanProbe struct {
+	version  uint16
+	suite    *tls.CipherSuite
+	accepted bool
+	err      error
+}
+
+// scan makes a handshake with each protocol version and, below TLS 1.3,
+// with each cipher suite of the version. The cipher suites of TLS 1.3
+// cannot be chosen, so only the one the server picks is reported.
+func (o *tlsCheckOptions) scan(ctx context.Context, address string, emit func(types.Event)) ([]tlsProtocol, error) {
+	insecure := make(map[uint16]bool)
+	suites := tls.CipherSuites()
+	for _, suite := range tls.InsecureCipherSuites() {
+		insecure[suite.ID] = true
+		suites = append(suites, suite)
+	}
+
+	var probes []*tlsScanProbe
+	for _, version := range tlsVersions {
+		probes = append(probes, &tlsScanProbe{version: version})
+		if version == tls.VersionTLS13 {
+			continue
+		}
+		for _, suite := range suites {
+			for _, v := range suite.SupportedVersions {
+				if v == version {
+					probes = append(probes, &tlsScanProbe{version: version, suite: suite})
+				}
+			}
+		}
+	}
+
+	var negotiated uint16 // the TLS 1.3 cipher suite picked by the server
+	jobs := make(chan *tlsScanProbe)
+	var done int
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for range tlsScanConcurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for probe := range jobs {
+				config := o.clientConfig()
+				config.MinVersion, config.MaxVersion = probe.version, probe.version
+				if probe.suite != nil {
+					config.CipherSuites = []uint16{probe.suite.ID}
+				}
+				state, _, err := o.handshake(ctx, address, config)
+
+				mu.Lock()
+				probe.accepted, probe.err = err == nil, err
+				if err == nil && probe.version == tls.VersionTLS13 {
+					negotiated = state.CipherSuite
+				}
+				done++
+				emit(types.Event{Type: types.EventProgress, Progress: float64(done) * 100 / float64(len(probes))})
+				mu.Unlock()
+			}
+		}()
+	}
+feed:
+	for _, probe := range probes {
+		select {
+		case jobs <- probe:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	protocols := make([]tlsProtocol, 0, len(tlsVersions))
+	for _, version := range tlsVersions {
+		protocol := tlsProtocol{Version: tls.VersionName(version)}
+		for _, probe := range probes {
+			if probe.version != version {
+				continue
+			}
+			switch {
+			case probe.suite == nil && probe.accepted:
+				protocol.Supported = true
+			case probe.suite == nil:
+				protocol.Error = probe.err.Error()
+			case probe.accepted:
+				protocol.CipherSuites = append(protocol.CipherSuites, tlsCipher{
+					Name:     probe.suite.Name,
+					ID:       fmt.Sprintf("0x%04x", probe.suite.ID),
+					Insecure: insecure[probe.suite.ID],
+				})
+			}
+		}
+		if version == tls.VersionTLS13 && protocol.Supported {
+			protocol.CipherSuites = []tlsCipher{{Name: tls.CipherSuiteName(negotiated), ID: fmt.Sprintf("0x%04x", negotiated)}}
+		}
+		// A version is supported when any of its suites is, even if the
+		// server rejected the suites offered by default
+		if len(protocol.CipherSuites) > 0 {
+			protocol.Supported, protocol.Error = true, ""
+		}
+
+		line := protocol.Version + ": not supported"
+		if protocol.Supported {
+			names := make([]string, len(protocol.CipherSuites))
+			for i, suite := range protocol.CipherSuites {
+				names[i] = suite.Name
+			}
+			line = protocol.Version + ": " + strings.Join(names, ", ")
+		}
+		emit(types.Event{Type: types.EventLine, Line: line})
+		protocols = append(protocols, protocol)
+	}
+	return protocols, nil
+}
+
+// tlsWarnings lists the weaknesses of the server's configuration
+func tlsWarnings(result *tlsCheckResult, now time.Time) []string {
+	warnings := []string{}
+	leaf := result.Certificates[0]
+	switch {
+	case leaf.Expired:
+		warnings = append(warnings, fmt.Sprintf("the certificate expired on %s", leaf.NotAfter.Format(time.DateOnly)))
+	case leaf.NotYetValid:
+		warnings = append(warnings, fmt.Sprintf("the certificate is not valid before %s", leaf.NotBefore.Format(time.DateOnly)))
+	case leaf.NotAfter.Sub(now) < certificateExpiryWarning:
+		warnings = append(warnings, fmt.Sprintf("the certificate expires in %d days", leaf.DaysRemaining))
+	}
+
+	for i, cert := range result.Certificates {
+		if cert.KeyType == "RSA" && cert.KeyBits < 2048 {
+			warnings = append(warnings, fmt.Sprintf("certificate %d has a %d-bit RSA key", i, cert.KeyBits))
+		}
+		// The signatures of roots are not checked
+		if !cert.SelfSigned && (strings.Contains(cert.SignatureAlgorithm, "SHA1") || strings.Contains(cert.SignatureAlgorithm, "MD")) {
+			warnings = append(warnings, fmt.Sprintf("certificate %d is signed with %s", i, cert.SignatureAlgorithm))
+		}
+	}
+
+	if result.OCSP.Status == "revoked" {
+		warnings = append(warnings, "the stapled OCSP response says the certificate is revoked")
+	}
+	for _, protocol := range result.Protocols {
+		if !protocol.Supported {
+			continue
+		}
+		if protocol.Version == tls.VersionName(tls.VersionTLS10) || protocol.Version == tls.VersionName(tls.VersionTLS11) {
+			warnings = append(warnings, protocol.Version+" is supported")
+		}
+		for _, suite := range protocol.CipherSuites {
+			if suite.Insecure {
+				warnings = append(warnings, fmt.Sprintf("insecure cipher suite %s is accepted with %s", suite.Name, protocol.Version))
+			}
+		}
+	}
+	return warnings
+}
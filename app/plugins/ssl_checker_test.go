@@ -0,0 +1,328 @@
+package plugins
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NetScout-Go/NetTool/app/plugins/types"
+)
+
+// testCA issues the certificates of the test servers
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+// newTestCA creates a self-signed CA
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "NetTool Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns a certificate for tls.test and 127.0.0.1, valid between
+// notBefore and notAfter, with the CA in its chain
+func (ca *testCA) issue(t *testing.T, notBefore, notAfter time.Time) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "tls.test"},
+		DNSNames:     []string{"tls.test"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der, ca.cert.Raw}, PrivateKey: key}
+}
+
+// bundle returns the CA as the value of the caBundle parameter
+func (ca *testCA) bundle() FileParam {
+	return FileParam{Name: "test-ca.pem", Data: ca.pem}
+}
+
+// newTestTLSServer starts an HTTPS server with config until the test ends
+func newTestTLSServer(t *testing.T, config *tls.Config) *httptest.Server {
+	t.Helper()
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	server.TLS = config
+	// Scans fail most handshakes on purpose
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+// serverPort returns the port of a test server as a parameter value
+func serverPort(t *testing.T, addr net.Addr) float64 {
+	t.Helper()
+	return float64(addr.(*net.TCPAddr).Port)
+}
+
+// checkTLS runs the checker and returns its result
+func checkTLS(t *testing.T, params map[string]interface{}) *tlsCheckResult {
+	t.Helper()
+	params["host"] = "127.0.0.1"
+	if _, ok := params["timeout"]; !ok {
+		params["timeout"] = 5.0
+	}
+	result, err := executeSSLChecker(context.Background(), params, func(types.Event) {})
+	if err != nil {
+		t.Fatalf("executeSSLChecker: %v", err)
+	}
+	return result.(*tlsCheckResult)
+}
+
+func TestSSLCheckerChainValidation(t *testing.T) {
+	ca := newTestCA(t)
+	now := time.Now()
+	valid := newTestTLSServer(t, &tls.Config{Certificates: []tls.Certificate{ca.issue(t, now.Add(-time.Hour), now.Add(12*time.Hour))}})
+	expired := newTestTLSServer(t, &tls.Config{Certificates: []tls.Certificate{ca.issue(t, now.Add(-48*time.Hour), now.Add(-24*time.Hour))}})
+
+	tests := []struct {
+		name          string
+		server        *httptest.Server
+		params        map[string]interface{}
+		trusted       bool
+		hostnameMatch bool
+		errors        []string // substrings of the validation errors, in order
+		warning       string
+	}{
+		{
+			name:          "untrusted",
+			server:        valid,
+			params:        map[string]interface{}{},
+			hostnameMatch: true,
+			errors:        []string{"unknown authority"},
+		},
+		{
+			name:          "trusted",
+			server:        valid,
+			params:        map[string]interface{}{"caBundle": ca.bundle()},
+			trusted:       true,
+			hostnameMatch: true,
+		},
+		{
+			name:    "hostname mismatch",
+			server:  valid,
+			params:  map[string]interface{}{"caBundle": ca.bundle(), "serverName": "other.test"},
+			trusted: true,
+			errors:  []string{"not other.test"},
+		},
+		{
+			name:          "expired",
+			server:        expired,
+			params:        map[string]interface{}{"caBundle": ca.bundle()},
+			hostnameMatch: true,
+			errors:        []string{"expired"},
+			warning:       "the certificate expired",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.params["port"] = serverPort(t, tt.server.Listener.Addr())
+			tt.params["scan"] = false
+			result := checkTLS(t, tt.params)
+
+			validation := result.Validation
+			if validation.Trusted != tt.trusted || validation.HostnameMatch != tt.hostnameMatch {
+				t.Errorf("trusted %v, hostname match %v; want %v, %v", validation.Trusted, validation.HostnameMatch, tt.trusted, tt.hostnameMatch)
+			}
+			if len(validation.Errors) != len(tt.errors) {
+				t.Fatalf("validation errors %q, want %q", validation.Errors, tt.errors)
+			}
+			for i, want := range tt.errors {
+				if !strings.Contains(validation.Errors[i], want) {
+					t.Errorf("validation error %q does not mention %q", validation.Errors[i], want)
+				}
+			}
+			if tt.trusted && len(validation.Chain) != 2 {
+				t.Errorf("verified chain %q, want the leaf and the CA", validation.Chain)
+			}
+			if tt.warning != "" && !slices.ContainsFunc(result.Warnings, func(w string) bool { return strings.Contains(w, tt.warning) }) {
+				t.Errorf("warnings %q do not mention %q", result.Warnings, tt.warning)
+			}
+
+			if len(result.Certificates) != 2 {
+				t.Fatalf("got %d certificates, want the leaf and the CA", len(result.Certificates))
+			}
+			leaf := result.Certificates[0]
+			if leaf.Subject != "CN=tls.test" || leaf.Issuer != "CN=NetTool Test CA" || leaf.KeyType != "ECDSA" || leaf.KeyBits != 256 || leaf.Curve != "P-256" {
+				t.Errorf("leaf = %s from %s, %s %d %s", leaf.Subject, leaf.Issuer, leaf.KeyType, leaf.KeyBits, leaf.Curve)
+			}
+			if leaf.Expired != (tt.server == expired) {
+				t.Errorf("leaf expired = %v", leaf.Expired)
+			}
+			if !result.Certificates[1].IsCA || !result.Certificates[1].SelfSigned {
+				t.Error("the CA is not reported as a self-signed CA")
+			}
+		})
+	}
+}
+
+func TestSSLCheckerProtocolScan(t *testing.T) {
+	ca := newTestCA(t)
+	cert := ca.issue(t, time.Now().Add(-time.Hour), time.Now().Add(12*time.Hour))
+
+	tests := []struct {
+		name      string
+		min, max  uint16
+		supported []string
+		suite     string // a suite accepted with the newest version
+		warning   string
+	}{
+		{name: "TLS 1.2 only", min: tls.VersionTLS12, max: tls.VersionTLS12, supported: []string{"TLS 1.2"}, suite: "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"},
+		{name: "TLS 1.3 only", min: tls.VersionTLS13, max: tls.VersionTLS13, supported: []string{"TLS 1.3"}, suite: "TLS_AES_128_GCM_SHA256"},
+		{name: "legacy", min: tls.VersionTLS10, max: tls.VersionTLS11, supported: []string{"TLS 1.0", "TLS 1.1"}, suite: "TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA", warning: "TLS 1.0 is supported"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestTLSServer(t, &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tt.min, MaxVersion: tt.max})
+			result := checkTLS(t, map[string]interface{}{"port": serverPort(t, server.Listener.Addr()), "caBundle": ca.bundle(), "scan": true})
+
+			if want := tls.VersionName(tt.max); result.Protocol != want {
+				t.Errorf("negotiated %s, want %s", result.Protocol, want)
+			}
+			var supported []string
+			var newest tlsProtocol
+			for _, protocol := range result.Protocols {
+				if protocol.Supported {
+					supported = append(supported, protocol.Version)
+					newest = protocol
+				} else if protocol.Error == "" || len(protocol.CipherSuites) > 0 {
+					t.Errorf("%s is not supported but has no error, or has cipher suites", protocol.Version)
+				}
+			}
+			if !slices.Equal(supported, tt.supported) {
+				t.Errorf("supported versions %q, want %q", supported, tt.supported)
+			}
+			if !slices.ContainsFunc(newest.CipherSuites, func(c tlsCipher) bool { return c.Name == tt.suite }) {
+				t.Errorf("%s suites %+v do not include %s", newest.Version, newest.CipherSuites, tt.suite)
+			}
+			if tt.warning != "" && !slices.Contains(result.Warnings, tt.warning) {
+				t.Errorf("warnings %q do not include %q", result.Warnings, tt.warning)
+			}
+		})
+	}
+}
+
+// newFakeSMTPServer starts an SMTP server that offers STARTTLS when
+// offerTLS is set, and makes a TLS handshake with config after it
+func newFakeSMTPServer(t *testing.T, config *tls.Config, offerTLS bool) (net.Addr, <-chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	commands := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				br := bufio.NewReader(conn)
+				io.WriteString(conn, "220-mail.test ESMTP\r\n220 ready\r\n")
+				for {
+					line, err := br.ReadString('\n')
+					if err != nil {
+						return
+					}
+					command := strings.TrimSpace(line)
+					commands <- command
+					switch {
+					case strings.HasPrefix(command, "EHLO") && offerTLS:
+						io.WriteString(conn, "250-mail.test\r\n250-PIPELINING\r\n250 STARTTLS\r\n")
+					case strings.HasPrefix(command, "EHLO"):
+						io.WriteString(conn, "250-mail.test\r\n250 PIPELINING\r\n")
+					case command == "STARTTLS" && offerTLS:
+						io.WriteString(conn, "220 go ahead\r\n")
+						tls.Server(conn, config).Handshake()
+						return
+					default:
+						io.WriteString(conn, "502 not implemented\r\n")
+					}
+				}
+			}()
+		}
+	}()
+	return ln.Addr(), commands
+}
+
+func TestSSLCheckerStartTLS(t *testing.T) {
+	ca := newTestCA(t)
+	config := &tls.Config{Certificates: []tls.Certificate{ca.issue(t, time.Now().Add(-time.Hour), time.Now().Add(12*time.Hour))}}
+
+	addr, commands := newFakeSMTPServer(t, config, true)
+	result := checkTLS(t, map[string]interface{}{
+		"port": serverPort(t, addr), "starttls": startTLSSMTP, "caBundle": ca.bundle(), "scan": false,
+	})
+	if result.StartTLS != startTLSSMTP {
+		t.Errorf("starttls = %q, want %q", result.StartTLS, startTLSSMTP)
+	}
+	if !result.Validation.Trusted || !result.Validation.HostnameMatch {
+		t.Errorf("validation errors %q after STARTTLS", result.Validation.Errors)
+	}
+	if got := []string{<-commands, <-commands}; !slices.Equal(got, []string{"EHLO nettool", "STARTTLS"}) {
+		t.Errorf("server received %q, want EHLO and STARTTLS", got)
+	}
+
+	addr, _ = newFakeSMTPServer(t, config, false)
+	_, err := executeSSLChecker(context.Background(), map[string]interface{}{
+		"host": "127.0.0.1", "port": serverPort(t, addr), "starttls": startTLSSMTP, "scan": false, "timeout": 5.0,
+	}, func(types.Event) {})
+	if err == nil || !strings.Contains(err.Error(), "does not offer STARTTLS") {
+		t.Errorf("error = %v, want STARTTLS not offered", err)
+	}
+}
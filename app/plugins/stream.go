@@ -0,0 +1,85 @@
+package plugins
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os/exec"
+	"strings"
+
+	"github.com/NetScout-Go/NetTool/app/plugins/types"
+)
+
+// eventLinePrefix marks a stdout line of a subprocess plugin as a structured
+// event, e.g. `@event {"type":"progress","progress":40}`. Such lines are not
+// part of the plugin's output.
+const eventLinePrefix = "@event "
+
+// maxOutputLine is the longest stdout line forwarded as a single event
+const maxOutputLine = 1024 * 1024
+
+// runStreaming runs cmd, passing every stdout line to emit as it is written,
+// and returns the complete stdout without event lines, and stderr
+func runStreaming(cmd *exec.Cmd, emit func(types.Event)) ([]byte, []byte, error) {
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	pipe, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, nil, err
+	}
+
+	var stdout bytes.Buffer
+	scanner := bufio.NewScanner(pipe)
+	scanner.Buffer(make([]byte, 64*1024), maxOutputLine)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if event, ok := parseEventLine(line); ok {
+			emit(event)
+			continue
+		}
+
+		stdout.WriteString(line)
+		stdout.WriteByte('\n')
+		emit(types.Event{Type: types.EventLine, Line: line})
+	}
+	scanErr := scanner.Err()
+
+	// Wait closes the pipe, so it must only be called once reading is done
+	err = cmd.Wait()
+	if err == nil {
+		err = scanErr
+	}
+	return stdout.Bytes(), stderr.Bytes(), err
+}
+
+// parseEventLine decodes a line written with eventLinePrefix
+func parseEventLine(line string) (types.Event, bool) {
+	if !strings.HasPrefix(line, eventLinePrefix) {
+		return types.Event{}, false
+	}
+
+	var event types.Event
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(line, eventLinePrefix)), &event); err != nil || event.Type == "" {
+		return types.Event{}, false
+	}
+	return event, true
+}
+
+// lastLineJSON decodes the last non-empty line of output as a JSON object or array
+func lastLineJSON(output []byte) (interface{}, bool) {
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	last := strings.TrimSpace(lines[len(lines)-1])
+	if !strings.HasPrefix(last, "{") && !strings.HasPrefix(last, "[") {
+		return nil, false
+	}
+
+	var result interface{}
+	if err := json.Unmarshal([]byte(last), &result); err != nil {
+		return nil, false
+	}
+	return result, true
+}
@@ -0,0 +1,793 @@
+package plugins
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+
+	"github.com/NetScout-Go/NetTool/app/internal/icmpsock"
+	"github.com/NetScout-Go/NetTool/app/plugins/types"
+)
+
+// Limits of the native traceroute
+const (
+	maxTraceHops         = 64
+	defaultTraceHops     = 30
+	maxTraceCycles       = 1000
+	maxHopSamples        = 100 // RTT samples kept per hop
+	defaultTracePortUDP  = 33434
+	defaultTracePortTCP  = 80
+	reverseLookupTimeout = 2 * time.Second
+)
+
+// Probe protocols and modes of the traceroute
+const (
+	traceUDP     = "udp"
+	traceICMP    = "icmp"
+	traceTCP     = "tcp"
+	traceModeMTR = "mtr"
+)
+
+// tracerouteDefinition describes the native traceroute, so it is listed without a plugin.json
+var tracerouteDefinition = types.PluginDefinition{
+	ID:          "traceroute",
+	Name:        "Traceroute",
+	Description: "Traces the path to a host hop by hop with UDP, ICMP or TCP SYN probes, once or continuously like mtr",
+	Version:     "2.0.0",
+	Author:      "NetTool Team",
+	License:     "MIT",
+	Icon:        "route",
+	Timeout:     3600, // long MTR runs
+	Parameters: []types.PluginParam{
+		{ID: "host", Name: "Host", Description: "Host name or IP address to trace the path to", Type: types.TypeHostname, Required: true},
+		{ID: "protocol", Name: "Probe Protocol", Description: "Packets sent as probes; TCP SYN probes pass most firewalls", Type: types.TypeSelect, Default: traceUDP, Options: []types.Option{
+			{Value: traceUDP, Label: "UDP"},
+			{Value: traceICMP, Label: "ICMP Echo"},
+			{Value: traceTCP, Label: "TCP SYN"},
+		}},
+		{ID: "port", Name: "Port", Description: "Destination port of UDP and TCP probes, 33434 for UDP and 80 for TCP by default", Type: types.TypePort},
+		{ID: "mode", Name: "Mode", Description: "Trace the path once, or keep per-hop statistics over many rounds like mtr", Type: types.TypeSelect, Default: "trace", Options: []types.Option{
+			{Value: "trace", Label: "Traceroute"},
+			{Value: traceModeMTR, Label: "MTR"},
+		}},
+		{ID: "maxHops", Name: "Max Hops", Description: "Largest TTL probed", Type: types.TypeNumber, Default: float64(defaultTraceHops), Min: types.FloatPtr(1), Max: types.FloatPtr(maxTraceHops), Step: types.FloatPtr(1)},
+		{ID: "queries", Name: "Queries", Description: "Probes per hop in traceroute mode", Type: types.TypeNumber, Default: float64(3), Min: types.FloatPtr(1), Max: types.FloatPtr(10), Step: types.FloatPtr(1)},
+		{ID: "cycles", Name: "Cycles", Description: "Rounds of probes in MTR mode", Type: types.TypeNumber, Default: float64(10), Min: types.FloatPtr(1), Max: types.FloatPtr(maxTraceCycles), Step: types.FloatPtr(1)},
+		{ID: "interval", Name: "Interval", Description: "Time between rounds in MTR mode", Type: types.TypeDuration, Default: "1s", Min: types.FloatPtr(0.1), Max: types.FloatPtr(60)},
+		{ID: "timeout", Name: "Timeout", Description: "Time to wait for the answers to a round of probes", Type: types.TypeDuration, Default: "2s", Min: types.FloatPtr(0.1), Max: types.FloatPtr(30)},
+		{ID: "resolveNames", Name: "Resolve Names", Description: "Look up the names of the hops", Type: types.TypeBoolean, Default: true},
+		{ID: "interface", Name: "Source Interface", Description: "Interface to send from; the system chooses when empty", Type: types.TypeInterface},
+		{ID: "ipVersion", Name: "IP Version", Description: "Address family used for host names", Type: types.TypeSelect, Default: "auto", Options: []types.Option{
+			{Value: "auto", Label: "Automatic"},
+			{Value: "4", Label: "IPv4"},
+			{Value: "6", Label: "IPv6"},
+		}},
+		types.CreateIterationParams(),
+	},
+}
+
+// traceOptions are the parameters of a traceroute run
+type traceOptions struct {
+	Host         string
+	Protocol     string
+	Port         int
+	Mode         string
+	MaxHops      int
+	Queries      int
+	Cycles       int
+	Interval     time.Duration
+	Timeout      time.Duration
+	ResolveNames bool
+	Interface    string
+	IPVersion    string
+}
+
+// hopAddress is a router that answered for a hop; several answer when
+// traffic is balanced over parallel paths
+type hopAddress struct {
+	Address string `json:"address"`
+	Name    string `json:"name,omitempty"`
+}
+
+// traceHop holds the statistics of the probes sent with one TTL
+type traceHop struct {
+	TTL         int          `json:"ttl"`
+	Addresses   []hopAddress `json:"addresses"`
+	Sent        int          `json:"sent"`
+	Received    int          `json:"received"`
+	Loss        float64      `json:"lossPercent"`
+	RTTs        []*float64   `json:"rttsMs"` // the most recent samples, null for lost probes
+	Last        float64      `json:"lastMs"`
+	Best        float64      `json:"bestMs"`
+	Avg         float64      `json:"avgMs"`
+	Worst       float64      `json:"worstMs"`
+	StdDev      float64      `json:"stdDevMs"`
+	Unreachable string       `json:"unreachable,omitempty"` // reported by a router instead of forwarding
+
+	sum, sumSquares float64
+}
+
+// traceResult is the result of the native traceroute
+type traceResult struct {
+	Host      string     `json:"host"`
+	Address   string     `json:"address"`
+	IPVersion int        `json:"ipVersion"`
+	Protocol  string     `json:"protocol"`
+	Port      int        `json:"port,omitempty"`
+	Mode      string     `json:"mode"`
+	Cycles    int        `json:"cycles"` // rounds of probes sent
+	Reached   bool       `json:"reached"`
+	Hops      []traceHop `json:"hops"`
+	Duration  float64    `json:"durationMs"`
+}
+
+// traceProbe is a probe of the current round
+type traceProbe struct {
+	ttl  int
+	key  int // echo sequence number, or local port of UDP and TCP probes
+	sent time.Time
+	done bool
+}
+
+// traceOutcome is the answer to a TCP probe, which the dialer gets rather
+// than the ICMP listener
+type traceOutcome struct {
+	probe    *traceProbe
+	received time.Time
+}
+
+// tracer sends rounds of probes to a destination and keeps per-hop statistics
+type tracer struct {
+	opts     traceOptions
+	dst      netip.Addr
+	source   netip.Addr     // of UDP and TCP probes
+	listener *icmpsock.Conn // raw socket receiving the answers to all probes
+	packets  chan icmpsock.Packet
+	done     chan struct{}
+	id       int // echo identifier of ICMP probes
+	seq      int // next echo sequence number
+	hops     []*traceHop
+	lastTTL  int // TTL at which the destination, or a router reporting it unreachable, answered
+	reached  bool
+	cycles   int
+	names    map[string]string
+	started  time.Time
+
+	mu     sync.Mutex // guards probes, which TCP dialers register
+	probes map[int]*traceProbe
+}
+
+// executeTraceroute traces the path to a host with native probes. In MTR mode
+// the statistics are emitted after every round.
+func executeTraceroute(ctx context.Context, params map[string]interface{}, emit func(types.Event)) (interface{}, error) {
+	opts, err := parseTraceParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := newTracer(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer t.Close()
+
+	rounds := opts.Queries
+	if opts.Mode == traceModeMTR {
+		rounds = opts.Cycles
+	}
+	for i := 0; i < rounds; i++ {
+		if i > 0 && opts.Mode == traceModeMTR {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(opts.Interval):
+			}
+		}
+		if err := t.cycle(ctx); err != nil {
+			return nil, err
+		}
+
+		if opts.Mode == traceModeMTR {
+			emit(types.Event{Type: types.EventPartial, Data: t.report()})
+		}
+		emit(types.Event{Type: types.EventProgress, Progress: float64(i+1) * 100 / float64(rounds)})
+	}
+
+	result := t.report()
+	for _, line := range formatTrace(result) {
+		emit(types.Event{Type: types.EventLine, Line: line})
+	}
+	return result, nil
+}
+
+// parseTraceParams reads the parameters of a traceroute run. Defaults are
+// applied here too, for installed traceroute plugins whose plugin.json lacks
+// parameters.
+func parseTraceParams(params map[string]interface{}) (traceOptions, error) {
+	opts := traceOptions{
+		Protocol:     traceUDP,
+		Mode:         "trace",
+		MaxHops:      defaultTraceHops,
+		Queries:      3,
+		Cycles:       10,
+		Interval:     time.Second,
+		Timeout:      2 * time.Second,
+		ResolveNames: true,
+		IPVersion:    "auto",
+	}
+
+	host, _ := params["host"].(string)
+	if host == "" {
+		return opts, fmt.Errorf("host parameter is required")
+	}
+	opts.Host = host
+
+	if protocol, ok := params["protocol"].(string); ok && protocol != "" {
+		opts.Protocol = strings.ToLower(protocol)
+	}
+	switch opts.Protocol {
+	case traceUDP:
+		opts.Port = defaultTracePortUDP
+	case traceTCP:
+		opts.Port = defaultTracePortTCP
+	case traceICMP:
+	default:
+		return opts, fmt.Errorf("unknown probe protocol %q", opts.Protocol)
+	}
+	if port, ok := params["port"].(float64); ok && port > 0 && opts.Protocol != traceICMP {
+		opts.Port = int(port)
+	}
+
+	if mode, ok := params["mode"].(string); ok && mode != "" {
+		opts.Mode = mode
+	}
+	if hops, ok := params["maxHops"].(float64); ok && hops > 0 {
+		opts.MaxHops = int(math.Min(hops, maxTraceHops))
+	}
+	if queries, ok := params["queries"].(float64); ok && queries > 0 {
+		opts.Queries = int(math.Min(queries, 10))
+	}
+	if cycles, ok := params["cycles"].(float64); ok && cycles > 0 {
+		opts.Cycles = int(math.Min(cycles, maxTraceCycles))
+	}
+	if interval, ok := params["interval"].(float64); ok && interval > 0 {
+		opts.Interval = time.Duration(interval * float64(time.Second))
+	}
+	if timeout, ok := params["timeout"].(float64); ok && timeout > 0 {
+		opts.Timeout = time.Duration(timeout * float64(time.Second))
+	}
+	if resolve, ok := params["resolveNames"].(bool); ok {
+		opts.ResolveNames = resolve
+	}
+	opts.Interface, _ = params["interface"].(string)
+	if version, ok := params["ipVersion"].(string); ok && version != "" {
+		opts.IPVersion = version
+	}
+	return opts, nil
+}
+
+// newTracer resolves the destination and opens the raw socket receiving the
+// answers to the probes
+func newTracer(ctx context.Context, opts traceOptions) (*tracer, error) {
+	dst, err := resolveHost(ctx, opts.Host, opts.IPVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	listener, err := icmpsock.Listen(dst, icmpsock.Options{Interface: opts.Interface, Raw: true})
+	if err != nil {
+		return nil, fmt.Errorf("traceroute needs a raw socket to receive the answers of routers: %w", err)
+	}
+
+	t := &tracer{
+		opts:     opts,
+		dst:      dst,
+		source:   netip.IPv4Unspecified(),
+		listener: listener,
+		packets:  make(chan icmpsock.Packet, 64),
+		done:     make(chan struct{}),
+		id:       echoID(),
+		names:    make(map[string]string),
+		started:  time.Now(),
+	}
+	if dst.Is6() {
+		t.source = netip.IPv6Unspecified()
+	}
+	if opts.Interface != "" {
+		if t.source, err = icmpsock.InterfaceSource(opts.Interface, dst.Is6(), dst.IsLinkLocalUnicast()); err != nil {
+			listener.Close()
+			return nil, err
+		}
+	}
+
+	go listener.ReadLoop(t.packets, t.done)
+	return t, nil
+}
+
+// Close stops the tracer
+func (t *tracer) Close() {
+	close(t.done)
+	t.listener.Close()
+}
+
+// hop returns the statistics of a TTL
+func (t *tracer) hop(ttl int) *traceHop {
+	for len(t.hops) < ttl {
+		t.hops = append(t.hops, &traceHop{TTL: len(t.hops) + 1})
+	}
+	return t.hops[ttl-1]
+}
+
+// cycle sends one probe for every TTL up to the destination, or up to
+// MaxHops while it is unknown, and waits for the answers
+func (t *tracer) cycle(ctx context.Context) error {
+	maxTTL := t.opts.MaxHops
+	if t.lastTTL > 0 {
+		maxTTL = t.lastTTL
+	}
+
+	cycleCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	t.mu.Lock()
+	t.probes = make(map[int]*traceProbe, maxTTL)
+	t.mu.Unlock()
+
+	var closers []io.Closer
+	defer func() {
+		for _, c := range closers {
+			c.Close()
+		}
+	}()
+	outcomes := make(chan traceOutcome, maxTTL)
+
+	sent := make([]*traceProbe, 0, maxTTL)
+	for ttl := 1; ttl <= maxTTL; ttl++ {
+		probe := &traceProbe{ttl: ttl}
+		closer, err := t.send(cycleCtx, probe, outcomes)
+		if err != nil {
+			return err
+		}
+		if closer != nil {
+			closers = append(closers, closer)
+		}
+		sent = append(sent, probe)
+	}
+
+	deadline := time.NewTimer(t.opts.Timeout)
+	defer deadline.Stop()
+	for !t.answered(sent) {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case packet := <-t.packets:
+			t.handle(packet)
+		case outcome := <-outcomes:
+			t.answer(outcome.probe, t.dst, outcome.received, true)
+		case <-deadline.C:
+			t.finishCycle(sent)
+			return nil
+		}
+	}
+	t.finishCycle(sent)
+	return nil
+}
+
+// answered reports whether every probe up to the last TTL was answered
+func (t *tracer) answered(sent []*traceProbe) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, probe := range sent {
+		if t.lastTTL > 0 && probe.ttl > t.lastTTL {
+			break
+		}
+		if !probe.done {
+			return false
+		}
+	}
+	return true
+}
+
+// finishCycle counts the probes that were not answered as lost, leaves out
+// the hops past the destination and looks up the names of new hops
+func (t *tracer) finishCycle(sent []*traceProbe) {
+	t.mu.Lock()
+	for _, probe := range sent {
+		if t.lastTTL > 0 && probe.ttl > t.lastTTL {
+			break
+		}
+		if !probe.done {
+			probe.done = true
+			t.hop(probe.ttl).record(nil)
+		}
+	}
+	t.mu.Unlock()
+
+	if t.lastTTL > 0 && len(t.hops) > t.lastTTL {
+		t.hops = t.hops[:t.lastTTL]
+	}
+	t.cycles++
+
+	if t.opts.ResolveNames {
+		t.resolveNames()
+	}
+}
+
+// send sends the probe with its TTL. UDP probes return their socket, to be
+// closed once the round is over, and TCP probes report their answer to
+// outcomes.
+func (t *tracer) send(ctx context.Context, probe *traceProbe, outcomes chan<- traceOutcome) (io.Closer, error) {
+	payload := make([]byte, 32)
+	dst := &net.UDPAddr{IP: t.dst.AsSlice(), Port: t.opts.Port, Zone: t.dst.Zone()}
+
+	switch t.opts.Protocol {
+	case traceICMP:
+		if err := t.listener.SetTTL(probe.ttl); err != nil {
+			return nil, err
+		}
+		probe.key = t.seq
+		t.seq = (t.seq + 1) & 0xffff
+		t.register(probe)
+		return nil, t.listener.WriteEcho(t.dst, t.id, probe.key, payload)
+
+	case traceUDP:
+		network := "udp4"
+		if t.dst.Is6() {
+			network = "udp6"
+		}
+		lc := net.ListenConfig{Control: icmpsock.ProbeControl(t.dst.Is6(), probe.ttl, t.opts.Interface, t.source, nil)}
+		conn, err := lc.ListenPacket(ctx, network, net.JoinHostPort(t.source.WithZone("").String(), "0"))
+		if err != nil {
+			return nil, err
+		}
+		probe.key = conn.LocalAddr().(*net.UDPAddr).Port
+		t.register(probe)
+		if _, err := conn.WriteTo(payload, dst); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return conn, nil
+
+	case traceTCP:
+		dialer := net.Dialer{Control: icmpsock.ProbeControl(t.dst.Is6(), probe.ttl, t.opts.Interface, t.source, func(port int) {
+			probe.key = port
+			t.register(probe)
+		})}
+		go func() {
+			conn, err := dialer.DialContext(ctx, "tcp", dst.String())
+			received := time.Now()
+			if err == nil {
+				conn.Close()
+			}
+			// A SYN-ACK or a reset comes from the destination; routers
+			// answer with ICMP errors, which the listener handles
+			if err == nil || errors.Is(err, syscall.ECONNREFUSED) {
+				outcomes <- traceOutcome{probe: probe, received: received}
+			}
+		}()
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unknown probe protocol %q", t.opts.Protocol)
+}
+
+// register makes a sent probe known to the listener
+func (t *tracer) register(probe *traceProbe) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	probe.sent = time.Now()
+	t.probes[probe.key] = probe
+}
+
+// handle matches an ICMP message to the probe it answers
+func (t *tracer) handle(packet icmpsock.Packet) {
+	from := packet.From.WithZone("")
+	dst := t.dst.WithZone("")
+
+	switch packet.Message.Type {
+	case ipv4.ICMPTypeEchoReply, ipv6.ICMPTypeEchoReply:
+		echo, ok := packet.Message.Body.(*icmp.Echo)
+		if !ok || t.opts.Protocol != traceICMP || echo.ID != t.id || from != dst {
+			return
+		}
+		t.answer(t.probe(echo.Seq), packet.From, packet.Received, true)
+		return
+	}
+
+	quoted, ok := icmpsock.ParseQuoted(packet.Message, t.dst.Is6())
+	if !ok || quoted.Dst.WithZone("") != dst {
+		return
+	}
+	var probe *traceProbe
+	switch {
+	case t.opts.Protocol == traceICMP && quoted.Echo && quoted.EchoID == t.id:
+		probe = t.probe(quoted.EchoSeq)
+	case t.opts.Protocol == traceUDP && quoted.Protocol == icmpsock.ProtocolUDP && quoted.DstPort == t.opts.Port,
+		t.opts.Protocol == traceTCP && quoted.Protocol == icmpsock.ProtocolTCP && quoted.DstPort == t.opts.Port:
+		probe = t.probe(quoted.SrcPort)
+	}
+	if probe == nil {
+		return
+	}
+
+	switch packet.Message.Type {
+	case ipv4.ICMPTypeTimeExceeded, ipv6.ICMPTypeTimeExceeded:
+		t.answer(probe, packet.From, packet.Received, false)
+	case ipv4.ICMPTypeDestinationUnreachable, ipv6.ICMPTypeDestinationUnreachable:
+		// The destination rejects UDP probes to a closed port; anything
+		// else ends the path at the router reporting it
+		if from == dst && isPortUnreachable(packet.Message) {
+			t.answer(probe, packet.From, packet.Received, true)
+			return
+		}
+		_, text := icmpErrorStatus(packet.Message)
+		t.hop(probe.ttl).Unreachable = text
+		t.answer(probe, packet.From, packet.Received, true)
+	}
+}
+
+func (t *tracer) probe(key int) *traceProbe {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.probes[key]
+}
+
+// answer records the answer to a probe. last marks answers that end the
+// path, from the destination or a router reporting it unreachable.
+func (t *tracer) answer(probe *traceProbe, from netip.Addr, received time.Time, last bool) {
+	if probe == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if probe.done {
+		// TCP retransmits the SYN, which may be answered again
+		return
+	}
+	probe.done = true
+
+	if last && (t.lastTTL == 0 || probe.ttl < t.lastTTL) {
+		t.lastTTL = probe.ttl
+		t.reached = from.WithZone("") == t.dst.WithZone("")
+	}
+
+	hop := t.hop(probe.ttl)
+	hop.addAddress(from.String())
+	rtt := float64(received.Sub(probe.sent).Microseconds()) / 1000
+	hop.record(&rtt)
+}
+
+// resolveNames looks up the names of the hops not looked up yet
+func (t *tracer) resolveNames() {
+	var lookups []string
+	for _, hop := range t.hops {
+		for _, addr := range hop.Addresses {
+			if _, ok := t.names[addr.Address]; !ok {
+				t.names[addr.Address] = ""
+				lookups = append(lookups, addr.Address)
+			}
+		}
+	}
+	if len(lookups) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), reverseLookupTimeout)
+	defer cancel()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, addr := range lookups {
+		wg.Add(1)
+		go func(addr string) {
+			defer wg.Done()
+			names, err := net.DefaultResolver.LookupAddr(ctx, addr)
+			if err != nil || len(names) == 0 {
+				return
+			}
+			mu.Lock()
+			t.names[addr] = strings.TrimSuffix(names[0], ".")
+			mu.Unlock()
+		}(addr)
+	}
+	wg.Wait()
+}
+
+// report returns the statistics so far. Hops past the last one that answered
+// are left out, except for the first of them, where the path went dark.
+func (t *tracer) report() *traceResult {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	result := &traceResult{
+		Host:      t.opts.Host,
+		Address:   t.dst.String(),
+		IPVersion: 4,
+		Protocol:  t.opts.Protocol,
+		Port:      t.opts.Port,
+		Mode:      t.opts.Mode,
+		Cycles:    t.cycles,
+		Reached:   t.reached,
+		Hops:      make([]traceHop, 0, len(t.hops)),
+		Duration:  float64(time.Since(t.started).Microseconds()) / 1000,
+	}
+	if t.dst.Is6() {
+		result.IPVersion = 6
+	}
+	if result.Mode != traceModeMTR {
+		result.Mode = "trace"
+	}
+
+	last := len(t.hops)
+	if t.lastTTL == 0 {
+		last = 0
+		for i, hop := range t.hops {
+			if hop.Received > 0 {
+				last = i + 1
+			}
+		}
+		last = min(last+1, len(t.hops))
+	}
+
+	for _, hop := range t.hops[:last] {
+		h := *hop
+		h.Addresses = make([]hopAddress, len(hop.Addresses))
+		for i, addr := range hop.Addresses {
+			h.Addresses[i] = hopAddress{Address: addr.Address, Name: t.names[addr.Address]}
+		}
+		h.RTTs = append([]*float64(nil), hop.RTTs...)
+		result.Hops = append(result.Hops, h)
+	}
+	return result
+}
+
+// addAddress adds a router answering for the hop
+func (h *traceHop) addAddress(addr string) {
+	for _, known := range h.Addresses {
+		if known.Address == addr {
+			return
+		}
+	}
+	h.Addresses = append(h.Addresses, hopAddress{Address: addr})
+}
+
+// record adds the RTT of a probe, nil for a lost one, to the statistics
+func (h *traceHop) record(rtt *float64) {
+	h.Sent++
+	h.RTTs = append(h.RTTs, rtt)
+	if len(h.RTTs) > maxHopSamples {
+		h.RTTs = h.RTTs[len(h.RTTs)-maxHopSamples:]
+	}
+
+	if rtt != nil {
+		ms := *rtt
+		h.Received++
+		h.Last = ms
+		if h.Received == 1 || ms < h.Best {
+			h.Best = ms
+		}
+		h.Worst = math.Max(h.Worst, ms)
+		h.sum += ms
+		h.sumSquares += ms * ms
+		avg := h.sum / float64(h.Received)
+		h.Avg = roundMs(avg)
+		h.StdDev = roundMs(math.Sqrt(math.Max(0, h.sumSquares/float64(h.Received)-avg*avg)))
+	}
+	h.Loss = math.Round(float64(h.Sent-h.Received)*10000/float64(h.Sent)) / 100
+}
+
+// isPortUnreachable reports whether msg is a port unreachable error
+func isPortUnreachable(msg *icmp.Message) bool {
+	if msg.Type == ipv6.ICMPTypeDestinationUnreachable {
+		return msg.Code == 4
+	}
+	return msg.Type == ipv4.ICMPTypeDestinationUnreachable && msg.Code == 3
+}
+
+// formatTrace renders a result as traceroute prints it
+func formatTrace(result *traceResult) []string {
+	target := result.Address
+	if result.Host != result.Address {
+		target = fmt.Sprintf("%s (%s)", result.Host, result.Address)
+	}
+	lines := []string{fmt.Sprintf("traceroute to %s, %d hops max, %s probes", target, len(result.Hops), strings.ToUpper(result.Protocol))}
+
+	for _, hop := range result.Hops {
+		var b strings.Builder
+		fmt.Fprintf(&b, "%2d ", hop.TTL)
+		for _, addr := range hop.Addresses {
+			if addr.Name != "" {
+				fmt.Fprintf(&b, " %s (%s)", addr.Name, addr.Address)
+			} else {
+				b.WriteString(" " + addr.Address)
+			}
+		}
+		for _, rtt := range hop.RTTs {
+			if rtt == nil {
+				b.WriteString("  *")
+			} else {
+				b.WriteString("  " + strconv.FormatFloat(*rtt, 'f', 3, 64) + " ms")
+			}
+		}
+		if hop.Unreachable != "" {
+			b.WriteString("  !" + hop.Unreachable)
+		}
+		lines = append(lines, b.String())
+	}
+	return lines
+}
+
+// tracerouteIterator runs the traceroute for the iterate command. In MTR
+// mode the tracer is kept across iterations, each adding a round of probes
+// to the per-hop statistics, until Cycles rounds were sent.
+type tracerouteIterator struct {
+	types.BaseIterablePlugin
+	tracer *tracer
+}
+
+// newTracerouteIterator creates the iterable traceroute
+func newTracerouteIterator(definition types.PluginDefinition) types.IterablePlugin {
+	it := &tracerouteIterator{}
+	it.Definition = definition
+	it.SupportsIterationFlag = true
+	it.ExecuteFunc = func(params map[string]interface{}) (interface{}, error) {
+		return executeTraceroute(context.Background(), params, func(types.Event) {})
+	}
+	return it
+}
+
+// ExecuteIteration traces the path again, or in MTR mode sends another round
+// of probes
+func (it *tracerouteIterator) ExecuteIteration(params map[string]interface{}, iterationCount int) (interface{}, bool, error) {
+	opts, err := parseTraceParams(params)
+	if err != nil {
+		it.closeTracer()
+		return nil, false, err
+	}
+	if opts.Mode != traceModeMTR {
+		it.closeTracer()
+		result, err := it.Execute(params)
+		return result, true, err
+	}
+
+	// The statistics of another destination, protocol or interface must not
+	// be carried over
+	if it.tracer == nil || iterationCount == 0 || it.tracer.opts != opts {
+		it.closeTracer()
+		if it.tracer, err = newTracer(context.Background(), opts); err != nil {
+			return nil, false, err
+		}
+	}
+	if err := it.tracer.cycle(context.Background()); err != nil {
+		it.closeTracer()
+		return nil, false, err
+	}
+
+	result := it.tracer.report()
+	if it.tracer.cycles >= opts.Cycles {
+		it.closeTracer()
+		return result, false, nil
+	}
+	return result, true, nil
+}
+
+// closeTracer releases the socket of the MTR tracer, if one is open
+func (it *tracerouteIterator) closeTracer() {
+	if it.tracer != nil {
+		it.tracer.Close()
+		it.tracer = nil
+	}
+}
@@ -1,5 +1,7 @@
 package types
 
+import "context"
+
 // PluginDefinition defines the structure for plugin metadata and configuration
 type PluginDefinition struct {
 	ID          string        `json:"id"`
@@ -12,6 +14,21 @@ type PluginDefinition struct {
 	Parameters  []PluginParam `json:"parameters"`
 	Requires    []string      `json:"requires,omitempty"` // System dependencies like iperf3
 	Repository  string        `json:"repository,omitempty"`
+	Timeout     int           `json:"timeout,omitempty"` // seconds a run may take, 0 for the default
+	Runtime     *Runtime      `json:"runtime,omitempty"` // set for out-of-process plugins
+	// OverrideBuiltin runs the plugin's own code even when NetTool has a
+	// builtin implementation of the same ID
+	OverrideBuiltin bool `json:"overrideBuiltin,omitempty"`
+}
+
+// Runtime describes how an out-of-process plugin is started. The process
+// speaks the protocol on stdin and stdout and serves requests until it is
+// shut down, so it is started once rather than for every run.
+type Runtime struct {
+	Protocol   string   `json:"protocol"`             // "jsonrpc-stdio/1"
+	Command    string   `json:"command"`              // executable, relative to the plugin directory or on PATH
+	Args       []string `json:"args,omitempty"`       // arguments passed to the command
+	MaxWorkers int      `json:"maxWorkers,omitempty"` // processes serving runs in parallel, 1 by default
 }
 
 // PluginParam defines a parameter for a plugin
@@ -22,11 +39,12 @@ type PluginParam struct {
 	Type        ParameterType `json:"type"`
 	Required    bool          `json:"required"`
 	Default     interface{}   `json:"default,omitempty"`
-	Options     []Option      `json:"options,omitempty"`    // For select type
-	Min         *float64      `json:"min,omitempty"`        // For number/range type
-	Max         *float64      `json:"max,omitempty"`        // For number/range type
-	Step        *float64      `json:"step,omitempty"`       // For number/range type
-	CanIterate  bool          `json:"canIterate,omitempty"` // Whether this parameter supports iteration
+	Options     []Option      `json:"options,omitempty"`     // For select type
+	Min         *float64      `json:"min,omitempty"`         // For number/range type
+	Max         *float64      `json:"max,omitempty"`         // For number/range type
+	Step        *float64      `json:"step,omitempty"`        // For number/range type
+	CanIterate  bool          `json:"canIterate,omitempty"`  // Whether this parameter supports iteration
+	Placeholder string        `json:"placeholder,omitempty"` // Example input shown in empty fields
 }
 
 // Option defines an option for a select parameter
@@ -49,6 +67,24 @@ const (
 	TypeSelect ParameterType = "select"
 	// TypeRange is the range type identifier.
 	TypeRange ParameterType = "range"
+	// TypeHostname is a host name or IP address.
+	TypeHostname ParameterType = "hostname"
+	// TypeIP is an IPv4 or IPv6 address.
+	TypeIP ParameterType = "ip"
+	// TypeCIDR is a network prefix such as 192.168.1.0/24.
+	TypeCIDR ParameterType = "cidr"
+	// TypePort is a TCP or UDP port number.
+	TypePort ParameterType = "port"
+	// TypePortList is a list of ports and port ranges such as 22,80,8000-8100.
+	TypePortList ParameterType = "ports"
+	// TypeHostList is a list of host names or IP addresses.
+	TypeHostList ParameterType = "hosts"
+	// TypeDuration is a duration such as 500ms or 1m30s, or a number of seconds.
+	TypeDuration ParameterType = "duration"
+	// TypeInterface is the name of one of the system's network interfaces.
+	TypeInterface ParameterType = "interface"
+	// TypeFile is an uploaded file.
+	TypeFile ParameterType = "file"
 )
 
 // Plugin defines the interface that all plugins must implement
@@ -60,6 +96,88 @@ type Plugin interface {
 	Execute(params map[string]interface{}) (interface{}, error)
 }
 
+// ContextPlugin is implemented by plugins that stop their work, including any
+// child processes, when the context is cancelled or its deadline passes
+type ContextPlugin interface {
+	Plugin
+
+	// ExecuteContext runs the plugin with the given parameters until ctx is done
+	ExecuteContext(ctx context.Context, params map[string]interface{}) (interface{}, error)
+}
+
+// ExecuteWithContext runs plugin under ctx. Plugins that do not implement
+// ContextPlugin keep running in the background after ctx is done, but the
+// caller gets ctx's error right away and their result is discarded.
+func ExecuteWithContext(ctx context.Context, plugin Plugin, params map[string]interface{}) (interface{}, error) {
+	if p, ok := plugin.(ContextPlugin); ok {
+		return p.ExecuteContext(ctx, params)
+	}
+	return RunWithContext(ctx, plugin.Execute, params)
+}
+
+// RunWithContext adapts an execute function without context support, see ExecuteWithContext
+func RunWithContext(ctx context.Context, execute func(map[string]interface{}) (interface{}, error), params map[string]interface{}) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	type outcome struct {
+		result interface{}
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		result, err := execute(params)
+		done <- outcome{result, err}
+	}()
+
+	select {
+	case o := <-done:
+		return o.result, o.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// EventType identifies the kind of a streamed plugin event
+type EventType string
+
+const (
+	// EventLine carries a line of output.
+	EventLine EventType = "line"
+	// EventPartial carries a partial structured result, e.g. one traceroute hop.
+	EventPartial EventType = "partial"
+	// EventProgress carries the completion percentage.
+	EventProgress EventType = "progress"
+)
+
+// Event is an incremental update emitted by a streaming plugin while it runs
+type Event struct {
+	Type     EventType   `json:"type"`
+	Line     string      `json:"line,omitempty"`
+	Data     interface{} `json:"data,omitempty"`
+	Progress float64     `json:"progress,omitempty"` // percentage
+}
+
+// StreamingPlugin is implemented by plugins that report output while they
+// run instead of only returning a result at the end. emit may be called from
+// any goroutine until ExecuteStream returns.
+type StreamingPlugin interface {
+	ContextPlugin
+
+	// ExecuteStream runs the plugin until ctx is done, passing events to emit
+	ExecuteStream(ctx context.Context, params map[string]interface{}, emit func(Event)) (interface{}, error)
+}
+
+// ExecuteWithEvents runs plugin under ctx, passing events of streaming
+// plugins to emit; other plugins run as with ExecuteWithContext
+func ExecuteWithEvents(ctx context.Context, plugin Plugin, params map[string]interface{}, emit func(Event)) (interface{}, error) {
+	if p, ok := plugin.(StreamingPlugin); ok {
+		return p.ExecuteStream(ctx, params, emit)
+	}
+	return ExecuteWithContext(ctx, plugin, params)
+}
+
 // IterablePlugin defines the interface for plugins that support iteration
 type IterablePlugin interface {
 	Plugin
@@ -180,15 +180,8 @@ class PluginIterationManager {
         // Make a copy of params to avoid modifying the original
         const iterationParams = {...this.params, iterationCount: this.iterationCount};
         
-        // Run the plugin
-        fetch(`/api/plugins/${this.pluginId}/run`, {
-            method: 'POST',
-            headers: {
-                'Content-Type': 'application/json',
-            },
-            body: JSON.stringify(iterationParams),
-        })
-        .then(response => response.json())
+        // Run the plugin as a job
+        PluginJobs.run(this.pluginId, iterationParams)
         .then(result => {
             // Add to results
             this.results.push({
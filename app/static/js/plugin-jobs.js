@@ -0,0 +1,52 @@
+/**
+ * NetTool Plugin Jobs
+ * Runs plugins through the job API: a run is submitted to /api/jobs and
+ * followed until it finishes, so that long runs never hold a request open.
+ * Load before plugin-manager.js and plugin-iteration.js.
+ */
+
+const PluginJobs = {
+    pollInterval: 1000, // milliseconds between job status requests
+    finishedStates: ['succeeded', 'failed', 'cancelled'],
+
+    // Run a plugin and resolve with its result. onUpdate, if given, is called
+    // with the job every time its status is fetched.
+    run: async function(pluginId, params, onUpdate) {
+        let job = await this.request('/api/jobs', {
+            method: 'POST',
+            headers: {
+                'Content-Type': 'application/json'
+            },
+            body: JSON.stringify({ pluginId: pluginId, params: params })
+        });
+
+        while (!this.finishedStates.includes(job.state)) {
+            if (onUpdate) onUpdate(job);
+            await new Promise(resolve => setTimeout(resolve, this.pollInterval));
+            job = await this.request(`/api/jobs/${job.id}`);
+        }
+        if (onUpdate) onUpdate(job);
+
+        if (job.state !== 'succeeded') {
+            throw new Error(job.error || `Plugin run ${job.state}`);
+        }
+        return job.result;
+    },
+
+    // Cancel a job that has not finished yet
+    cancel: function(jobId) {
+        return this.request(`/api/jobs/${jobId}`, { method: 'DELETE' });
+    },
+
+    // Fetch a JSON response, turning API errors into exceptions
+    request: async function(url, options) {
+        const response = await fetch(url, options);
+        const data = await response.json().catch(() => ({}));
+        if (!response.ok) {
+            throw new Error(data.error || `Server responded with status: ${response.status}`);
+        }
+        return data;
+    }
+};
+
+window.PluginJobs = PluginJobs;
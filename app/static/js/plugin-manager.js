@@ -142,30 +142,14 @@ const PluginManager = {
             document.getElementById('loadingMessage').textContent = 'Plugin is still running... Please wait.';
         }, 5000);
         
-        // Call API to run plugin
-        fetch(`/api/plugins/${this.activePluginId}/run`, {
-            method: 'POST',
-            headers: {
-                'Content-Type': 'application/json'
-            },
-            body: JSON.stringify(params)
-        })
-        .then(response => {
-            clearTimeout(loadingTimeout);
-            if (!response.ok) {
-                if (response.status === 500) {
-                    // Instead of throwing an error, update the loading message
-                    document.getElementById('loadingMessage').textContent = 'Processing results...';
-                    // Try again after a short delay
-                    setTimeout(() => this.checkPluginStatus(params), 1500);
-                    return null;
-                }
-                throw new Error(`Server responded with status: ${response.status}`);
+        // Run the plugin as a job and follow it until it finishes
+        PluginJobs.run(this.activePluginId, params, job => {
+            if (job.state === 'running' && job.progress > 0) {
+                document.getElementById('loadingMessage').textContent = `Plugin is running... ${Math.round(job.progress)}%`;
             }
-            return response.json();
         })
         .then(data => {
-            if (data === null) return; // Handled by retry mechanism
+            clearTimeout(loadingTimeout);
             
             // Hide loading indicator
             document.getElementById('resultsLoading').classList.add('d-none');
@@ -187,72 +171,14 @@ const PluginManager = {
             }
         })
         .catch(error => {
+            clearTimeout(loadingTimeout);
             console.error('Error running plugin:', error);
             document.getElementById('resultsLoading').classList.add('d-none');
             document.getElementById('pluginResults').classList.remove('d-none');
             document.getElementById('pluginResults').innerHTML = `
                 <div class="alert alert-danger">
                     <i class="bi bi-exclamation-triangle-fill"></i>
-                    Error running plugin: ${error.message}
-                </div>
-            `;
-        });
-    },
-    
-    // Check plugin status and retry if necessary
-    checkPluginStatus: function(params) {
-        console.log('Checking plugin status and retrying...');
-        
-        fetch(`/api/plugins/${this.activePluginId}/run`, {
-            method: 'POST',
-            headers: {
-                'Content-Type': 'application/json'
-            },
-            body: JSON.stringify(params)
-        })
-        .then(response => {
-            if (!response.ok) {
-                if (response.status === 500) {
-                    // If it's still processing, try again after a delay
-                    document.getElementById('loadingMessage').textContent = 'Plugin is still running... Please be patient.';
-                    setTimeout(() => this.checkPluginStatus(params), 2000);
-                    return null;
-                }
-                throw new Error(`Server responded with status: ${response.status}`);
-            }
-            return response.json();
-        })
-        .then(data => {
-            if (data === null) return; // Handled by retry mechanism
-            
-            // Hide loading indicator
-            document.getElementById('resultsLoading').classList.add('d-none');
-            document.getElementById('pluginResults').classList.remove('d-none');
-            
-            // Save result for export and caching
-            this.lastResult = data;
-            
-            // Cache results for this plugin
-            try {
-                localStorage.setItem(`plugin_results_${this.activePluginId}`, JSON.stringify(data));
-                const timestamp = new Date().toLocaleString();
-                localStorage.setItem(`plugin_timestamp_${this.activePluginId}`, timestamp);
-                
-                // Display results
-                this.displayResults(data, timestamp);
-            } catch (e) {
-                console.error('Error caching results:', e);
-            }
-        })
-        .catch(error => {
-            console.error('Error checking plugin status:', error);
-            // After several retries, give up and show the error
-            document.getElementById('resultsLoading').classList.add('d-none');
-            document.getElementById('pluginResults').classList.remove('d-none');
-            document.getElementById('pluginResults').innerHTML = `
-                <div class="alert alert-danger">
-                    <i class="bi bi-exclamation-triangle-fill"></i>
-                    Error running plugin: ${error.message}
+                    Error running plugin: ${this.escapeHtml(error.message)}
                 </div>
             `;
         });
@@ -314,6 +240,15 @@ const PluginManager = {
             case 'dns_lookup':
                 this.displayDNSLookupResults(data, resultsElement);
                 break;
+            case 'dns_propagation':
+                this.displayDNSPropagationResults(data, resultsElement);
+                break;
+            case 'reverse_dns_lookup':
+                this.displayReverseDNSResults(data, resultsElement);
+                break;
+            case 'ssl_checker':
+                this.displaySSLCheckerResults(data, resultsElement);
+                break;
             case 'bandwidth_test':
                 this.displayBandwidthResults(data, resultsElement);
                 break;
@@ -331,7 +266,24 @@ const PluginManager = {
     
     // Display ping results
     displayPingResults: function(data, element) {
-        // Implementation for ping results display
+        const probes = data.probes || [];
+        let probesHtml = '';
+        probes.forEach(probe => {
+            probesHtml += `
+                <tr>
+                    <td>${probe.seq}</td>
+                    <td>${probe.from || data.address}</td>
+                    <td>${probe.ttl || 'N/A'}</td>
+                    <td>${probe.status === 'reply' ? probe.rttMs.toFixed(3) + ' ms' : 'N/A'}</td>
+                    <td>
+                        <span class="badge ${probe.status === 'reply' ? 'bg-success' : 'bg-warning'}">${probe.status}</span>
+                        ${probe.duplicates ? '<span class="badge bg-info">DUP</span>' : ''}
+                        ${probe.outOfOrder ? '<span class="badge bg-info">out of order</span>' : ''}
+                    </td>
+                </tr>
+            `;
+        });
+
         element.innerHTML = `
             <div class="ping-results">
                 <div class="row mb-4">
@@ -341,23 +293,23 @@ const PluginManager = {
                             <div class="result-body">
                                 <div class="result-row">
                                     <div class="result-label">Host</div>
-                                    <div class="result-value">${data.host}</div>
+                                    <div class="result-value">${data.host} (${data.address})</div>
                                 </div>
                                 <div class="result-row">
                                     <div class="result-label">Packets</div>
-                                    <div class="result-value">${data.transmitted} sent, ${data.received} received</div>
+                                    <div class="result-value">${data.sent} sent, ${data.received} received, ${data.duplicates} duplicates, ${data.outOfOrder} out of order</div>
                                 </div>
                                 <div class="result-row">
                                     <div class="result-label">Packet Loss</div>
-                                    <div class="result-value">${data.packetLoss}%</div>
+                                    <div class="result-value">${data.lossPercent}%</div>
                                 </div>
                                 <div class="result-row">
                                     <div class="result-label">Round Trip Time</div>
                                     <div class="result-value">
-                                        min: ${data.timeMin.toFixed(3)} ms<br>
-                                        avg: ${data.timeAvg.toFixed(3)} ms<br>
-                                        max: ${data.timeMax.toFixed(3)} ms<br>
-                                        stddev: ${data.timeStdDev.toFixed(3)} ms
+                                        min: ${data.minMs.toFixed(3)} ms<br>
+                                        avg: ${data.avgMs.toFixed(3)} ms<br>
+                                        max: ${data.maxMs.toFixed(3)} ms<br>
+                                        mdev: ${data.mdevMs.toFixed(3)} ms
                                     </div>
                                 </div>
                             </div>
@@ -368,9 +320,22 @@ const PluginManager = {
                     </div>
                 </div>
                 <div class="result-card">
-                    <div class="result-header">Raw Output</div>
+                    <div class="result-header">Probes</div>
                     <div class="result-body">
-                        <pre class="raw-output">${data.rawOutput || 'No raw output available'}</pre>
+                        <table class="table table-sm">
+                            <thead>
+                                <tr>
+                                    <th>Seq</th>
+                                    <th>From</th>
+                                    <th>TTL</th>
+                                    <th>RTT</th>
+                                    <th>Status</th>
+                                </tr>
+                            </thead>
+                            <tbody>
+                                ${probesHtml}
+                            </tbody>
+                        </table>
                     </div>
                 </div>
             </div>
@@ -380,23 +345,16 @@ const PluginManager = {
         setTimeout(() => {
             const ctx = document.getElementById('pingChart').getContext('2d');
             new Chart(ctx, {
-                type: 'bar',
+                type: 'line',
                 data: {
-                    labels: ['Min', 'Avg', 'Max'],
+                    labels: probes.map(probe => probe.seq),
                     datasets: [{
                         label: 'Round Trip Time (ms)',
-                        data: [data.timeMin, data.timeAvg, data.timeMax],
-                        backgroundColor: [
-                            'rgba(75, 192, 192, 0.2)',
-                            'rgba(54, 162, 235, 0.2)',
-                            'rgba(255, 99, 132, 0.2)'
-                        ],
-                        borderColor: [
-                            'rgba(75, 192, 192, 1)',
-                            'rgba(54, 162, 235, 1)',
-                            'rgba(255, 99, 132, 1)'
-                        ],
-                        borderWidth: 1
+                        data: probes.map(probe => probe.status === 'reply' ? probe.rttMs : null),
+                        backgroundColor: 'rgba(54, 162, 235, 0.2)',
+                        borderColor: 'rgba(54, 162, 235, 1)',
+                        borderWidth: 1,
+                        spanGaps: false
                     }]
                 },
                 options: {
@@ -416,16 +374,24 @@ const PluginManager = {
     
     // Display traceroute results
     displayTracerouteResults: function(data, element) {
-        // Implementation for traceroute results display
         let hopsHtml = '';
         data.hops.forEach(hop => {
+            const addresses = hop.addresses.length
+                ? hop.addresses.map(addr => addr.name ? `${this.escapeHtml(addr.name)} (${addr.address})` : addr.address).join('<br>')
+                : '*';
+            const answered = hop.received > 0;
             hopsHtml += `
                 <tr>
-                    <td>${hop.hop}</td>
-                    <td>${hop.host}</td>
-                    <td>${hop.name || 'N/A'}</td>
-                    <td>${hop.rtt.toFixed(3)} ms</td>
-                    <td><span class="badge ${hop.status === 'OK' ? 'bg-success' : 'bg-warning'}">${hop.status}</span></td>
+                    <td>${hop.ttl}</td>
+                    <td>${addresses}</td>
+                    <td>${hop.lossPercent}%</td>
+                    <td>${hop.sent}</td>
+                    <td>${answered ? hop.lastMs.toFixed(3) : 'N/A'}</td>
+                    <td>${answered ? hop.avgMs.toFixed(3) : 'N/A'}</td>
+                    <td>${answered ? hop.bestMs.toFixed(3) : 'N/A'}</td>
+                    <td>${answered ? hop.worstMs.toFixed(3) : 'N/A'}</td>
+                    <td>${answered ? hop.stdDevMs.toFixed(3) : 'N/A'}</td>
+                    <td>${hop.unreachable ? `<span class="badge bg-danger">${hop.unreachable}</span>` : ''}</td>
                 </tr>
             `;
         });
@@ -439,12 +405,22 @@ const PluginManager = {
                             <div class="result-body">
                                 <div class="result-row">
                                     <div class="result-label">Target Host</div>
-                                    <div class="result-value">${data.host}</div>
+                                    <div class="result-value">${data.host} (${data.address})</div>
+                                </div>
+                                <div class="result-row">
+                                    <div class="result-label">Probes</div>
+                                    <div class="result-value">${data.protocol.toUpperCase()}${data.port ? ' to port ' + data.port : ''}, ${data.cycles} ${data.mode === 'mtr' ? 'cycles' : 'per hop'}</div>
                                 </div>
                                 <div class="result-row">
                                     <div class="result-label">Hops</div>
                                     <div class="result-value">${data.hops.length}</div>
                                 </div>
+                                <div class="result-row">
+                                    <div class="result-label">Reached</div>
+                                    <div class="result-value">
+                                        <span class="badge ${data.reached ? 'bg-success' : 'bg-warning'}">${data.reached ? 'Yes' : 'No'}</span>
+                                    </div>
+                                </div>
                             </div>
                         </div>
                     </div>
@@ -460,10 +436,15 @@ const PluginManager = {
                                 <thead>
                                     <tr>
                                         <th>Hop</th>
-                                        <th>IP</th>
-                                        <th>Hostname</th>
-                                        <th>RTT</th>
-                                        <th>Status</th>
+                                        <th>Host</th>
+                                        <th>Loss</th>
+                                        <th>Sent</th>
+                                        <th>Last</th>
+                                        <th>Avg</th>
+                                        <th>Best</th>
+                                        <th>Worst</th>
+                                        <th>StDev</th>
+                                        <th></th>
                                     </tr>
                                 </thead>
                                 <tbody>
@@ -473,12 +454,6 @@ const PluginManager = {
                         </div>
                     </div>
                 </div>
-                <div class="result-card">
-                    <div class="result-header">Raw Output</div>
-                    <div class="result-body">
-                        <pre class="raw-output">${data.rawOutput || 'No raw output available'}</pre>
-                    </div>
-                </div>
             </div>
         `;
         
@@ -488,10 +463,10 @@ const PluginManager = {
             new Chart(ctx, {
                 type: 'line',
                 data: {
-                    labels: data.hops.map(hop => `Hop ${hop.hop}`),
+                    labels: data.hops.map(hop => `Hop ${hop.ttl}`),
                     datasets: [{
-                        label: 'Round Trip Time (ms)',
-                        data: data.hops.map(hop => hop.rtt),
+                        label: 'Average Round Trip Time (ms)',
+                        data: data.hops.map(hop => hop.received > 0 ? hop.avgMs : null),
                         backgroundColor: 'rgba(54, 162, 235, 0.2)',
                         borderColor: 'rgba(54, 162, 235, 1)',
                         borderWidth: 2,
@@ -613,22 +588,40 @@ const PluginManager = {
     
     // Display DNS lookup results
     displayDNSLookupResults: function(data, element) {
-        // Implementation for DNS lookup results display
+        const flags = Object.entries(data.flags).filter(([, set]) => set).map(([flag]) => flag).join(' ');
+        const dnssecBadges = {secure: 'bg-success', signed: 'bg-info', insecure: 'bg-secondary'};
         let recordsHtml = '';
-        Object.entries(data.results).forEach(([type, records]) => {
+        [['Answer', data.answers], ['Authority', data.authority], ['Additional', data.additional]].forEach(([section, records]) => {
             if (records && records.length > 0) {
                 recordsHtml += `
                     <div class="dns-record-type">
-                        <h5 class="record-type">${type} Records</h5>
-                        <ul class="record-list">
+                        <h5 class="record-type">${section}</h5>
+                        <table class="table table-sm">
+                            <thead>
+                                <tr>
+                                    <th>Name</th>
+                                    <th>TTL</th>
+                                    <th>Type</th>
+                                    <th>Data</th>
+                                </tr>
+                            </thead>
+                            <tbody>
                 `;
                 
                 records.forEach(record => {
-                    recordsHtml += `<li>${record}</li>`;
+                    recordsHtml += `
+                                <tr>
+                                    <td>${this.escapeHtml(record.name)}</td>
+                                    <td>${record.ttl}</td>
+                                    <td>${record.type}</td>
+                                    <td>${this.escapeHtml(record.data)}</td>
+                                </tr>
+                    `;
                 });
                 
                 recordsHtml += `
-                        </ul>
+                            </tbody>
+                        </table>
                     </div>
                 `;
             }
@@ -641,12 +634,31 @@ const PluginManager = {
                     <div class="result-body">
                         <div class="result-row">
                             <div class="result-label">Domain</div>
-                            <div class="result-value">${data.domain}</div>
+                            <div class="result-value">${this.escapeHtml(data.domain)}${data.query !== data.domain + '.' ? ' (' + this.escapeHtml(data.query) + ')' : ''}</div>
                         </div>
                         <div class="result-row">
                             <div class="result-label">Record Type</div>
                             <div class="result-value">${data.recordType}</div>
                         </div>
+                        <div class="result-row">
+                            <div class="result-label">Resolver</div>
+                            <div class="result-value">${this.escapeHtml(data.resolver)} (${data.transport.toUpperCase()}${data.tcpFallback ? ', retried over TCP' : ''})</div>
+                        </div>
+                        <div class="result-row">
+                            <div class="result-label">Status</div>
+                            <div class="result-value">
+                                <span class="badge ${data.rcode === 'NOERROR' ? 'bg-success' : 'bg-warning'}">${data.rcode}</span>
+                                flags: ${flags || 'none'}
+                            </div>
+                        </div>
+                        <div class="result-row">
+                            <div class="result-label">DNSSEC</div>
+                            <div class="result-value"><span class="badge ${dnssecBadges[data.dnssec] || 'bg-secondary'}">${data.dnssec}</span></div>
+                        </div>
+                        <div class="result-row">
+                            <div class="result-label">Query Time</div>
+                            <div class="result-value">${data.timeMs.toFixed(3)} ms, ${data.messageSize} bytes</div>
+                        </div>
                     </div>
                 </div>
                 
@@ -662,6 +674,307 @@ const PluginManager = {
         `;
     },
     
+    // Display DNS propagation results
+    displayDNSPropagationResults: function(data, element) {
+        let resolversHtml = '';
+        data.resolvers.forEach(resolver => {
+            const divergent = data.divergent.includes(resolver.resolver);
+            let answer;
+            if (resolver.error) {
+                answer = `<span class="text-danger">${this.escapeHtml(resolver.error)}</span>`;
+            } else if (resolver.answers.length === 0) {
+                answer = `<span class="badge bg-warning">${resolver.rcode}</span>`;
+            } else {
+                answer = resolver.answers.map(a => this.escapeHtml(a)).join('<br>');
+            }
+            resolversHtml += `
+                <tr class="${divergent ? 'table-warning' : ''}">
+                    <td>${this.escapeHtml(resolver.resolver)}${resolver.name ? ' <span class="text-muted">(' + this.escapeHtml(resolver.name) + ')</span>' : ''}</td>
+                    <td>${answer}</td>
+                    <td>${resolver.error ? '-' : resolver.ttl}</td>
+                    <td>${resolver.group >= 0 ? resolver.group + 1 : '-'}</td>
+                    <td>${resolver.error ? '-' : resolver.timeMs.toFixed(1) + ' ms'}</td>
+                </tr>
+            `;
+        });
+        
+        let groupsHtml = '';
+        data.groups.forEach((group, i) => {
+            groupsHtml += `
+                <tr>
+                    <td>${i + 1}</td>
+                    <td>${group.answers.length > 0 ? group.answers.map(a => this.escapeHtml(a)).join('<br>') : group.rcode}${group.expected ? ' <span class="badge bg-success">expected</span>' : ''}</td>
+                    <td>${group.resolvers.length}</td>
+                    <td>${group.minTtl === group.maxTtl ? group.minTtl : group.minTtl + '-' + group.maxTtl}</td>
+                </tr>
+            `;
+        });
+        
+        let status;
+        if (data.complete) {
+            status = `<span class="badge bg-success">Complete</span> all ${data.resolvers.length} resolvers agree`;
+        } else {
+            status = `<span class="badge bg-warning">Divergent</span> ${data.divergent.length} of ${data.resolvers.length} resolvers diverge; their answers expire within ${data.settlesWithin}s`;
+        }
+        
+        element.innerHTML = `
+            <div class="dns-propagation-results">
+                <div class="result-card">
+                    <div class="result-header">Propagation</div>
+                    <div class="result-body">
+                        <div class="result-row">
+                            <div class="result-label">Domain</div>
+                            <div class="result-value">${this.escapeHtml(data.domain)} ${data.recordType} (${data.transport.toUpperCase()})</div>
+                        </div>
+                        ${data.expected ? `
+                        <div class="result-row">
+                            <div class="result-label">Expected</div>
+                            <div class="result-value">${data.expected.map(a => this.escapeHtml(a)).join(', ')} (${data.propagated || 0} of ${data.resolvers.length} resolvers)</div>
+                        </div>` : ''}
+                        <div class="result-row">
+                            <div class="result-label">Status</div>
+                            <div class="result-value">${status}</div>
+                        </div>
+                        <div class="result-row">
+                            <div class="result-label">Polls</div>
+                            <div class="result-value">${data.polls} in ${(data.durationMs / 1000).toFixed(1)} s</div>
+                        </div>
+                    </div>
+                </div>
+                
+                <div class="result-card">
+                    <div class="result-header">Answer Groups</div>
+                    <div class="result-body">
+                        <table class="table table-sm">
+                            <thead>
+                                <tr>
+                                    <th>Group</th>
+                                    <th>Answers</th>
+                                    <th>Resolvers</th>
+                                    <th>TTL</th>
+                                </tr>
+                            </thead>
+                            <tbody>
+                                ${groupsHtml || '<tr><td colspan="4" class="text-muted">No resolver answered</td></tr>'}
+                            </tbody>
+                        </table>
+                    </div>
+                </div>
+                
+                <div class="result-card">
+                    <div class="result-header">Resolvers</div>
+                    <div class="result-body">
+                        <table class="table table-sm">
+                            <thead>
+                                <tr>
+                                    <th>Resolver</th>
+                                    <th>Answers</th>
+                                    <th>TTL</th>
+                                    <th>Group</th>
+                                    <th>Time</th>
+                                </tr>
+                            </thead>
+                            <tbody>
+                                ${resolversHtml}
+                            </tbody>
+                        </table>
+                    </div>
+                </div>
+            </div>
+        `;
+    },
+    
+    // Display reverse DNS results
+    displayReverseDNSResults: function(data, element) {
+        let entriesHtml = '';
+        data.results.forEach(entry => {
+            let names;
+            if (entry.error) {
+                names = `<span class="text-danger">${this.escapeHtml(entry.error)}</span>`;
+            } else if (!entry.names) {
+                names = `<span class="text-muted">${entry.rcode}</span>`;
+            } else {
+                names = entry.names.map(name => {
+                    let badge = '';
+                    if (name.verified) {
+                        badge = ' <span class="badge bg-success">verified</span>';
+                    } else if (name.forwardAddresses) {
+                        badge = ` <span class="badge bg-warning" title="${name.forwardAddresses.map(a => this.escapeHtml(a)).join(', ')}">resolves elsewhere</span>`;
+                    }
+                    return this.escapeHtml(name.name) + badge;
+                }).join('<br>');
+            }
+            entriesHtml += `
+                <tr>
+                    <td>${this.escapeHtml(entry.ipAddress)}</td>
+                    <td>${names}</td>
+                    <td>${entry.macAddress ? this.escapeHtml(entry.macAddress) + ' (' + this.escapeHtml(entry.device) + ')' : '-'}</td>
+                </tr>
+            `;
+        });
+        
+        element.innerHTML = `
+            <div class="reverse-dns-results">
+                <div class="result-card">
+                    <div class="result-header">Reverse Lookup</div>
+                    <div class="result-body">
+                        <div class="result-row">
+                            <div class="result-label">Addresses</div>
+                            <div class="result-value">${data.arpTable ? 'ARP table' : data.targets.map(t => this.escapeHtml(t)).join(', ')} (${data.addresses} addresses)</div>
+                        </div>
+                        <div class="result-row">
+                            <div class="result-label">Resolver</div>
+                            <div class="result-value">${data.resolvers.map(r => this.escapeHtml(r)).join(', ')} (${data.transport.toUpperCase()})</div>
+                        </div>
+                        <div class="result-row">
+                            <div class="result-label">Named</div>
+                            <div class="result-value">${data.named}, ${data.verified} forward-confirmed${data.failed > 0 ? `, <span class="text-danger">${data.failed} failed</span>` : ''}</div>
+                        </div>
+                        <div class="result-row">
+                            <div class="result-label">Time</div>
+                            <div class="result-value">${(data.durationMs / 1000).toFixed(2)} s</div>
+                        </div>
+                    </div>
+                </div>
+                
+                <div class="result-card">
+                    <div class="result-header">Names</div>
+                    <div class="result-body">
+                        <table class="table table-sm">
+                            <thead>
+                                <tr>
+                                    <th>Address</th>
+                                    <th>Names</th>
+                                    <th>Neighbour</th>
+                                </tr>
+                            </thead>
+                            <tbody>
+                                ${entriesHtml || '<tr><td colspan="3" class="text-muted">No address has a name</td></tr>'}
+                            </tbody>
+                        </table>
+                    </div>
+                </div>
+            </div>
+        `;
+    },
+    
+    // Display SSL/TLS checker results
+    displaySSLCheckerResults: function(data, element) {
+        let certificatesHtml = '';
+        data.certificates.forEach((cert, i) => {
+            let expiry = `<span class="badge bg-success">${cert.daysRemaining} days</span>`;
+            if (cert.expired) {
+                expiry = '<span class="badge bg-danger">expired</span>';
+            } else if (cert.daysRemaining < 30) {
+                expiry = `<span class="badge bg-warning">${cert.daysRemaining} days</span>`;
+            }
+            const names = (cert.dnsNames || []).concat(cert.ipAddresses || []);
+            certificatesHtml += `
+                <tr>
+                    <td>${i}</td>
+                    <td>${this.escapeHtml(cert.subject)}<br><small class="text-muted">issued by ${this.escapeHtml(cert.issuer)}</small></td>
+                    <td>${names.map(n => this.escapeHtml(n)).join('<br>') || '-'}</td>
+                    <td>${cert.keyType} ${cert.keyBits}${cert.curve ? ' (' + cert.curve + ')' : ''}<br><small>${cert.signatureAlgorithm}</small></td>
+                    <td>${new Date(cert.notAfter).toLocaleDateString()} ${expiry}</td>
+                </tr>
+            `;
+        });
+        
+        let protocolsHtml = '';
+        (data.protocols || []).forEach(protocol => {
+            const suites = (protocol.cipherSuites || []).map(suite =>
+                suite.insecure ? `<span class="text-danger">${suite.name}</span>` : suite.name).join('<br>');
+            protocolsHtml += `
+                <tr>
+                    <td>${protocol.version}</td>
+                    <td>${protocol.supported ? '<span class="badge bg-success">yes</span>' : '<span class="badge bg-secondary">no</span>'}</td>
+                    <td>${suites || '-'}</td>
+                </tr>
+            `;
+        });
+        
+        const validation = data.validation;
+        let validationHtml = validation.trusted && validation.hostnameMatch
+            ? `<span class="badge bg-success">Valid</span> for ${this.escapeHtml(validation.name)}`
+            : validation.errors.map(e => `<span class="text-danger">${this.escapeHtml(e)}</span>`).join('<br>');
+        
+        let ocspHtml = '<span class="badge bg-secondary">not stapled</span>';
+        if (data.ocsp.stapled) {
+            ocspHtml = data.ocsp.error
+                ? `<span class="text-danger">${this.escapeHtml(data.ocsp.error)}</span>`
+                : `<span class="badge ${data.ocsp.status === 'good' ? 'bg-success' : 'bg-danger'}">${data.ocsp.status}</span>`;
+        }
+        
+        element.innerHTML = `
+            <div class="ssl-checker-results">
+                <div class="result-card">
+                    <div class="result-header">Connection</div>
+                    <div class="result-body">
+                        <div class="result-row">
+                            <div class="result-label">Server</div>
+                            <div class="result-value">${this.escapeHtml(data.host)} (${this.escapeHtml(data.address)})${data.starttls ? ' via ' + data.starttls.toUpperCase() + ' STARTTLS' : ''}</div>
+                        </div>
+                        <div class="result-row">
+                            <div class="result-label">Negotiated</div>
+                            <div class="result-value">${data.protocol}, ${data.cipherSuite}</div>
+                        </div>
+                        <div class="result-row">
+                            <div class="result-label">Chain</div>
+                            <div class="result-value">${validationHtml} <small class="text-muted">(${this.escapeHtml(validation.roots)} roots)</small></div>
+                        </div>
+                        <div class="result-row">
+                            <div class="result-label">OCSP Stapling</div>
+                            <div class="result-value">${ocspHtml}</div>
+                        </div>
+                        ${data.warnings.length > 0 ? `
+                        <div class="result-row">
+                            <div class="result-label">Warnings</div>
+                            <div class="result-value text-warning">${data.warnings.map(w => this.escapeHtml(w)).join('<br>')}</div>
+                        </div>` : ''}
+                    </div>
+                </div>
+                
+                <div class="result-card">
+                    <div class="result-header">Certificate Chain</div>
+                    <div class="result-body">
+                        <table class="table table-sm">
+                            <thead>
+                                <tr>
+                                    <th>#</th>
+                                    <th>Subject</th>
+                                    <th>Names</th>
+                                    <th>Key</th>
+                                    <th>Expires</th>
+                                </tr>
+                            </thead>
+                            <tbody>
+                                ${certificatesHtml}
+                            </tbody>
+                        </table>
+                    </div>
+                </div>
+                ${protocolsHtml ? `
+                <div class="result-card">
+                    <div class="result-header">Protocols</div>
+                    <div class="result-body">
+                        <table class="table table-sm">
+                            <thead>
+                                <tr>
+                                    <th>Version</th>
+                                    <th>Supported</th>
+                                    <th>Cipher Suites</th>
+                                </tr>
+                            </thead>
+                            <tbody>
+                                ${protocolsHtml}
+                            </tbody>
+                        </table>
+                    </div>
+                </div>` : ''}
+            </div>
+        `;
+    },
+    
     // Display bandwidth test results
     displayBandwidthResults: function(data, element) {
         // Implementation for bandwidth test results display
@@ -13,12 +13,27 @@ export const networkApi = {
   getNetworkInfo: () => api.get('/network-info'),
 }
 
+// Service latency probe API
+export const serviceProbesApi = {
+  getAll: () => api.get('/service-probes'),
+  replaceAll: (probes) => api.put('/service-probes', probes),
+  save: (probe) => api.post('/service-probes', probe),
+  remove: (name) => api.delete(`/service-probes/${encodeURIComponent(name)}`),
+}
+
 // Plugins API
 export const pluginsApi = {
   getAll: () => api.get('/plugins'),
   getById: (id) => api.get(`/plugins/${id}`),
-  run: (id, params = {}) => api.post(`/plugins/${id}/run`, params),
-  runPlugin: (id, params = {}) => api.post('/run-plugin', { id, params }),
+}
+
+// Plugin jobs API
+export const jobsApi = {
+  submit: (pluginId, params = {}) => api.post('/jobs', { pluginId, params }),
+  list: (filter = {}) => api.get('/jobs', { params: filter }),
+  get: (id) => api.get(`/jobs/${id}`),
+  events: (id, after = 0) => api.get(`/jobs/${id}/events`, { params: { after } }),
+  cancel: (id) => api.delete(`/jobs/${id}`),
 }
 
 // Plugin Manager API
@@ -5,6 +5,9 @@ export function useWebSocket() {
   const [connected, setConnected] = useState(false)
   const wsRef = useRef(null)
   const reconnectTimeoutRef = useRef(null)
+  // Topics beyond the default network subscription, re-sent after reconnecting
+  const subscriptionsRef = useRef(new Map())
+  const listenersRef = useRef(new Set())
 
   const connect = useCallback(() => {
     // Determine WebSocket URL
@@ -25,6 +28,9 @@ export function useWebSocket() {
           clearTimeout(reconnectTimeoutRef.current)
           reconnectTimeoutRef.current = null
         }
+        subscriptionsRef.current.forEach((interval, topic) => {
+          wsRef.current.send(JSON.stringify({ type: 'subscribe', topic, interval }))
+        })
       }
 
       wsRef.current.onmessage = (event) => {
@@ -33,6 +39,7 @@ export function useWebSocket() {
           if (message.type === 'network_update') {
             setNetworkData(message.data)
           }
+          listenersRef.current.forEach((listener) => listener(message))
         } catch (error) {
           console.error('Error parsing WebSocket message:', error)
         }
@@ -78,9 +85,31 @@ export function useWebSocket() {
     }
   }, [])
 
+  // subscribe asks for a topic such as 'interface:eth0', 'alerts' or
+  // 'plugin-job:<id>'; interval (seconds) applies to periodic topics
+  const subscribe = useCallback((topic, interval) => {
+    subscriptionsRef.current.set(topic, interval)
+    sendMessage({ type: 'subscribe', topic, interval })
+  }, [sendMessage])
+
+  const unsubscribe = useCallback((topic) => {
+    subscriptionsRef.current.delete(topic)
+    sendMessage({ type: 'unsubscribe', topic })
+  }, [sendMessage])
+
+  // addListener registers a callback for every message; it returns a function
+  // that removes it again
+  const addListener = useCallback((listener) => {
+    listenersRef.current.add(listener)
+    return () => listenersRef.current.delete(listener)
+  }, [])
+
   return {
     networkData,
     connected,
     sendMessage,
+    subscribe,
+    unsubscribe,
+    addListener,
   }
 }
@@ -1,4 +1,4 @@
-import { useState, useEffect } from 'react'
+import { useState, useEffect, useRef } from 'react'
 import { useParams, Link } from 'react-router-dom'
 import { motion } from 'framer-motion'
 import { 
@@ -9,7 +9,9 @@ import {
   AlertCircle,
   CheckCircle
 } from 'lucide-react'
-import { pluginsApi } from '../api'
+import { pluginsApi, jobsApi } from '../api'
+
+const JOB_POLL_INTERVAL = 1000
 
 export default function PluginPage() {
   const { id } = useParams()
@@ -19,6 +21,12 @@ export default function PluginPage() {
   const [result, setResult] = useState(null)
   const [error, setError] = useState(null)
   const [params, setParams] = useState({})
+  const [output, setOutput] = useState([])
+  const [progress, setProgress] = useState(null)
+  const jobRef = useRef(null)
+
+  // Stop following a job when leaving the page
+  useEffect(() => () => { jobRef.current = null }, [])
 
   useEffect(() => {
     loadPlugin()
@@ -51,9 +59,32 @@ export default function PluginPage() {
     setRunning(true)
     setResult(null)
     setError(null)
+    setOutput([])
+    setProgress(null)
     try {
-      const response = await pluginsApi.run(id, params)
-      setResult(response.data)
+      let { data: job } = await jobsApi.submit(id, params)
+      jobRef.current = job.id
+      let lastSeq = 0
+      const fetchOutput = async () => {
+        const { data: events } = await jobsApi.events(job.id, lastSeq)
+        if (events.length === 0) return
+        lastSeq = events[events.length - 1].seq
+        const lines = events.filter(e => e.type === 'line').map(e => e.line)
+        if (lines.length > 0) setOutput(prev => [...prev, ...lines])
+        const last = events.filter(e => e.type === 'progress').pop()
+        if (last) setProgress(last.progress)
+      }
+      while (!['succeeded', 'failed', 'cancelled'].includes(job.state)) {
+        await new Promise(resolve => setTimeout(resolve, JOB_POLL_INTERVAL))
+        if (jobRef.current !== job.id) return
+        job = (await jobsApi.get(job.id)).data
+        await fetchOutput()
+      }
+      if (job.state === 'succeeded') {
+        setResult(job.result)
+      } else {
+        setError(job.error || `Plugin run ${job.state}`)
+      }
     } catch (err) {
       setError(err.response?.data?.error || 'Failed to run plugin')
       console.error(err)
@@ -66,6 +97,17 @@ export default function PluginPage() {
     setParams(prev => ({ ...prev, [name]: value }))
   }
 
+  // File parameters are sent as base64 data URLs
+  const handleFileChange = (name, file) => {
+    if (!file) {
+      handleParamChange(name, '')
+      return
+    }
+    const reader = new FileReader()
+    reader.onload = () => handleParamChange(name, reader.result)
+    reader.readAsDataURL(file)
+  }
+
   if (loading) {
     return (
       <div className="flex items-center justify-center py-12">
@@ -133,6 +175,31 @@ export default function PluginPage() {
                           <option key={opt} value={opt}>{opt}</option>
                         ))}
                       </select>
+                    ) : param.Type === 'interface' ? (
+                      <select
+                        value={params[param.Name] || ''}
+                        onChange={(e) => handleParamChange(param.Name, e.target.value)}
+                        className="w-full px-4 py-2 bg-dark-900/50 border border-dark-800 rounded-xl text-white focus:outline-none focus:border-primary-500/50 focus:ring-2 focus:ring-primary-500/20"
+                      >
+                        <option value="">Select an interface</option>
+                        {param.Options?.map((opt) => (
+                          <option key={opt.Value} value={opt.Value}>{opt.Label}</option>
+                        ))}
+                      </select>
+                    ) : param.Type === 'file' ? (
+                      <input
+                        type="file"
+                        onChange={(e) => handleFileChange(param.Name, e.target.files[0])}
+                        className="w-full text-sm text-dark-300 file:mr-4 file:px-4 file:py-2 file:rounded-xl file:border-0 file:bg-primary-500/20 file:text-primary-400"
+                      />
+                    ) : param.Type === 'hosts' ? (
+                      <textarea
+                        rows={3}
+                        value={params[param.Name] || ''}
+                        onChange={(e) => handleParamChange(param.Name, e.target.value)}
+                        placeholder={param.Placeholder || param.Description}
+                        className="w-full px-4 py-2 bg-dark-900/50 border border-dark-800 rounded-xl text-white placeholder:text-dark-500 focus:outline-none focus:border-primary-500/50 focus:ring-2 focus:ring-primary-500/20"
+                      />
                     ) : param.Type === 'boolean' ? (
                       <label className="flex items-center gap-3 cursor-pointer">
                         <input
@@ -143,7 +210,7 @@ export default function PluginPage() {
                         />
                         <span className="text-dark-300">{param.Description}</span>
                       </label>
-                    ) : param.Type === 'number' ? (
+                    ) : param.Type === 'number' || param.Type === 'port' ? (
                       <input
                         type="number"
                         value={params[param.Name] || ''}
@@ -207,6 +274,21 @@ export default function PluginPage() {
               </motion.div>
             )}
 
+            {running && (output.length > 0 || progress !== null) && (
+              <div className="space-y-2 mb-4">
+                {progress !== null && (
+                  <div className="h-2 bg-dark-800 rounded-full overflow-hidden">
+                    <div className="h-full bg-primary-500 transition-all" style={{ width: `${progress}%` }} />
+                  </div>
+                )}
+                {output.length > 0 && (
+                  <pre className="bg-dark-900/50 p-4 rounded-xl overflow-auto max-h-96 text-sm text-dark-200 font-mono">
+                    {output.join('\n')}
+                  </pre>
+                )}
+              </div>
+            )}
+
             {result ? (
               <motion.div
                 initial={{ opacity: 0 }}
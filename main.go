@@ -2,18 +2,23 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strconv"
 	"strings"
-	"sync"
+	"syscall"
 	"time"
 
 	"github.com/NetScout-Go/NetTool/app/core"
+	"github.com/NetScout-Go/NetTool/app/history"
 	"github.com/NetScout-Go/NetTool/app/plugins"
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
@@ -41,6 +46,8 @@ func main() {
 	// Parse command line flags
 	port := flag.Int("port", 8080, "Port to run the server on")
 	version := flag.Bool("version", false, "Show version information")
+	probeConfig := flag.String("probe-config", core.DefaultServiceProbeConfigPath, "Path to the service latency probe configuration")
+	historyDir := flag.String("history-dir", "app/history/data", "Directory of the telemetry history store (empty to disable)")
 	flag.Parse()
 
 	// Show version if requested
@@ -85,8 +92,33 @@ func main() {
 		c.Next()
 	})
 
+	// Load the service latency probe targets, keeping the defaults on failure
+	if err := core.LoadServiceProbes(*probeConfig); err != nil {
+		log.Printf("⚠️  Failed to load service probes from %s: %v", *probeConfig, err)
+	}
+
+	// Open the telemetry history store
+	var historyStore *history.Store
+	if *historyDir != "" {
+		store, err := history.Open(*historyDir)
+		if err != nil {
+			log.Printf("⚠️  Telemetry history disabled: %v", err)
+		} else {
+			historyStore = store
+			closeOnSignal(historyStore)
+		}
+	}
+
+	// Sample network information in the background; the REST API and the
+	// broadcaster only read the cached snapshot
+	sampler := core.NewSampler(core.DefaultSamplerConfig())
+	sampler.Start()
+
 	// Start network info broadcaster in the background
-	go startNetworkInfoBroadcaster()
+	go startNetworkInfoBroadcaster(sampler)
+	if historyStore != nil {
+		go recordHistoryFrom(sampler, historyStore)
+	}
 
 	// Initialize plugin manager
 	pluginManager := plugins.NewPluginManager()
@@ -94,6 +126,15 @@ func main() {
 	// Register plugins - our new implementation handles both modular and hardcoded plugins
 	pluginManager.RegisterPlugins()
 
+	// Run plugins in the background and push job updates to subscribed clients
+	jobManager := plugins.NewJobManager(pluginManager, 0)
+	jobManager.OnEvent(func(job plugins.Job) {
+		publish(topicJobPrefix+job.ID, "job_update", job)
+	})
+	jobManager.OnOutput(func(jobID string, event plugins.JobEvent) {
+		publish(topicJobPrefix+jobID, "job_event", event)
+	})
+
 	// Initialize plugin installer
 	pluginInstaller := plugins.NewPluginInstaller("app/plugins/plugins", pluginManager)
 
@@ -129,17 +170,39 @@ func main() {
 				return
 			}
 
-			result, err := pluginManager.RunPlugin(pluginID, params)
+			result, err := pluginManager.RunPluginContext(c.Request.Context(), pluginID, params)
 			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				respondPluginError(c, err)
 				return
 			}
 			c.JSON(http.StatusOK, result)
 		})
 
+		// Check that a plugin is able to run; out-of-process plugins are asked
+		// by one of their processes
+		api.GET("/plugins/:id/health", func(c *gin.Context) {
+			ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+			defer cancel()
+
+			err := pluginManager.CheckHealth(ctx, c.Param("id"))
+			switch {
+			case errors.Is(err, plugins.ErrPluginNotFound):
+				c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			case err != nil:
+				c.JSON(http.StatusServiceUnavailable, gin.H{"status": "error", "error": err.Error()})
+			default:
+				c.JSON(http.StatusOK, gin.H{"status": "ok"})
+			}
+		})
+
 		// Get network information for the dashboard
+		// An optional ?interface= query parameter restricts the report to one interface
 		api.GET("/network-info", func(c *gin.Context) {
-			networkInfo, err := core.GetNetworkInfo()
+			networkInfo, err := sampler.Snapshot(c.Query("interface"))
+			if errors.Is(err, core.ErrInterfaceNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+				return
+			}
 			if err != nil {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 				return
@@ -151,6 +214,195 @@ func main() {
 			c.JSON(http.StatusOK, networkInfo)
 		})
 
+		// Get per-interface details for every network interface
+		api.GET("/interfaces", func(c *gin.Context) {
+			c.JSON(http.StatusOK, sampler.Interfaces())
+		})
+
+		// Connected WebSocket clients with their subscriptions and queue statistics
+		api.GET("/ws/clients", func(c *gin.Context) {
+			c.JSON(http.StatusOK, wsHub.stats())
+		})
+
+		// Telemetry history, e.g. /api/history?metric=latency&from=2024-01-01T00:00:00Z&step=5m
+		api.GET("/history", func(c *gin.Context) {
+			if historyStore == nil {
+				c.JSON(http.StatusServiceUnavailable, gin.H{"error": "telemetry history is disabled"})
+				return
+			}
+
+			metric := c.Query("metric")
+			if metric == "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "metric parameter is required", "metrics": historyStore.Metrics()})
+				return
+			}
+
+			to := time.Now()
+			if value := c.Query("to"); value != "" {
+				parsed, err := parseHistoryTime(value)
+				if err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "invalid 'to': " + err.Error()})
+					return
+				}
+				to = parsed
+			}
+
+			from := to.Add(-time.Hour)
+			if value := c.Query("from"); value != "" {
+				parsed, err := parseHistoryTime(value)
+				if err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "invalid 'from': " + err.Error()})
+					return
+				}
+				from = parsed
+			}
+
+			var step time.Duration
+			if value := c.Query("step"); value != "" {
+				parsed, err := parseHistoryStep(value)
+				if err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "invalid 'step': " + err.Error()})
+					return
+				}
+				step = parsed
+			}
+
+			points, err := historyStore.Query(metric, from, to, step)
+			if err != nil {
+				if errors.Is(err, history.ErrInvalidMetric) || errors.Is(err, history.ErrInvalidRange) {
+					c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+					return
+				}
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+
+			c.JSON(http.StatusOK, gin.H{
+				"metric": metric,
+				"from":   from,
+				"to":     to,
+				"step":   step.String(),
+				"points": points,
+			})
+		})
+
+		// List the metrics with recorded history
+		api.GET("/history/metrics", func(c *gin.Context) {
+			if historyStore == nil {
+				c.JSON(http.StatusServiceUnavailable, gin.H{"error": "telemetry history is disabled"})
+				return
+			}
+			c.JSON(http.StatusOK, historyStore.Metrics())
+		})
+
+		// Service latency probe targets
+		api.GET("/service-probes", func(c *gin.Context) {
+			c.JSON(http.StatusOK, core.GetServiceProbes())
+		})
+
+		// Replace every probe target
+		api.PUT("/service-probes", func(c *gin.Context) {
+			var probes []core.ServiceProbe
+			if err := c.BindJSON(&probes); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+
+			if err := core.SetServiceProbes(probes); err != nil {
+				respondProbeError(c, err)
+				return
+			}
+			c.JSON(http.StatusOK, core.GetServiceProbes())
+		})
+
+		// Add a probe target or replace the one with the same name
+		api.POST("/service-probes", func(c *gin.Context) {
+			var probe core.ServiceProbe
+			if err := c.BindJSON(&probe); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+
+			if err := core.AddServiceProbe(probe); err != nil {
+				respondProbeError(c, err)
+				return
+			}
+			c.JSON(http.StatusOK, core.GetServiceProbes())
+		})
+
+		// Remove a probe target by name
+		api.DELETE("/service-probes/:name", func(c *gin.Context) {
+			if err := core.RemoveServiceProbe(c.Param("name")); err != nil {
+				respondProbeError(c, err)
+				return
+			}
+			c.JSON(http.StatusOK, core.GetServiceProbes())
+		})
+
+		// Plugin jobs: submit a run and follow it without holding the request open
+		api.POST("/jobs", func(c *gin.Context) {
+			var request struct {
+				PluginID string                 `json:"pluginId"`
+				Params   map[string]interface{} `json:"params"`
+			}
+			if err := c.BindJSON(&request); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+
+			job, err := jobManager.Submit(request.PluginID, request.Params)
+			if err != nil {
+				respondJobError(c, err)
+				return
+			}
+			c.JSON(http.StatusAccepted, job)
+		})
+
+		api.GET("/jobs", func(c *gin.Context) {
+			c.JSON(http.StatusOK, jobManager.List(plugins.JobFilter{
+				PluginID: c.Query("plugin"),
+				State:    plugins.JobState(c.Query("state")),
+			}))
+		})
+
+		api.GET("/jobs/:id", func(c *gin.Context) {
+			job, err := jobManager.Get(c.Param("id"))
+			if err != nil {
+				respondJobError(c, err)
+				return
+			}
+			c.JSON(http.StatusOK, job)
+		})
+
+		// Output of a job's plugin, optionally only the events after a sequence number
+		api.GET("/jobs/:id/events", func(c *gin.Context) {
+			after := 0
+			if value := c.Query("after"); value != "" {
+				parsed, err := strconv.Atoi(value)
+				if err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "after must be a sequence number"})
+					return
+				}
+				after = parsed
+			}
+
+			events, err := jobManager.Events(c.Param("id"), after)
+			if err != nil {
+				respondJobError(c, err)
+				return
+			}
+			c.JSON(http.StatusOK, events)
+		})
+
+		api.DELETE("/jobs/:id", func(c *gin.Context) {
+			job, err := jobManager.Cancel(c.Param("id"))
+			if err != nil {
+				respondJobError(c, err)
+				return
+			}
+			c.JSON(http.StatusOK, job)
+		})
+
 		// General plugin runner endpoint for dashboard features
 		api.POST("/run-plugin", func(c *gin.Context) {
 			var request struct {
@@ -163,9 +415,9 @@ func main() {
 				return
 			}
 
-			result, err := pluginManager.RunPlugin(request.ID, request.Params)
+			result, err := pluginManager.RunPluginContext(c.Request.Context(), request.ID, request.Params)
 			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				respondPluginError(c, err)
 				return
 			}
 
@@ -443,155 +695,131 @@ func main() {
 	log.Fatal(r.Run(fmt.Sprintf(":%d", *port)))
 }
 
-// Clients map to manage WebSocket connections
-var clients = make(map[*websocket.Conn]bool)
-var clientsMutex = sync.Mutex{}
-
-func handleWebSocketConnection(w http.ResponseWriter, r *http.Request) {
-	ws, err := upgrader.Upgrade(w, r, nil)
-	if err != nil {
-		log.Printf("Error upgrading to WebSocket: %v", err)
-		return
+// respondProbeError maps service probe configuration errors to HTTP status codes
+func respondProbeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, core.ErrInvalidProbe):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, core.ErrProbeNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 	}
-	defer ws.Close()
-
-	// Register new client
-	clientsMutex.Lock()
-	clients[ws] = true
-	clientsMutex.Unlock()
-
-	// Remove client when connection closes
-	defer func() {
-		clientsMutex.Lock()
-		delete(clients, ws)
-		clientsMutex.Unlock()
-	}()
+}
 
-	// No need to start individual updaters anymore
-	// We're using the global broadcaster
+// respondPluginError maps plugin run errors to HTTP status codes. Invalid
+// parameters are listed per field.
+func respondPluginError(c *gin.Context, err error) {
+	var paramsErr *plugins.ParamsError
+	switch {
+	case errors.Is(err, plugins.ErrPluginNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.As(err, &paramsErr):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "fields": paramsErr.Fields})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
 
-	// Handle incoming messages (not required for this application, but included for completeness)
-	for {
-		_, _, err := ws.ReadMessage()
-		if err != nil {
-			log.Printf("Error reading message: %v", err)
-			break
-		}
+// respondJobError maps plugin job errors to HTTP status codes
+func respondJobError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, plugins.ErrJobNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, plugins.ErrJobFinished):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		respondPluginError(c, err)
 	}
 }
 
-func sendPeriodicNetworkUpdates(ws *websocket.Conn) {
-	ticker := time.NewTicker(5 * time.Second)
+// recordHistoryFrom records the sampler's snapshot in the history store on a
+// fixed interval, whether or not any client is connected
+func recordHistoryFrom(sampler *core.Sampler, store *history.Store) {
+	ticker := time.NewTicker(3 * time.Second)
 	defer ticker.Stop()
 
-	for {
-		select {
-		case <-ticker.C:
-			networkInfo, err := core.GetNetworkInfo()
-			if err != nil {
-				log.Printf("Error getting network info: %v", err)
-				continue
-			}
-
-			// Check if this specific client is still connected
-			clientsMutex.Lock()
-			if !clients[ws] {
-				clientsMutex.Unlock()
-				return
-			}
-			clientsMutex.Unlock()
-
-			// Send update to this client
-			err = ws.WriteJSON(map[string]interface{}{
-				"type":      "network_update",
-				"data":      networkInfo,
-				"timestamp": time.Now().Format(time.RFC3339),
-			})
-			if err != nil {
-				log.Printf("Error sending network update: %v", err)
-				return
-			}
+	for now := range ticker.C {
+		networkInfo, err := sampler.Snapshot("")
+		if err != nil {
+			log.Printf("Error getting network info for history: %v", err)
+			continue
 		}
+		networkInfo.Timestamp = now
+
+		recordNetworkHistory(store, networkInfo)
 	}
 }
 
-// Broadcast network information to all connected clients
-func broadcastNetworkInfo() {
-	networkInfo, err := core.GetNetworkInfo()
-	if err != nil {
-		log.Printf("Error getting network info for broadcast: %v", err)
-		return
+// recordNetworkHistory stores the dashboard metrics of a network snapshot.
+// Latency and jitter are skipped while every probe is lost, since they would
+// otherwise be recorded as zero.
+func recordNetworkHistory(store *history.Store, info *core.NetworkInfo) {
+	values := map[string]float64{
+		"packet_loss": info.Connection.PacketLoss,
+		"bandwidth":   info.Traffic.CurrentBandwidth,
+		"rx_mbps":     info.Traffic.RxMbps,
+		"tx_mbps":     info.Traffic.TxMbps,
+		"arp_count":   float64(len(info.ARPEntries)),
 	}
 
-	clientsMutex.Lock()
-	defer clientsMutex.Unlock()
-
-	// Send update to all connected clients
-	for client := range clients {
-		err := client.WriteJSON(map[string]interface{}{
-			"type":      "network_update",
-			"data":      networkInfo,
-			"timestamp": time.Now().Format(time.RFC3339),
-		})
-		if err != nil {
-			log.Printf("Error sending network update to client: %v", err)
-			// Consider removing the client from the clients map if needed
-		}
+	if info.Connection.PacketLoss < 100 {
+		values["latency"] = info.Connection.LatencyMS
+		values["jitter"] = info.Connection.JitterMS
 	}
-}
 
-// startNetworkInfoBroadcaster sends network updates to all connected clients
-func startNetworkInfoBroadcaster() {
-	ticker := time.NewTicker(3 * time.Second)
-	defer ticker.Stop()
+	if info.Connection.SignalStrength != 0 {
+		values["signal"] = float64(info.Connection.SignalStrength)
+	}
 
-	for {
-		<-ticker.C
+	for _, service := range info.ServiceLatency {
+		if service.Success {
+			values["service:"+service.Name] = service.LatencyMS
+		}
+	}
 
-		// Only broadcast if there are clients connected
-		clientsMutex.Lock()
-		clientCount := len(clients)
-		clientsMutex.Unlock()
+	if err := store.RecordAll(info.Timestamp, values); err != nil {
+		log.Printf("Error recording network history: %v", err)
+	}
+}
 
-		if clientCount == 0 {
-			continue
-		}
+// parseHistoryTime accepts RFC 3339 timestamps and unix seconds
+func parseHistoryTime(value string) (time.Time, error) {
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
 
-		// Get network info once for all clients
-		networkInfo, err := core.GetNetworkInfo()
-		if err != nil {
-			log.Printf("Error getting network info for broadcast: %v", err)
-			continue
+// parseHistoryStep accepts durations such as "5m" and plain seconds
+func parseHistoryStep(value string) (time.Duration, error) {
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds < 0 {
+			return 0, errors.New("step must not be negative")
 		}
+		return time.Duration(seconds) * time.Second, nil
+	}
 
-		// Set timestamp to current time
-		networkInfo.Timestamp = time.Now()
+	step, err := time.ParseDuration(value)
+	if err == nil && step < 0 {
+		return 0, errors.New("step must not be negative")
+	}
+	return step, err
+}
 
-		// Prepare the message once for all clients
-		message := map[string]interface{}{
-			"type":      "network_update",
-			"data":      networkInfo,
-			"timestamp": time.Now().Format(time.RFC3339),
-		}
+// closeOnSignal flushes the history store before the process exits on
+// SIGINT or SIGTERM
+func closeOnSignal(store *history.Store) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
 
-		// Broadcast to all clients
-		clientsMutex.Lock()
-		for client := range clients {
-			// Send in a non-blocking way
-			go func(c *websocket.Conn) {
-				if err := c.WriteJSON(message); err != nil {
-					log.Printf("Error broadcasting to client: %v", err)
-
-					// Close and remove failed client
-					c.Close()
-					clientsMutex.Lock()
-					delete(clients, c)
-					clientsMutex.Unlock()
-				}
-			}(client)
+	go func() {
+		<-signals
+		if err := store.Close(); err != nil {
+			log.Printf("Error closing history store: %v", err)
 		}
-		clientsMutex.Unlock()
-	}
+		os.Exit(0)
+	}()
 }
 
 // serveSPA configures the router to serve the React Single Page Application
@@ -0,0 +1,143 @@
+#!/usr/bin/env bash
+set -euo pipefail
+
+# Checks the native traceroute against a path of two routers built from
+# network namespaces:
+#
+#   nt-client -- nt-r1 -- nt-r2 -- nt-target
+#
+# Every probe protocol is traced over IPv4 and IPv6, and the MTR mode is run
+# for a few rounds through the iterate command. Needs root and iproute2.
+
+if [[ $EUID -ne 0 ]]; then
+    echo "This script must be run as root to create network namespaces." >&2
+    exit 1
+fi
+
+PYTHON_BIN="$(command -v python3 || command -v python || true)"
+if [[ -z "$PYTHON_BIN" ]]; then
+    echo "Python is required to check the results." >&2
+    exit 1
+fi
+
+ROOT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")/.." && pwd)"
+WORK_DIR="$(mktemp -d)"
+NAMESPACES=(nt-client nt-r1 nt-r2 nt-target)
+
+cleanup() {
+    for ns in "${NAMESPACES[@]}"; do
+        ip netns del "$ns" 2>/dev/null || true
+    done
+    rm -rf "$WORK_DIR"
+}
+trap cleanup EXIT
+
+# link connects two namespaces on subnet n: the first gets address .1 and the
+# second .2, with fd91:n::1 and fd91:n::2 for IPv6
+link() {
+    local left=$1 right=$2 n=$3
+    ip link add "nt$n-a" netns "$left" type veth peer name "nt$n-b" netns "$right"
+    ip -n "$left" addr add "10.91.$n.1/24" dev "nt$n-a"
+    ip -n "$right" addr add "10.91.$n.2/24" dev "nt$n-b"
+    ip -n "$left" addr add "fd91:$n::1/64" dev "nt$n-a" nodad
+    ip -n "$right" addr add "fd91:$n::2/64" dev "nt$n-b" nodad
+    ip -n "$left" link set "nt$n-a" up
+    ip -n "$right" link set "nt$n-b" up
+}
+
+echo "Building the iterate command..."
+(cd "$ROOT_DIR" && go build -o "$WORK_DIR/iterate" ./app/cmd/iterate)
+
+echo "Creating the namespaces..."
+for ns in "${NAMESPACES[@]}"; do
+    ip netns add "$ns"
+    ip -n "$ns" link set lo up
+    # Routers must answer every probe, however fast they are sent, and
+    # without waiting for duplicate address detection on new links
+    ip netns exec "$ns" sysctl -qw net.ipv4.icmp_ratelimit=0 net.ipv4.icmp_msgs_per_sec=100000 net.ipv4.icmp_msgs_burst=10000 \
+        net.ipv6.icmp.ratelimit=0 net.ipv6.conf.default.accept_dad=0
+done
+for ns in nt-r1 nt-r2; do
+    ip netns exec "$ns" sysctl -qw net.ipv4.ip_forward=1 net.ipv6.conf.all.forwarding=1
+done
+
+# The client is .2 on subnet 1, the routers' addresses towards it are
+# 10.91.1.1 and 10.91.2.2, and the target is 10.91.3.2
+link nt-r1 nt-client 1
+link nt-r1 nt-r2 2
+link nt-r2 nt-target 3
+ip -n nt-client route add default via 10.91.1.1
+ip -n nt-client -6 route add default via fd91:1::1
+ip -n nt-r1 route add 10.91.3.0/24 via 10.91.2.2
+ip -n nt-r1 -6 route add fd91:3::/64 via fd91:2::2
+ip -n nt-r2 route add 10.91.1.0/24 via 10.91.2.1
+ip -n nt-r2 -6 route add fd91:1::/64 via fd91:2::1
+ip -n nt-target route add default via 10.91.3.1
+ip -n nt-target -6 route add default via fd91:3::1
+
+# check_hops checks that the hops of the results in a file are the expected
+# addresses, that the target was reached and that every hop sent the
+# expected number of probes
+check_hops() {
+    local file=$1 sent=$2
+    shift 2
+    "$PYTHON_BIN" - "$file" "$sent" "$@" <<'EOF'
+import json
+import sys
+
+path, sent, expected = sys.argv[1], int(sys.argv[2]), sys.argv[3:]
+with open(path) as f:
+    data = json.load(f)
+# Iterations are saved as a list, single runs as their result
+result = data[-1]["result"] if isinstance(data, list) else data
+
+hops = [[a["address"] for a in hop["addresses"]] for hop in result["hops"]]
+if hops != [[address] for address in expected]:
+    sys.exit(f"hops {hops}, expected {expected}")
+if not result["reached"]:
+    sys.exit("the target was not reached")
+for hop in result["hops"]:
+    if hop["sent"] != sent or hop["received"] != sent:
+        sys.exit(f"hop {hop['ttl']} answered {hop['received']} of {hop['sent']} probes, expected {sent}")
+EOF
+}
+
+trace() {
+    ip netns exec nt-client "$WORK_DIR/iterate" -plugin traceroute -param resolveNames=false -param timeout=1s "$@" >"$WORK_DIR/log" 2>&1 || {
+        cat "$WORK_DIR/log" >&2
+        return 1
+    }
+}
+
+failed=0
+for protocol in udp icmp tcp; do
+    for family in 4 6; do
+        if [[ $family == 4 ]]; then
+            target=10.91.3.2
+            expected=(10.91.1.1 10.91.2.2 10.91.3.2)
+        else
+            target=fd91:3::2
+            expected=(fd91:1::1 fd91:2::2 fd91:3::2)
+        fi
+
+        if trace -param "host=$target" -param "protocol=$protocol" -param queries=3 -output "$WORK_DIR/result.json" &&
+            check_hops "$WORK_DIR/result.json" 3 "${expected[@]}"; then
+            echo "PASS $protocol over IPv$family"
+        else
+            echo "FAIL $protocol over IPv$family"
+            failed=1
+        fi
+    done
+done
+
+# MTR keeps its statistics across iterations, so every hop has one probe per
+# iteration
+if trace -param host=10.91.3.2 -param mode=mtr -iterate -max 3 -delay 0 -output "$WORK_DIR/mtr.json" &&
+    check_hops "$WORK_DIR/mtr.json" 3 10.91.1.1 10.91.2.2 10.91.3.2; then
+    echo "PASS mtr"
+else
+    echo "FAIL mtr"
+    failed=1
+fi
+
+exit $failed
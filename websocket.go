@@ -0,0 +1,358 @@
+// Package main provides the WebSocket message protocol of /ws.
+// Clients subscribe to the topics they need and only receive updates for those.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NetScout-Go/NetTool/app/core"
+	"github.com/gorilla/websocket"
+)
+
+// Topics clients can subscribe to. Topics ending in a colon take a name,
+// e.g. "interface:eth0" or "plugin-job:<id>".
+const (
+	topicNetwork         = "network"
+	topicAlerts          = "alerts"
+	topicInterfacePrefix = "interface:"
+	topicJobPrefix       = "plugin-job:"
+)
+
+// Update intervals of the periodic topics. Snapshots never change faster than
+// the sampler's counter interval, so shorter intervals are raised to it.
+const (
+	defaultUpdateInterval = 3 * time.Second
+	minUpdateInterval     = time.Second
+	maxUpdateInterval     = 5 * time.Minute
+)
+
+// clientMessage is a request sent by a WebSocket client:
+//
+//	{"type": "subscribe", "topic": "interface:eth0", "interval": 5}
+//	{"type": "unsubscribe", "topic": "network"}
+//	{"type": "ping", "id": "42"}
+type clientMessage struct {
+	Type     string  `json:"type"`
+	Topic    string  `json:"topic,omitempty"`
+	Interval float64 `json:"interval,omitempty"` // seconds between updates of a periodic topic
+	ID       string  `json:"id,omitempty"`       // echoed in the reply
+}
+
+// serverMessage is a reply or an update sent to a WebSocket client
+type serverMessage struct {
+	Type      string      `json:"type"`
+	Topic     string      `json:"topic,omitempty"`
+	ID        string      `json:"id,omitempty"`
+	Interval  float64     `json:"interval,omitempty"`
+	Data      interface{} `json:"data,omitempty"`
+	Error     string      `json:"error,omitempty"`
+	Timestamp string      `json:"timestamp"`
+}
+
+// subscription is one topic of a client
+type subscription struct {
+	interval time.Duration // zero for event topics
+	lastSent time.Time
+}
+
+// wsClient is a connected WebSocket client and its subscriptions
+type wsClient struct {
+	// Updated atomically; kept first for 64-bit alignment on 32-bit ARM
+	sent     uint64
+	skipped  uint64
+	lastPong int64 // unix nanoseconds
+
+	id          uint64
+	conn        *websocket.Conn
+	connectedAt time.Time
+
+	queue     chan serverMessage // drained by writePump only
+	done      chan struct{}
+	closeOnce sync.Once
+
+	mu            sync.Mutex
+	subscriptions map[string]*subscription
+}
+
+// errInvalidTopic is returned for topics outside the protocol
+var errInvalidTopic = errors.New("invalid topic")
+
+// periodicTopic reports whether topic is sent on an interval rather than on events
+func periodicTopic(topic string) bool {
+	return topic == topicNetwork || strings.HasPrefix(topic, topicInterfacePrefix)
+}
+
+func validateTopic(topic string) error {
+	switch {
+	case topic == topicNetwork, topic == topicAlerts:
+		return nil
+	case strings.HasPrefix(topic, topicInterfacePrefix) && len(topic) > len(topicInterfacePrefix):
+		return nil
+	case strings.HasPrefix(topic, topicJobPrefix) && len(topic) > len(topicJobPrefix):
+		return nil
+	}
+	return fmt.Errorf("%w: %q", errInvalidTopic, topic)
+}
+
+// updateInterval converts a requested interval in seconds, zero selecting the default
+func updateInterval(seconds float64) time.Duration {
+	if seconds <= 0 || math.IsNaN(seconds) {
+		return defaultUpdateInterval
+	}
+	if seconds > maxUpdateInterval.Seconds() {
+		return maxUpdateInterval
+	}
+
+	interval := time.Duration(seconds * float64(time.Second))
+	if interval < minUpdateInterval {
+		return minUpdateInterval
+	}
+	return interval
+}
+
+func newWSClient(conn *websocket.Conn) *wsClient {
+	return &wsClient{
+		conn:        conn,
+		connectedAt: time.Now(),
+		queue:       make(chan serverMessage, sendQueueSize),
+		done:        make(chan struct{}),
+		// Subscribe to the dashboard snapshot by default so that clients
+		// unaware of the protocol keep receiving network updates
+		subscriptions: map[string]*subscription{
+			topicNetwork: {interval: defaultUpdateInterval},
+		},
+	}
+}
+
+func (c *wsClient) subscribe(topic string, interval time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !periodicTopic(topic) {
+		interval = 0
+	}
+	if sub, ok := c.subscriptions[topic]; ok {
+		sub.interval = interval
+		return
+	}
+	c.subscriptions[topic] = &subscription{interval: interval}
+}
+
+func (c *wsClient) unsubscribe(topic string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, ok := c.subscriptions[topic]
+	delete(c.subscriptions, topic)
+	return ok
+}
+
+func (c *wsClient) subscribed(topic string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, ok := c.subscriptions[topic]
+	return ok
+}
+
+// dueTopics returns the periodic topics whose interval has elapsed at now and
+// marks them as sent
+func (c *wsClient) dueTopics(now time.Time) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var due []string
+	for topic, sub := range c.subscriptions {
+		if sub.interval == 0 {
+			continue
+		}
+		// Allow for ticker jitter so a 3s interval is not stretched to 4s
+		if now.Sub(sub.lastSent) >= sub.interval-minUpdateInterval/2 {
+			sub.lastSent = now
+			due = append(due, topic)
+		}
+	}
+	return due
+}
+
+// handleMessage answers a single client request
+func (c *wsClient) handleMessage(data []byte) serverMessage {
+	var msg clientMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return serverMessage{Type: "error", Error: fmt.Sprintf("invalid message: %v", err)}
+	}
+
+	switch msg.Type {
+	case "ping":
+		return serverMessage{Type: "pong", ID: msg.ID}
+
+	case "subscribe":
+		if err := validateTopic(msg.Topic); err != nil {
+			return serverMessage{Type: "error", ID: msg.ID, Topic: msg.Topic, Error: err.Error()}
+		}
+		interval := time.Duration(0)
+		if periodicTopic(msg.Topic) {
+			interval = updateInterval(msg.Interval)
+		}
+		c.subscribe(msg.Topic, interval)
+		return serverMessage{Type: "subscribed", ID: msg.ID, Topic: msg.Topic, Interval: interval.Seconds()}
+
+	case "unsubscribe":
+		if !c.unsubscribe(msg.Topic) {
+			return serverMessage{Type: "error", ID: msg.ID, Topic: msg.Topic, Error: "not subscribed"}
+		}
+		return serverMessage{Type: "unsubscribed", ID: msg.ID, Topic: msg.Topic}
+
+	case "subscriptions":
+		c.mu.Lock()
+		topics := make(map[string]float64, len(c.subscriptions))
+		for topic, sub := range c.subscriptions {
+			topics[topic] = sub.interval.Seconds()
+		}
+		c.mu.Unlock()
+		return serverMessage{Type: "subscriptions", ID: msg.ID, Data: topics}
+	}
+
+	return serverMessage{Type: "error", ID: msg.ID, Error: fmt.Sprintf("unknown message type %q", msg.Type)}
+}
+
+// publish sends an event to every client subscribed to topic
+func publish(topic, messageType string, data interface{}) {
+	message := serverMessage{
+		Type:      messageType,
+		Topic:     topic,
+		Data:      data,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	for _, client := range wsHub.list() {
+		if client.subscribed(topic) {
+			client.enqueue(message, false)
+		}
+	}
+}
+
+// startNetworkInfoBroadcaster sends every client the topics it subscribed to
+// on the intervals it asked for, built from the sampler's snapshot, and
+// publishes alerts on interface and connection changes
+func startNetworkInfoBroadcaster(sampler *core.Sampler) {
+	ticker := time.NewTicker(minUpdateInterval)
+	defer ticker.Stop()
+
+	alerts := newAlertWatcher()
+
+	for now := range ticker.C {
+		for _, alert := range alerts.check(sampler) {
+			publish(topicAlerts, "alert", alert)
+		}
+
+		// Build each snapshot at most once per tick, whoever asks for it
+		snapshots := make(map[string]serverMessage)
+		for _, client := range wsHub.list() {
+			for _, topic := range client.dueTopics(now) {
+				message, ok := snapshots[topic]
+				if !ok {
+					message, ok = topicSnapshot(sampler, topic, now)
+					if !ok {
+						continue
+					}
+					snapshots[topic] = message
+				}
+				client.enqueue(message, true)
+			}
+		}
+	}
+}
+
+// topicSnapshot builds the update of a periodic topic
+func topicSnapshot(sampler *core.Sampler, topic string, now time.Time) (serverMessage, bool) {
+	name, messageType := "", "network_update"
+	if strings.HasPrefix(topic, topicInterfacePrefix) {
+		name, messageType = strings.TrimPrefix(topic, topicInterfacePrefix), "interface_update"
+	}
+
+	networkInfo, err := sampler.Snapshot(name)
+	if errors.Is(err, core.ErrInterfaceNotFound) {
+		return serverMessage{Type: "error", Topic: topic, Error: err.Error(), Timestamp: now.Format(time.RFC3339)}, true
+	}
+	if err != nil {
+		log.Printf("Error getting network info for broadcast: %v", err)
+		return serverMessage{}, false
+	}
+	networkInfo.Timestamp = now
+
+	return serverMessage{
+		Type:      messageType,
+		Topic:     topic,
+		Data:      networkInfo,
+		Timestamp: now.Format(time.RFC3339),
+	}, true
+}
+
+// alert is published on the alerts topic
+type alert struct {
+	Level     string `json:"level"` // "info" or "warning"
+	Message   string `json:"message"`
+	Interface string `json:"interface,omitempty"`
+}
+
+// alertWatcher compares successive samples and reports what changed
+type alertWatcher struct {
+	interfaces map[string]bool // up state by name
+	status     string
+}
+
+func newAlertWatcher() *alertWatcher {
+	return &alertWatcher{}
+}
+
+func (w *alertWatcher) check(sampler *core.Sampler) []alert {
+	var alerts []alert
+
+	interfaces := make(map[string]bool)
+	for _, iface := range sampler.Interfaces() {
+		interfaces[iface.Name] = iface.Up
+	}
+
+	// The first sample only sets the baseline
+	if w.interfaces != nil {
+		for name, up := range interfaces {
+			wasUp, known := w.interfaces[name]
+			switch {
+			case !known:
+				alerts = append(alerts, alert{Level: "info", Message: fmt.Sprintf("Interface %s appeared", name), Interface: name})
+			case wasUp && !up:
+				alerts = append(alerts, alert{Level: "warning", Message: fmt.Sprintf("Interface %s went down", name), Interface: name})
+			case !wasUp && up:
+				alerts = append(alerts, alert{Level: "info", Message: fmt.Sprintf("Interface %s came up", name), Interface: name})
+			}
+		}
+		for name := range w.interfaces {
+			if _, ok := interfaces[name]; !ok {
+				alerts = append(alerts, alert{Level: "warning", Message: fmt.Sprintf("Interface %s was removed", name), Interface: name})
+			}
+		}
+	}
+	w.interfaces = interfaces
+
+	if info, err := sampler.Snapshot(""); err == nil {
+		status := info.Connection.Status
+		if w.status != "" && status != w.status {
+			level := "info"
+			if status != "connected" {
+				level = "warning"
+			}
+			alerts = append(alerts, alert{Level: level, Message: fmt.Sprintf("Connection is %s (was %s)", status, w.status)})
+		}
+		w.status = status
+	}
+
+	return alerts
+}
@@ -0,0 +1,215 @@
+// Package main provides the hub of WebSocket clients.
+// Each client has a bounded outbound queue drained by its own writer goroutine,
+// so a slow connection never blocks the broadcaster or other clients.
+package main
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Connection limits of WebSocket clients
+const (
+	sendQueueSize  = 32               // outbound messages buffered per client
+	writeWait      = 10 * time.Second // time allowed to write a message
+	pongWait       = 60 * time.Second // time allowed between pongs from the client
+	pingPeriod     = pongWait * 9 / 10
+	maxMessageSize = 4096 // largest request a client may send
+)
+
+// ClientStats describes a connected WebSocket client
+type ClientStats struct {
+	ID            uint64             `json:"id"`
+	RemoteAddr    string             `json:"remoteAddr"`
+	ConnectedAt   time.Time          `json:"connectedAt"`
+	LastPongAt    *time.Time         `json:"lastPongAt,omitempty"`
+	Subscriptions map[string]float64 `json:"subscriptions"` // topic to interval in seconds, zero for event topics
+	Queued        int                `json:"queued"`        // messages waiting to be written
+	QueueSize     int                `json:"queueSize"`
+	Sent          uint64             `json:"sent"`
+	Skipped       uint64             `json:"skipped"` // periodic updates left out while the client lagged behind
+}
+
+// hub tracks the connected WebSocket clients
+type hub struct {
+	mu      sync.Mutex
+	clients map[*wsClient]bool
+	nextID  uint64
+}
+
+var wsHub = &hub{clients: make(map[*wsClient]bool)}
+
+func (h *hub) register(client *wsClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.nextID++
+	client.id = h.nextID
+	h.clients[client] = true
+}
+
+func (h *hub) unregister(client *wsClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.clients, client)
+}
+
+// list returns the connected clients
+func (h *hub) list() []*wsClient {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	list := make([]*wsClient, 0, len(h.clients))
+	for client := range h.clients {
+		list = append(list, client)
+	}
+	return list
+}
+
+// stats returns the statistics of every connected client, oldest first
+func (h *hub) stats() []ClientStats {
+	clients := h.list()
+	stats := make([]ClientStats, 0, len(clients))
+	for _, client := range clients {
+		stats = append(stats, client.stats())
+	}
+
+	sort.Slice(stats, func(i, j int) bool { return stats[i].ID < stats[j].ID })
+	return stats
+}
+
+// enqueue queues a message for the client without blocking. Periodic updates
+// are skipped while the queue is half full, which downsamples a lagging
+// client to what it can keep up with; a client whose queue is full even for
+// replies and events is disconnected.
+func (c *wsClient) enqueue(message serverMessage, periodic bool) {
+	if message.Timestamp == "" {
+		message.Timestamp = time.Now().Format(time.RFC3339)
+	}
+
+	if periodic && len(c.queue) >= sendQueueSize/2 {
+		atomic.AddUint64(&c.skipped, 1)
+		return
+	}
+
+	select {
+	case c.queue <- message:
+	case <-c.done:
+	default:
+		log.Printf("Dropping slow WebSocket client %d (%s)", c.id, c.conn.RemoteAddr())
+		c.close()
+	}
+}
+
+// close disconnects the client; it is safe to call more than once
+func (c *wsClient) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+	})
+}
+
+// writePump is the only goroutine writing to the connection. It drains the
+// queue and pings the client so that dead peers are noticed.
+func (c *wsClient) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case message := <-c.queue:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteJSON(message); err != nil {
+				log.Printf("Error writing to WebSocket client %d: %v", c.id, err)
+				c.close()
+				return
+			}
+			atomic.AddUint64(&c.sent, 1)
+
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				c.close()
+				return
+			}
+
+		case <-c.done:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+			return
+		}
+	}
+}
+
+// readPump reads client requests until the connection fails or the client
+// stops answering pings
+func (c *wsClient) readPump() {
+	defer c.close()
+
+	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		atomic.StoreInt64(&c.lastPong, time.Now().UnixNano())
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				log.Printf("Error reading message: %v", err)
+			}
+			return
+		}
+
+		c.enqueue(c.handleMessage(data), false)
+	}
+}
+
+func (c *wsClient) stats() ClientStats {
+	stats := ClientStats{
+		ID:          c.id,
+		RemoteAddr:  c.conn.RemoteAddr().String(),
+		ConnectedAt: c.connectedAt,
+		Queued:      len(c.queue),
+		QueueSize:   cap(c.queue),
+		Sent:        atomic.LoadUint64(&c.sent),
+		Skipped:     atomic.LoadUint64(&c.skipped),
+	}
+	if lastPong := atomic.LoadInt64(&c.lastPong); lastPong != 0 {
+		at := time.Unix(0, lastPong)
+		stats.LastPongAt = &at
+	}
+
+	c.mu.Lock()
+	stats.Subscriptions = make(map[string]float64, len(c.subscriptions))
+	for topic, sub := range c.subscriptions {
+		stats.Subscriptions[topic] = sub.interval.Seconds()
+	}
+	c.mu.Unlock()
+
+	return stats
+}
+
+func handleWebSocketConnection(w http.ResponseWriter, r *http.Request) {
+	ws, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("Error upgrading to WebSocket: %v", err)
+		return
+	}
+
+	client := newWSClient(ws)
+	wsHub.register(client)
+	defer wsHub.unregister(client)
+
+	go client.writePump()
+	client.readPump()
+}